@@ -18,6 +18,6 @@ func main() {
 	os.Exit(prog.Run(
 		[3]*os.File{os.Stdin, os.Stdout, os.Stderr}, os.Args,
 		prog.Composite(
-			&buildinfo.Program{}, &daemon.Program{}, &lsp.Program{},
+			&buildinfo.Program{}, &daemon.Program{}, &lsp.Program{LibDirs: shell.LibPaths},
 			&shell.Program{ActivateDaemon: daemon.Activate})))
 }
@@ -14,5 +14,5 @@ import (
 func main() {
 	os.Exit(prog.Run(
 		[3]*os.File{os.Stdin, os.Stdout, os.Stderr}, os.Args,
-		prog.Composite(&buildinfo.Program{}, &lsp.Program{}, &shell.Program{})))
+		prog.Composite(&buildinfo.Program{}, &lsp.Program{LibDirs: shell.LibPaths}, &shell.Program{})))
 }
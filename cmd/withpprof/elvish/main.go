@@ -17,6 +17,6 @@ func main() {
 	os.Exit(prog.Run(
 		[3]*os.File{os.Stdin, os.Stdout, os.Stderr}, os.Args,
 		prog.Composite(
-			&pprof.Program{}, &buildinfo.Program{}, &daemon.Program{}, &lsp.Program{},
+			&pprof.Program{}, &buildinfo.Program{}, &daemon.Program{}, &lsp.Program{LibDirs: shell.LibPaths},
 			&shell.Program{ActivateDaemon: daemon.Activate})))
 }
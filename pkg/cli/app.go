@@ -130,13 +130,14 @@ func NewApp(spec AppSpec) App {
 	lp.RedrawCb(a.redraw)
 
 	a.codeArea = tk.NewCodeArea(tk.CodeAreaSpec{
-		Bindings:    spec.CodeAreaBindings,
-		Highlighter: a.Highlighter.Get,
-		Prompt:      a.Prompt.Get,
-		RPrompt:     a.RPrompt.Get,
-		QuotePaste:  spec.QuotePaste,
-		OnSubmit:    a.CommitCode,
-		State:       spec.CodeAreaState,
+		Bindings:      spec.CodeAreaBindings,
+		Highlighter:   a.Highlighter.Get,
+		Prompt:        a.Prompt.Get,
+		RPrompt:       a.RPrompt.Get,
+		Autosuggester: spec.Autosuggester,
+		QuotePaste:    spec.QuotePaste,
+		OnSubmit:      a.CommitCode,
+		State:         spec.CodeAreaState,
 
 		SimpleAbbreviations:    spec.SimpleAbbreviations,
 		CommandAbbreviations:   spec.CommandAbbreviations,
@@ -267,11 +268,13 @@ func (a *app) redraw(flag redrawFlag) {
 		a.codeArea.MutateState(func(s *tk.CodeAreaState) {
 			s.HideTips = true
 			s.HideRPrompt = hideRPrompt
+			s.HideSuggestion = true
 		})
 		bufMain := renderApp([]tk.Widget{a.codeArea /* no addon */}, width, height)
 		a.codeArea.MutateState(func(s *tk.CodeAreaState) {
 			s.HideTips = false
 			s.HideRPrompt = false
+			s.HideSuggestion = false
 		})
 		// Insert a newline after the buffer and position the cursor there.
 		bufMain.Extend(term.NewBuffer(width), true)
@@ -279,6 +282,11 @@ func (a *app) redraw(flag redrawFlag) {
 		a.TTY.UpdateBuffer(bufNotes, bufMain, flag&fullRedraw != 0)
 		a.TTY.ResetBuffer()
 	} else {
+		// Suggestions are only useful when the user is editing the code
+		// directly.
+		a.codeArea.MutateState(func(s *tk.CodeAreaState) {
+			s.HideSuggestion = len(addons) > 0
+		})
 		bufMain := renderApp(append([]tk.Widget{a.codeArea}, addons...), width, height)
 		a.TTY.UpdateBuffer(bufNotes, bufMain, flag&fullRedraw != 0)
 	}
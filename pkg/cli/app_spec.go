@@ -13,9 +13,10 @@ type AppSpec struct {
 	BeforeReadline    []func()
 	AfterReadline     []func(string)
 
-	Highlighter Highlighter
-	Prompt      Prompt
-	RPrompt     Prompt
+	Highlighter   Highlighter
+	Prompt        Prompt
+	RPrompt       Prompt
+	Autosuggester func(code string) string
 
 	GlobalBindings   tk.Bindings
 	CodeAreaBindings tk.Bindings
@@ -335,6 +335,38 @@ func TestReadCode_HidesRPromptInFinalRedrawIfNotPersistent(t *testing.T) {
 	f.TTY.TestBuffer(t, wantBuf)
 }
 
+func TestReadCode_ShowsSuggestion_ExceptInFinalRedraw(t *testing.T) {
+	f := Setup(WithSpec(func(spec *AppSpec) {
+		spec.Autosuggester = func(code string) string {
+			if code == "" {
+				return ""
+			}
+			return " more"
+		}
+	}))
+	defer f.Stop()
+
+	feedInput(f.TTY, "code")
+	f.TTY.TestBuffer(t,
+		bb().Write("code").SetDotHere().Write(" more", ui.Dim).Buffer())
+
+	feedInput(f.TTY, "\n")
+	f.TestTTY(t, "code", "\n", term.DotHere)
+}
+
+func TestReadCode_HidesSuggestionWithAddon(t *testing.T) {
+	f := Setup(WithSpec(func(spec *AppSpec) {
+		spec.CodeAreaState.Buffer = tk.CodeBuffer{Content: "code", Dot: 4}
+		spec.Autosuggester = func(string) string { return " more" }
+	}))
+	defer f.Stop()
+
+	f.App.PushAddon(tk.Label{Content: ui.T("addon> ")})
+	f.App.Redraw()
+	f.TestTTY(t, "code\n",
+		term.DotHere, "addon> ")
+}
+
 // Addon.
 
 func TestReadCode_LetsLastWidgetHandleEvents(t *testing.T) {
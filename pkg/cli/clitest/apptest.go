@@ -25,6 +25,7 @@ var Styles = ui.RuneStylesheet{
 	'V': ui.Stylings(ui.Underlined, ui.FgGreen),
 	'$': ui.FgMagenta,
 	'c': ui.FgCyan, // mnemonic "Comment"
+	'd': ui.Dim,
 }
 
 // Fixture is a test fixture.
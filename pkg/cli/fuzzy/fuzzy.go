@@ -0,0 +1,237 @@
+// Package fuzzy implements fuzzy matching with ranking, in the style of fzf.
+//
+// A pattern matches a text if all of its runes appear in the text in order. A
+// match is given a score that rewards matched runes at word boundaries and
+// consecutive matched runes, and penalizes gaps between them.
+package fuzzy
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"src.elv.sh/pkg/ui"
+)
+
+// Constants used in scoring, taken from fzf.
+const (
+	scoreMatch        = 16
+	scoreGapStart     = -3
+	scoreGapExtension = -1
+
+	// A rune right after a non-word rune, like the "b" in "foo-bar".
+	bonusBoundary = scoreMatch / 2
+	// A non-word rune; they are usually as important as word boundaries.
+	bonusNonWord = scoreMatch / 2
+	// A camel case transition like the "B" in "fooBar", or the first digit
+	// after a non-digit like the "1" in "foo1".
+	bonusCamel123 = bonusBoundary + scoreGapExtension
+	// A rune right after another matched rune. It is set so that consecutive
+	// matches are rewarded more than matches separated by a gap.
+	bonusConsecutive = -(scoreGapStart + scoreGapExtension)
+	// The bonus of the first rune of the pattern is multiplied by this.
+	bonusFirstCharMultiplier = 2
+)
+
+// Match returns whether the pattern matches s, and if so, the score of the
+// match and the byte indices of the matched runes in s, in increasing order.
+//
+// The pattern is split into terms at whitespaces, and all the terms must
+// match; the score is then the sum of the scores of all terms. Each term is
+// matched case-insensitively if it is all lower case, and case-sensitively
+// otherwise. A pattern with no terms matches everything with a score of 0.
+func Match(pattern, s string) (score int, positions []int, ok bool) {
+	for _, term := range strings.Fields(pattern) {
+		termScore, termPositions, ok := matchTerm(term, s)
+		if !ok {
+			return 0, nil, false
+		}
+		score += termScore
+		positions = append(positions, termPositions...)
+	}
+	if len(positions) > 0 {
+		sort.Ints(positions)
+		positions = dedupInts(positions)
+	}
+	return score, positions, true
+}
+
+// RecencyBonus returns a bonus for the i-th of n items, ordered from the least
+// recent to the most recent. The bonus is at most as big as that of one
+// consecutive matched rune, so that it only decides the order of matches of
+// similar quality.
+func RecencyBonus(i, n int) int {
+	if n <= 1 {
+		return 0
+	}
+	return bonusConsecutive * i / (n - 1)
+}
+
+var stylingForMatched = ui.Bold
+
+// Highlight returns a copy of t with the runes at the given byte indices
+// highlighted.
+func Highlight(t ui.Text, positions []int) ui.Text {
+	if len(positions) == 0 {
+		return t
+	}
+	var sb strings.Builder
+	for _, seg := range t {
+		sb.WriteString(seg.Text)
+	}
+	s := sb.String()
+	var indices []int
+	for _, pos := range positions {
+		_, size := utf8.DecodeRuneInString(s[pos:])
+		if n := len(indices); n > 0 && indices[n-1] == pos {
+			// Extend the previous range.
+			indices[n-1] = pos + size
+		} else {
+			indices = append(indices, pos, pos+size)
+		}
+	}
+	parts := t.Partition(indices...)
+	for i := 1; i < len(parts); i += 2 {
+		parts[i] = ui.StyleText(parts[i], stylingForMatched)
+	}
+	return ui.Concat(parts...)
+}
+
+type runeClass int
+
+const (
+	classNonWord runeClass = iota
+	classLower
+	classUpper
+	classNumber
+	classLetter // Letters without case
+)
+
+func classOf(r rune) runeClass {
+	switch {
+	case unicode.IsLower(r):
+		return classLower
+	case unicode.IsUpper(r):
+		return classUpper
+	case unicode.IsDigit(r):
+		return classNumber
+	case unicode.IsLetter(r):
+		return classLetter
+	default:
+		return classNonWord
+	}
+}
+
+func bonusFor(prev, cur runeClass) int {
+	switch {
+	case prev == classNonWord && cur != classNonWord:
+		return bonusBoundary
+	case prev == classLower && cur == classUpper,
+		prev != classNumber && cur == classNumber:
+		return bonusCamel123
+	case cur == classNonWord:
+		return bonusNonWord
+	}
+	return 0
+}
+
+func matchTerm(term, s string) (int, []int, bool) {
+	pattern := []rune(term)
+	ignoreCase := term == strings.ToLower(term)
+	// Runes of s and their byte indices.
+	var runes []rune
+	var indices []int
+	for i, r := range s {
+		runes = append(runes, r)
+		indices = append(indices, i)
+	}
+	eq := func(i, j int) bool {
+		r := runes[i]
+		if ignoreCase {
+			r = unicode.ToLower(r)
+		}
+		return r == pattern[j]
+	}
+
+	// Find the end of the first match by scanning forward, and then the
+	// start of the shortest match ending there by scanning backward.
+	pi, end := 0, -1
+	for i := range runes {
+		if eq(i, pi) {
+			pi++
+			if pi == len(pattern) {
+				end = i
+				break
+			}
+		}
+	}
+	if end == -1 {
+		return 0, nil, false
+	}
+	start := end
+	for pi = len(pattern) - 1; ; start-- {
+		if eq(start, pi) {
+			pi--
+			if pi < 0 {
+				break
+			}
+		}
+	}
+
+	// Score the match in [start, end].
+	score, inGap, consecutive, firstBonus := 0, false, 0, 0
+	positions := make([]int, 0, len(pattern))
+	prevClass := classNonWord
+	if start > 0 {
+		prevClass = classOf(runes[start-1])
+	}
+	pi = 0
+	for i := start; i <= end; i++ {
+		class := classOf(runes[i])
+		if pi < len(pattern) && eq(i, pi) {
+			positions = append(positions, indices[i])
+			score += scoreMatch
+			bonus := bonusFor(prevClass, class)
+			if consecutive == 0 {
+				firstBonus = bonus
+			} else {
+				// A boundary in a run of consecutive matches starts a new
+				// chunk.
+				if bonus >= bonusBoundary && bonus > firstBonus {
+					firstBonus = bonus
+				}
+				bonus = max(bonus, firstBonus, bonusConsecutive)
+			}
+			if pi == 0 {
+				score += bonus * bonusFirstCharMultiplier
+			} else {
+				score += bonus
+			}
+			inGap = false
+			consecutive++
+			pi++
+		} else {
+			if inGap {
+				score += scoreGapExtension
+			} else {
+				score += scoreGapStart
+			}
+			inGap = true
+			consecutive = 0
+			firstBonus = 0
+		}
+		prevClass = class
+	}
+	return score, positions, true
+}
+
+func dedupInts(a []int) []int {
+	out := a[:1]
+	for _, x := range a[1:] {
+		if x != out[len(out)-1] {
+			out = append(out, x)
+		}
+	}
+	return out
+}
@@ -0,0 +1,94 @@
+package fuzzy
+
+import (
+	"testing"
+
+	"src.elv.sh/pkg/tt"
+	"src.elv.sh/pkg/ui"
+)
+
+var Args = tt.Args
+
+// Returns only the positions of a match, or nil if there is no match.
+func positions(pattern, s string) []int {
+	_, pos, ok := Match(pattern, s)
+	if !ok {
+		return nil
+	}
+	return pos
+}
+
+func TestMatch_Positions(t *testing.T) {
+	tt.Test(t, positions,
+		Args("", "foo").Rets([]int(nil)),
+		Args("fb", "foo-bar").Rets([]int{0, 4}),
+		// The shortest match ending at the first possible position is used.
+		Args("ab", "a-a-b").Rets([]int{2, 4}),
+		// Terms are matched separately.
+		Args("bar fo", "foo-bar").Rets([]int{0, 1, 4, 5, 6}),
+		// Byte indices are used.
+		Args("好界", "你好世界").Rets([]int{3, 9}),
+		// Smart case.
+		Args("FB", "foo-bar").Rets([]int(nil)),
+		Args("fB", "fooBar").Rets([]int{0, 3}),
+		Args("fb", "FooBar").Rets([]int{0, 3}),
+		// No match.
+		Args("bf", "foo-bar").Rets([]int(nil)),
+	)
+}
+
+func score(pattern, s string) int {
+	score, _, _ := Match(pattern, s)
+	return score
+}
+
+func TestMatch_Scores(t *testing.T) {
+	for _, test := range []struct {
+		pattern, better, worse string
+	}{
+		// Consecutive characters.
+		{"abc", "xabcx", "xaxbxcx"},
+		// Word boundaries.
+		{"fb", "foo-bar", "xfxb"},
+		{"fb", "fooBar", "xfxb"},
+		{"bar", "foo/bar", "foobar"},
+		// Short gaps.
+		{"ab", "axb", "axxxb"},
+		// Matching at the start.
+		{"ls", "ls -l", "xls -l"},
+	} {
+		if score(test.pattern, test.better) <= score(test.pattern, test.worse) {
+			t.Errorf("want score(%q, %q) = %d > score(%q, %q) = %d",
+				test.pattern, test.better, score(test.pattern, test.better),
+				test.pattern, test.worse, score(test.pattern, test.worse))
+		}
+	}
+}
+
+func TestRecencyBonus(t *testing.T) {
+	tt.Test(t, RecencyBonus,
+		Args(0, 1).Rets(0),
+		Args(0, 5).Rets(0),
+		Args(4, 5).Rets(bonusConsecutive),
+	)
+	if RecencyBonus(4, 5) >= scoreMatch {
+		t.Errorf("recency bonus should be smaller than one matched rune")
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tt.Test(t, Highlight,
+		Args(ui.T("foo-bar"), []int(nil)).Rets(ui.T("foo-bar")),
+		Args(ui.T("foo-bar"), []int{0, 4, 5}).Rets(
+			ui.MarkLines(
+				"foo-bar", ui.RuneStylesheet{'b': ui.Bold},
+				"b   bb ")),
+		// Existing styles are kept.
+		Args(ui.T("foo", ui.FgRed), []int{1}).Rets(
+			ui.Concat(ui.T("f", ui.FgRed), ui.T("o", ui.FgRed, ui.Bold),
+				ui.T("o", ui.FgRed))),
+		// Multi-byte runes.
+		Args(ui.T("你好"), []int{3}).Rets(
+			ui.Concat(ui.T("你"), ui.T("好", ui.Bold))),
+	)
+}
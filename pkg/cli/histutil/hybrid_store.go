@@ -21,7 +21,7 @@ type hybridStore struct {
 
 func (s hybridStore) AddCmd(cmd storedefs.Cmd) (int, error) {
 	seq, err := s.shared.AddCmd(cmd)
-	s.session.AddCmd(storedefs.Cmd{Text: cmd.Text, Seq: seq})
+	s.session.AddCmd(storedefs.Cmd{Text: cmd.Text, Seq: seq, Meta: cmd.Meta})
 	return seq, err
 }
 
@@ -5,9 +5,12 @@ import (
 	"strings"
 
 	"src.elv.sh/pkg/cli"
+	"src.elv.sh/pkg/cli/fuzzy"
+	"src.elv.sh/pkg/cli/term"
 	"src.elv.sh/pkg/cli/tk"
 	"src.elv.sh/pkg/diag"
 	"src.elv.sh/pkg/ui"
+	"src.elv.sh/pkg/wcwidth"
 )
 
 // Completion is a mode specialized for viewing and inserting completion
@@ -31,11 +34,15 @@ type CompletionItem struct {
 	ToShow ui.Text
 	// Used when inserting a candidate.
 	ToInsert string
+	// Shown dimmed after the candidate. Optional.
+	Description string
 }
 
 type completion struct {
 	tk.ComboBox
 	attached tk.CodeArea
+	// The width of the last render, used for laying out descriptions.
+	width *int
 }
 
 var errNoCandidates = errors.New("no candidates")
@@ -49,6 +56,7 @@ func NewCompletion(app cli.App, cfg CompletionSpec) (Completion, error) {
 	if len(cfg.Items) == 0 {
 		return nil, errNoCandidates
 	}
+	width := new(int)
 	w := tk.NewComboBox(tk.ComboBoxSpec{
 		CodeArea: tk.CodeAreaSpec{
 			Prompt:      modePrompt(" COMPLETING "+cfg.Name+" ", true),
@@ -58,7 +66,7 @@ func NewCompletion(app cli.App, cfg CompletionSpec) (Completion, error) {
 			Horizontal: true,
 			Bindings:   cfg.Bindings,
 			OnSelect: func(it tk.Items, i int) {
-				text := it.(completionItems)[i].ToInsert
+				text := it.(completionItems).items[i].ToInsert
 				codeArea.MutateState(func(s *tk.CodeAreaState) {
 					s.Pending = tk.PendingCode{
 						From: cfg.Replace.From, To: cfg.Replace.To, Content: text}
@@ -71,30 +79,92 @@ func NewCompletion(app cli.App, cfg CompletionSpec) (Completion, error) {
 			ExtendStyle: true,
 		},
 		OnFilter: func(w tk.ComboBox, p string) {
-			w.ListBox().Reset(filterCompletionItems(cfg.Items, cfg.Filter.makePredicate(p)), 0)
+			items := filterCompletionItems(cfg.Items, cfg.Filter, p)
+			w.ListBox().Reset(newCompletionItems(items, width), 0)
 		},
 	})
-	return completion{w, codeArea}, nil
+	return completion{w, codeArea, width}, nil
+}
+
+func (w completion) Render(width, height int) *term.Buffer {
+	*w.width = width
+	return w.ComboBox.Render(width, height)
+}
+
+func (w completion) MaxHeight(width, height int) int {
+	*w.width = width
+	return w.ComboBox.MaxHeight(width, height)
 }
 
 func (w completion) Dismiss() {
 	w.attached.MutateState(func(s *tk.CodeAreaState) { s.Pending = tk.PendingCode{} })
 }
 
-type completionItems []CompletionItem
-
-func filterCompletionItems(all []CompletionItem, p func(string) bool) completionItems {
+func filterCompletionItems(all []CompletionItem, f FilterSpec, p string) []CompletionItem {
+	matches := f.filter(p, len(all),
+		func(i int) string { return unstyle(all[i].ToShow) }, nil)
 	var filtered []CompletionItem
-	for _, candidate := range all {
-		if p(unstyle(candidate.ToShow)) {
-			filtered = append(filtered, candidate)
-		}
+	for _, m := range matches {
+		candidate := all[m.index]
+		candidate.ToShow = fuzzy.Highlight(candidate.ToShow, m.positions)
+		filtered = append(filtered, candidate)
 	}
 	return filtered
 }
 
-func (it completionItems) Show(i int) ui.Text { return it[i].ToShow }
-func (it completionItems) Len() int           { return len(it) }
+const (
+	// Gap between a candidate and its description.
+	completionDescGap = 2
+	// Descriptions are not shown when there is less space for them than this.
+	completionMinDescWidth = 8
+)
+
+var stylingForDescription = ui.Dim
+
+// Completion items, laid out in aligned columns when some of them have
+// descriptions.
+type completionItems struct {
+	items []CompletionItem
+	// Points to the width of the widget; the layout is only known when
+	// rendering.
+	width *int
+	// The maximal widths of candidates and descriptions.
+	showWidth, descWidth int
+}
+
+func newCompletionItems(items []CompletionItem, width *int) completionItems {
+	it := completionItems{items: items, width: width}
+	for _, item := range items {
+		it.showWidth = max(it.showWidth, wcwidth.Of(unstyle(item.ToShow)))
+		it.descWidth = max(it.descWidth, wcwidth.Of(item.Description))
+	}
+	return it
+}
+
+func (it completionItems) Len() int { return len(it.items) }
+
+// Show returns the candidate, padded to the width of the widest candidate and
+// followed by the description right-aligned to the width of the widest
+// description, so that items form aligned columns. Descriptions that don't fit
+// are truncated, and are not shown at all when the terminal is too narrow.
+func (it completionItems) Show(i int) ui.Text {
+	item := it.items[i]
+	if it.descWidth == 0 {
+		return item.ToShow
+	}
+	descWidth := min(it.descWidth, *it.width-it.showWidth-completionDescGap)
+	if descWidth < min(it.descWidth, completionMinDescWidth) {
+		return item.ToShow
+	}
+	desc := item.Description
+	if wcwidth.Of(desc) > descWidth {
+		desc = wcwidth.Trim(desc, descWidth-1) + "…"
+	}
+	padding := it.showWidth - wcwidth.Of(unstyle(item.ToShow)) +
+		completionDescGap + descWidth - wcwidth.Of(desc)
+	return ui.Concat(item.ToShow,
+		ui.T(strings.Repeat(" ", padding)), ui.T(desc, stylingForDescription))
+}
 
 func unstyle(t ui.Text) string {
 	var sb strings.Builder
@@ -7,6 +7,7 @@ import (
 	. "src.elv.sh/pkg/cli/clitest"
 	"src.elv.sh/pkg/cli/term"
 	"src.elv.sh/pkg/diag"
+	"src.elv.sh/pkg/tt"
 	"src.elv.sh/pkg/ui"
 )
 
@@ -42,6 +43,92 @@ func TestCompletion_Dismiss(t *testing.T) {
 	f.TestTTY(t /* nothing */)
 }
 
+var itemsWithDescriptions = []CompletionItem{
+	{ToShow: ui.T("foo"), ToInsert: "foo", Description: "description of foo"},
+	{ToShow: ui.T("lorem"), ToInsert: "lorem", Description: "ipsum"},
+	{ToShow: ui.T("x"), ToInsert: "x"},
+}
+
+var descriptionStyles = ui.RuneStylesheet{
+	'+': ui.Inverse,
+	'd': ui.Dim,
+	'D': ui.Stylings(ui.Dim, ui.Inverse),
+}
+
+func TestCompletion_Descriptions(t *testing.T) {
+	f := Setup(WithTTY(func(tty TTYCtrl) { tty.SetSize(24, 60) }))
+	defer f.Stop()
+
+	startCompletion(f.App, CompletionSpec{Items: itemsWithDescriptions})
+	// Candidates and descriptions are aligned.
+	f.TestTTY(t,
+		"foo\n", Styles,
+		"___",
+		" COMPLETING   ", Styles,
+		"************* ", term.DotHere, "\n",
+		"foo    description of foo  x                        \n", descriptionStyles,
+		"+++++++DDDDDDDDDDDDDDDDDD",
+		"lorem               ipsum", descriptionStyles,
+		"                    ddddd",
+	)
+}
+
+func TestCompletion_Descriptions_TruncatedOnNarrowTerminal(t *testing.T) {
+	f := Setup(WithTTY(func(tty TTYCtrl) { tty.SetSize(24, 16) }))
+	defer f.Stop()
+
+	startCompletion(f.App, CompletionSpec{Items: itemsWithDescriptions})
+	f.TestTTY(t,
+		"foo\n", Styles,
+		"___",
+		" COMPLETING   ", Styles,
+		"************* ", term.DotHere, "\n",
+		"foo    descript…\n", descriptionStyles,
+		"+++++++DDDDDDDDD",
+		"lorem      ipsum\n", descriptionStyles,
+		"           ddddd",
+		"x               ",
+	)
+}
+
+func TestCompletion_Descriptions_HiddenOnVeryNarrowTerminal(t *testing.T) {
+	f := Setup(WithTTY(func(tty TTYCtrl) { tty.SetSize(24, 14) }))
+	defer f.Stop()
+
+	startCompletion(f.App, CompletionSpec{Items: itemsWithDescriptions})
+	f.TestTTY(t,
+		"foo\n", Styles,
+		"___",
+		// The prompt takes up the entire width, so the dot is on the next
+		// line.
+		" COMPLETING   ", Styles,
+		"************* ", "\n",
+		term.DotHere, "\n",
+		"foo    x\n", Styles,
+		"+++++",
+		"lorem",
+	)
+}
+
+func TestFilterCompletionItems_Fuzzy(t *testing.T) {
+	items := []CompletionItem{
+		{ToShow: ui.T("fxxb"), ToInsert: "fxxb"},
+		{ToShow: ui.T("foo-bar", ui.FgBlue), ToInsert: "foo-bar"},
+		{ToShow: ui.T("ls"), ToInsert: "ls"},
+	}
+	tt.Test(t, filterCompletionItems,
+		Args(items, fuzzyFilter, "fb").Rets([]CompletionItem{
+			{ToShow: ui.Concat(
+				ui.T("f", ui.FgBlue, ui.Bold), ui.T("oo-", ui.FgBlue),
+				ui.T("b", ui.FgBlue, ui.Bold), ui.T("ar", ui.FgBlue)),
+				ToInsert: "foo-bar"},
+			{ToShow: ui.Concat(
+				ui.T("f", ui.Bold), ui.T("xx"), ui.T("b", ui.Bold)),
+				ToInsert: "fxxb"},
+		}),
+	)
+}
+
 func TestNewCompletion_NoItems(t *testing.T) {
 	f := Setup()
 	defer f.Stop()
@@ -58,6 +145,11 @@ func TestNewCompletion_FocusedWidgetNotCodeArea(t *testing.T) {
 	})
 }
 
+func startCompletion(app cli.App, spec CompletionSpec) {
+	w, err := NewCompletion(app, spec)
+	startMode(app, w, err)
+}
+
 func setupStartedCompletion(t *testing.T) *Fixture {
 	f := Setup()
 	w, _ := NewCompletion(f.App, CompletionSpec{
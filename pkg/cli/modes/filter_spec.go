@@ -1,8 +1,10 @@
 package modes
 
 import (
+	"sort"
 	"strings"
 
+	"src.elv.sh/pkg/cli/fuzzy"
 	"src.elv.sh/pkg/ui"
 )
 
@@ -13,6 +15,12 @@ type FilterSpec struct {
 	Maker func(string) func(string) bool
 	// Highlighter for the filter. If nil, the filter will not be highlighted.
 	Highlighter func(string) (ui.Text, []ui.Text)
+	// Called to determine whether the filter text should be used as a fuzzy
+	// pattern instead of being passed to Maker. With fuzzy matching, items are
+	// ranked by how well they match, and the matched characters are
+	// highlighted. If nil, fuzzy matching is not used. Not supported by the
+	// navigation mode.
+	Fuzzy func() bool
 }
 
 func (f FilterSpec) makePredicate(p string) func(string) bool {
@@ -21,3 +29,61 @@ func (f FilterSpec) makePredicate(p string) func(string) bool {
 	}
 	return f.Maker(p)
 }
+
+// An item that has passed the filter.
+type filterMatch struct {
+	// Index of the item among all the items.
+	index int
+	// Byte indices of the matched characters. Only set for fuzzy matches.
+	positions []int
+}
+
+// Filters n items whose texts are given by the text function. If fuzzy
+// matching is used and the filter text is not empty, the result is ranked from
+// the best match to the worst, using the recency function (if not nil) to get
+// an additional bonus for each item; equally good matches keep their original
+// order. Otherwise, the result is in the original order.
+func (f FilterSpec) filter(p string, n int, text func(int) string, recency func(int) int) []filterMatch {
+	var matches []filterMatch
+	fuzzyMatch := f.Fuzzy != nil && f.Fuzzy()
+	if !fuzzyMatch || strings.TrimSpace(p) == "" {
+		pred := f.makePredicate(p)
+		if fuzzyMatch {
+			// An empty fuzzy pattern matches everything.
+			pred = func(string) bool { return true }
+		}
+		for i := 0; i < n; i++ {
+			if pred(text(i)) {
+				matches = append(matches, filterMatch{index: i})
+			}
+		}
+		return matches
+	}
+	var scores []int
+	for i := 0; i < n; i++ {
+		score, positions, ok := fuzzy.Match(p, text(i))
+		if !ok {
+			continue
+		}
+		if recency != nil {
+			score += recency(i)
+		}
+		matches = append(matches, filterMatch{i, positions})
+		scores = append(scores, score)
+	}
+	sort.Stable(byScore{matches, scores})
+	return matches
+}
+
+// Sorts filter matches from the highest score to the lowest.
+type byScore struct {
+	matches []filterMatch
+	scores  []int
+}
+
+func (b byScore) Len() int           { return len(b.matches) }
+func (b byScore) Less(i, j int) bool { return b.scores[i] > b.scores[j] }
+func (b byScore) Swap(i, j int) {
+	b.matches[i], b.matches[j] = b.matches[j], b.matches[i]
+	b.scores[i], b.scores[j] = b.scores[j], b.scores[i]
+}
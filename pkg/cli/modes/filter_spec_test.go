@@ -0,0 +1,52 @@
+package modes
+
+import (
+	"testing"
+
+	. "src.elv.sh/pkg/cli/clitest"
+	"src.elv.sh/pkg/tt"
+	"src.elv.sh/pkg/ui"
+)
+
+// Styles, plus a style for selected items with matched characters.
+var fuzzyStyles = ui.RuneStylesheet{'u': ui.Stylings(ui.Inverse, ui.Bold)}
+
+func init() {
+	for r, s := range Styles {
+		fuzzyStyles[r] = s
+	}
+}
+
+var fuzzyFilter = FilterSpec{Fuzzy: func() bool { return true }}
+
+func filterIndices(f FilterSpec, p string, items []string, recency func(int) int) []int {
+	matches := f.filter(p, len(items),
+		func(i int) string { return items[i] }, recency)
+	var indices []int
+	for _, m := range matches {
+		indices = append(indices, m.index)
+	}
+	return indices
+}
+
+func TestFilterSpec_Filter(t *testing.T) {
+	items := []string{"axxb", "ab", "a-b", "c"}
+	var noRecency func(int) int
+	newestFirst := func(i int) int { return 100 - i }
+	tt.Test(t, filterIndices,
+		// Not fuzzy: substring match in the original order.
+		Args(FilterSpec{}, "b", items, noRecency).Rets([]int{0, 1, 2}),
+		// Fuzzy: ranked.
+		Args(fuzzyFilter, "ab", items, noRecency).Rets([]int{1, 2, 0}),
+		// Recency bonus.
+		Args(fuzzyFilter, "c", items, newestFirst).Rets([]int{3}),
+		Args(fuzzyFilter, "ab", []string{"ab", "ab"}, newestFirst).Rets([]int{0, 1}),
+		Args(fuzzyFilter, "ab", []string{"ab", "ab"}, func(i int) int { return i }).
+			Rets([]int{1, 0}),
+		// Equally good matches keep their original order.
+		Args(fuzzyFilter, "ab", []string{"xab", "ab", "yab"}, noRecency).
+			Rets([]int{1, 0, 2}),
+		// Fuzzy with an empty pattern: not ranked.
+		Args(fuzzyFilter, " ", items, noRecency).Rets([]int{0, 1, 2, 3}),
+	)
+}
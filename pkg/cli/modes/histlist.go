@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"src.elv.sh/pkg/cli"
+	"src.elv.sh/pkg/cli/fuzzy"
 	"src.elv.sh/pkg/cli/tk"
 	"src.elv.sh/pkg/store/storedefs"
 	"src.elv.sh/pkg/ui"
@@ -24,12 +25,49 @@ type HistlistSpec struct {
 	// Dedup is called to determine whether deduplication should be done.
 	// Defaults to true if unset.
 	Dedup func() bool
+	// Scope is called to determine which commands should be shown. Defaults to
+	// HistlistScopeGlobal if unset.
+	Scope func() HistlistScope
+	// ID of the current session, used for HistlistScopeSession.
+	SessionID string
+	// The current directory, used for HistlistScopeDir.
+	Dir string
 	// Configuration for the filter.
 	Filter FilterSpec
 	// RPrompt of the code area (first row of the widget).
 	CodeAreaRPrompt func() ui.Text
 }
 
+// HistlistScope determines which commands are shown in the histlist mode.
+type HistlistScope int
+
+// Possible values of HistlistScope.
+const (
+	// All commands.
+	HistlistScopeGlobal HistlistScope = iota
+	// Commands run in the current session.
+	HistlistScopeSession
+	// Commands run in the current directory.
+	HistlistScopeDir
+	numHistlistScopes
+)
+
+var histlistScopeNames = [...]string{"global", "session", "dir"}
+
+// String returns the name of the scope.
+func (s HistlistScope) String() string {
+	if s < 0 || s >= numHistlistScopes {
+		return fmt.Sprintf("!(BAD SCOPE: %d)", int(s))
+	}
+	return histlistScopeNames[s]
+}
+
+// Next returns the scope after s, cycling back to HistlistScopeGlobal after the
+// last scope.
+func (s HistlistScope) Next() HistlistScope {
+	return (s + 1) % numHistlistScopes
+}
+
 // NewHistlist creates a new histlist mode.
 func NewHistlist(app cli.App, spec HistlistSpec) (Histlist, error) {
 	codeArea, err := FocusedCodeArea(app)
@@ -42,6 +80,9 @@ func NewHistlist(app cli.App, spec HistlistSpec) (Histlist, error) {
 	if spec.Dedup == nil {
 		spec.Dedup = func() bool { return true }
 	}
+	if spec.Scope == nil {
+		spec.Scope = func() HistlistScope { return HistlistScopeGlobal }
+	}
 
 	cmds, err := spec.AllCmds()
 	if err != nil {
@@ -51,12 +92,15 @@ func NewHistlist(app cli.App, spec HistlistSpec) (Histlist, error) {
 	for i, cmd := range cmds {
 		last[cmd.Text] = i
 	}
-	cmdItems := histlistItems{cmds, last}
+	cmdItems := histlistItems{entries: cmds, last: last}
 
 	w := tk.NewComboBox(tk.ComboBoxSpec{
 		CodeArea: tk.CodeAreaSpec{
 			Prompt: func() ui.Text {
 				content := " HISTORY "
+				if scope := spec.Scope(); scope != HistlistScopeGlobal {
+					content += "(" + scope.String() + ") "
+				}
 				if spec.Dedup() {
 					content += "(dedup on) "
 				}
@@ -81,7 +125,8 @@ func NewHistlist(app cli.App, spec HistlistSpec) (Histlist, error) {
 			},
 		},
 		OnFilter: func(w tk.ComboBox, p string) {
-			it := cmdItems.filter(spec.Filter.makePredicate(p), spec.Dedup())
+			it := cmdItems.filter(
+				spec.scopePredicate(), spec.Filter, p, spec.Dedup())
 			w.ListBox().Reset(it, it.Len()-1)
 		},
 	})
@@ -91,26 +136,73 @@ func NewHistlist(app cli.App, spec HistlistSpec) (Histlist, error) {
 type histlistItems struct {
 	entries []storedefs.Cmd
 	last    map[string]int
+	// Positions of the characters matched by a fuzzy filter, or nil if the
+	// list is not filtered that way.
+	positions [][]int
 }
 
-func (it histlistItems) filter(p func(string) bool, dedup bool) histlistItems {
-	var filtered []storedefs.Cmd
-	for i, entry := range it.entries {
-		text := entry.Text
-		if dedup && it.last[text] != i {
-			continue
+// Returns a predicate for commands in the current scope, or nil if all commands
+// are in scope.
+func (spec *HistlistSpec) scopePredicate() func(storedefs.Cmd) bool {
+	switch spec.Scope() {
+	case HistlistScopeSession:
+		return func(cmd storedefs.Cmd) bool { return cmd.Meta.Session == spec.SessionID }
+	case HistlistScopeDir:
+		return func(cmd storedefs.Cmd) bool { return cmd.Meta.Dir == spec.Dir }
+	default:
+		return nil
+	}
+}
+
+func (it histlistItems) filter(scope func(storedefs.Cmd) bool, f FilterSpec, p string, dedup bool) histlistItems {
+	if scope != nil {
+		it = it.scoped(scope)
+	}
+	entries := it.entries
+	if dedup {
+		entries = nil
+		for i, entry := range it.entries {
+			if it.last[entry.Text] == i {
+				entries = append(entries, entry)
+			}
 		}
-		if p(text) {
-			filtered = append(filtered, entry)
+	}
+	n := len(entries)
+	// Filter the entries from the newest to the oldest, so that the newest
+	// entry comes first among equally good fuzzy matches, and then reverse
+	// the result, since the newest entries are shown at the bottom.
+	matches := f.filter(p, n,
+		func(i int) string { return entries[n-1-i].Text },
+		func(i int) int { return fuzzy.RecencyBonus(n-1-i, n) })
+	var filtered histlistItems
+	for i := len(matches) - 1; i >= 0; i-- {
+		m := matches[i]
+		filtered.entries = append(filtered.entries, entries[n-1-m.index])
+		filtered.positions = append(filtered.positions, m.positions)
+	}
+	return filtered
+}
+
+func (it histlistItems) scoped(scope func(storedefs.Cmd) bool) histlistItems {
+	var entries []storedefs.Cmd
+	last := map[string]int{}
+	for _, entry := range it.entries {
+		if scope(entry) {
+			last[entry.Text] = len(entries)
+			entries = append(entries, entry)
 		}
 	}
-	return histlistItems{filtered, nil}
+	return histlistItems{entries: entries, last: last}
 }
 
 func (it histlistItems) Show(i int) ui.Text {
 	entry := it.entries[i]
 	// TODO: The alignment of the index works up to 10000 entries.
-	return ui.T(fmt.Sprintf("%4d %s", entry.Seq, entry.Text))
+	if it.positions == nil || it.positions[i] == nil {
+		return ui.T(fmt.Sprintf("%4d %s", entry.Seq, entry.Text))
+	}
+	return ui.Concat(ui.T(fmt.Sprintf("%4d ", entry.Seq)),
+		fuzzy.Highlight(ui.T(entry.Text), it.positions[i]))
 }
 
 func (it histlistItems) Len() int { return len(it.entries) }
@@ -120,6 +120,57 @@ func TestHistlist_Dedup(t *testing.T) {
 		"++++++++++++++++++++++++++++++++++++++++++++++++++")
 }
 
+func TestHistlist_Scope(t *testing.T) {
+	f := Setup()
+	defer f.Stop()
+
+	st := histutil.NewMemStore()
+	for _, cmd := range []storedefs.Cmd{
+		{Text: "ls", Seq: 0, Meta: storedefs.CmdMeta{Dir: "/a", Session: "s1"}},
+		{Text: "echo", Seq: 1, Meta: storedefs.CmdMeta{Dir: "/b", Session: "s1"}},
+		{Text: "ls", Seq: 2, Meta: storedefs.CmdMeta{Dir: "/b", Session: "s2"}},
+	} {
+		st.AddCmd(cmd)
+	}
+	scope := HistlistScopeSession
+	spec := HistlistSpec{
+		AllCmds: st.AllCmds, Scope: func() HistlistScope { return scope },
+		SessionID: "s1", Dir: "/a"}
+
+	// Deduplication only considers commands in the scope.
+	startHistlist(f.App, spec)
+	f.TestTTY(t,
+		"\n",
+		" HISTORY (session) (dedup on)  ", Styles,
+		"****************************** ", term.DotHere, "\n",
+		"   0 ls\n",
+		"   1 echo                                         ", Styles,
+		"++++++++++++++++++++++++++++++++++++++++++++++++++")
+	f.App.PopAddon()
+
+	scope = HistlistScopeDir
+	startHistlist(f.App, spec)
+	f.TestTTY(t,
+		"\n",
+		" HISTORY (dir) (dedup on)  ", Styles,
+		"************************** ", term.DotHere, "\n",
+		"   0 ls                                           ", Styles,
+		"++++++++++++++++++++++++++++++++++++++++++++++++++")
+}
+
+func TestHistlistScope(t *testing.T) {
+	scope := HistlistScopeGlobal
+	for _, want := range []string{"session", "dir", "global"} {
+		scope = scope.Next()
+		if scope.String() != want {
+			t.Errorf("got scope %v, want %v", scope, want)
+		}
+	}
+	if s := HistlistScope(10).String(); s != "!(BAD SCOPE: 10)" {
+		t.Errorf("got %q for bad scope", s)
+	}
+}
+
 func TestHistlist_CustomFilter(t *testing.T) {
 	f := Setup()
 	defer f.Stop()
@@ -152,6 +203,30 @@ func TestHistlist_CustomFilter(t *testing.T) {
 		"++++++++++++++++++++++++++++++++++++++++++++++++++")
 }
 
+func TestHistlist_FuzzyFilter(t *testing.T) {
+	f := Setup()
+	defer f.Stop()
+
+	st := histutil.NewMemStore(
+		// 0        1        2
+		"foo-bar", "fxxxb", "ls")
+
+	startHistlist(f.App, HistlistSpec{
+		AllCmds: st.AllCmds,
+		Filter:  FilterSpec{Fuzzy: func() bool { return true }},
+	})
+	f.TTY.Inject(term.K('f'), term.K('b'))
+	// The best match is at the bottom and selected, even though it is older.
+	f.TestTTY(t,
+		"\n",
+		" HISTORY (dedup on)  fb", Styles,
+		"********************   ", term.DotHere, "\n",
+		"   1 fxxxb\n", Styles,
+		"     b   b",
+		"   0 foo-bar                                      ", fuzzyStyles,
+		"+++++u+++u++++++++++++++++++++++++++++++++++++++++")
+}
+
 func startHistlist(app cli.App, spec HistlistSpec) {
 	w, err := NewHistlist(app, spec)
 	startMode(app, w, err)
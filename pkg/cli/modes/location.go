@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"src.elv.sh/pkg/cli"
+	"src.elv.sh/pkg/cli/fuzzy"
 	"src.elv.sh/pkg/cli/tk"
 	"src.elv.sh/pkg/fsutil"
 	"src.elv.sh/pkg/store/storedefs"
@@ -89,7 +90,7 @@ func NewLocation(app cli.App, cfg LocationSpec) (Location, error) {
 		}
 	}
 
-	l := locationList{dirs}
+	l := locationList{dirs: dirs}
 
 	w := tk.NewComboBox(tk.ComboBoxSpec{
 		CodeArea: tk.CodeAreaSpec{
@@ -111,7 +112,7 @@ func NewLocation(app cli.App, cfg LocationSpec) (Location, error) {
 			},
 		},
 		OnFilter: func(w tk.ComboBox, p string) {
-			w.ListBox().Reset(l.filter(cfg.Filter.makePredicate(p)), 0)
+			w.ListBox().Reset(l.filter(cfg.Filter, p), 0)
 		},
 	})
 	return w, nil
@@ -152,21 +153,32 @@ func (ws LocationWSIterator) Parse(path string) (kind, root string) {
 
 type locationList struct {
 	dirs []storedefs.Dir
+	// Positions of the characters matched by a fuzzy filter, or nil if the
+	// list is not filtered that way.
+	positions [][]int
 }
 
-func (l locationList) filter(p func(string) bool) locationList {
-	var filteredDirs []storedefs.Dir
-	for _, dir := range l.dirs {
-		if p(fsutil.TildeAbbr(dir.Path)) {
-			filteredDirs = append(filteredDirs, dir)
-		}
+func (l locationList) filter(f FilterSpec, p string) locationList {
+	n := len(l.dirs)
+	matches := f.filter(p, n,
+		func(i int) string { return fsutil.TildeAbbr(l.dirs[i].Path) },
+		// Directories are sorted by their scores, which take recency into
+		// account.
+		func(i int) int { return fuzzy.RecencyBonus(n-1-i, n) })
+	var filtered locationList
+	for _, m := range matches {
+		filtered.dirs = append(filtered.dirs, l.dirs[m.index])
+		filtered.positions = append(filtered.positions, m.positions)
 	}
-	return locationList{filteredDirs}
+	return filtered
 }
 
 func (l locationList) Show(i int) ui.Text {
-	return ui.T(fmt.Sprintf("%s %s",
-		showScore(l.dirs[i].Score), fsutil.TildeAbbr(l.dirs[i].Path)))
+	score, path := showScore(l.dirs[i].Score), fsutil.TildeAbbr(l.dirs[i].Path)
+	if l.positions == nil || l.positions[i] == nil {
+		return ui.T(fmt.Sprintf("%s %s", score, path))
+	}
+	return ui.Concat(ui.T(score+" "), fuzzy.Highlight(ui.T(path), l.positions[i]))
 }
 
 func (l locationList) Len() int { return len(l.dirs) }
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"strings"
 	"testing"
@@ -259,3 +260,25 @@ func startLocation(app cli.App, spec LocationSpec) {
 	w, err := NewLocation(app, spec)
 	startMode(app, w, err)
 }
+
+func TestLocationList_FuzzyFilter(t *testing.T) {
+	l := locationList{dirs: []storedefs.Dir{
+		{Path: "/fxxb", Score: 20},
+		{Path: "/foo/bar", Score: 10},
+		{Path: "/ls", Score: 5},
+	}}
+	filtered := l.filter(fuzzyFilter, "fb")
+	var shown []ui.Text
+	for i := 0; i < filtered.Len(); i++ {
+		shown = append(shown, filtered.Show(i))
+	}
+	want := []ui.Text{
+		ui.Concat(ui.T(" 10 "), ui.T("/"), ui.T("f", ui.Bold),
+			ui.T("oo/"), ui.T("b", ui.Bold), ui.T("ar")),
+		ui.Concat(ui.T(" 20 "), ui.T("/"), ui.T("f", ui.Bold),
+			ui.T("xx"), ui.T("b", ui.Bold)),
+	}
+	if !reflect.DeepEqual(shown, want) {
+		t.Errorf("got %v, want %v", shown, want)
+	}
+}
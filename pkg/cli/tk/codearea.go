@@ -36,6 +36,11 @@ type CodeAreaSpec struct {
 	Prompt func() ui.Text
 	// Right-prompt callback.
 	RPrompt func() ui.Text
+	// A function that returns a suggested continuation of the given code,
+	// which is shown after the code when the dot is at the end and there is
+	// no pending code. If this function is not given, the Widget does not
+	// show any suggestion.
+	Autosuggester func(code string) string
 	// A function that calls the callback with string pairs for abbreviations
 	// and their expansions. If no function is provided the Widget does not
 	// expand any abbreviations of the specified type.
@@ -55,10 +60,11 @@ type CodeAreaSpec struct {
 
 // CodeAreaState keeps the mutable state of the CodeArea widget.
 type CodeAreaState struct {
-	Buffer      CodeBuffer
-	Pending     PendingCode
-	HideRPrompt bool
-	HideTips    bool
+	Buffer         CodeBuffer
+	Pending        PendingCode
+	HideRPrompt    bool
+	HideTips       bool
+	HideSuggestion bool
 }
 
 // CodeBuffer represents the buffer of the CodeArea widget.
@@ -126,6 +132,9 @@ func NewCodeArea(spec CodeAreaSpec) CodeArea {
 	if spec.RPrompt == nil {
 		spec.RPrompt = func() ui.Text { return nil }
 	}
+	if spec.Autosuggester == nil {
+		spec.Autosuggester = func(string) string { return "" }
+	}
 	if spec.SimpleAbbreviations == nil {
 		spec.SimpleAbbreviations = func(func(a, f string)) {}
 	}
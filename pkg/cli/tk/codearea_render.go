@@ -8,14 +8,18 @@ import (
 
 // View model, calculated from State and used for rendering.
 type view struct {
-	prompt  ui.Text
-	rprompt ui.Text
-	code    ui.Text
-	dot     int
-	tips    []ui.Text
+	prompt     ui.Text
+	rprompt    ui.Text
+	code       ui.Text
+	dot        int
+	suggestion ui.Text
+	tips       []ui.Text
 }
 
-var stylingForPending = ui.Underlined
+var (
+	stylingForPending    = ui.Underlined
+	stylingForSuggestion = ui.Dim
+)
 
 func getView(w *codeArea) *view {
 	s := w.CopyState()
@@ -31,12 +35,19 @@ func getView(w *codeArea) *view {
 		styledCode = ui.Concat(parts[0], pending, parts[2])
 	}
 
+	var suggestion ui.Text
+	if !s.HideSuggestion && pFrom == pTo && code.Dot == len(code.Content) {
+		if text := w.Autosuggester(code.Content); text != "" {
+			suggestion = ui.T(text, stylingForSuggestion)
+		}
+	}
+
 	var rprompt ui.Text
 	if !s.HideRPrompt {
 		rprompt = w.RPrompt()
 	}
 
-	return &view{w.Prompt(), rprompt, styledCode, code.Dot, errors}
+	return &view{w.Prompt(), rprompt, styledCode, code.Dot, suggestion, errors}
 }
 
 func patchPending(c CodeBuffer, p PendingCode) (CodeBuffer, int, int) {
@@ -76,7 +87,8 @@ func renderView(v *view, buf *term.BufferBuilder) {
 	buf.
 		WriteStyled(parts[0]).
 		SetDotHere().
-		WriteStyled(parts[1])
+		WriteStyled(parts[1]).
+		WriteStyled(v.suggestion)
 
 	buf.EagerWrap = false
 	buf.Indent = 0
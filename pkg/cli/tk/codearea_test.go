@@ -15,6 +15,8 @@ var bb = term.NewBufferBuilder
 
 func p(t ui.Text) func() ui.Text { return func() ui.Text { return t } }
 
+func suggest(s string) func(string) string { return func(string) string { return s } }
+
 var codeAreaRenderTests = []renderTest{
 	{
 		Name: "prompt only",
@@ -168,6 +170,41 @@ var codeAreaRenderTests = []renderTest{
 		Width: 10, Height: 24,
 		Want: bb(10).Write("code").SetDotHere(),
 	},
+	{
+		Name: "suggestion with dot at end",
+		Given: NewCodeArea(CodeAreaSpec{
+			Autosuggester: suggest(" more"),
+			State:         CodeAreaState{Buffer: CodeBuffer{Content: "code", Dot: 4}}}),
+		Width: 10, Height: 24,
+		Want: bb(10).Write("code").SetDotHere().WriteStringSGR(" more", "2"),
+	},
+	{
+		Name: "no suggestion with dot not at end",
+		Given: NewCodeArea(CodeAreaSpec{
+			Autosuggester: suggest(" more"),
+			State:         CodeAreaState{Buffer: CodeBuffer{Content: "code", Dot: 2}}}),
+		Width: 10, Height: 24,
+		Want: bb(10).Write("co").SetDotHere().Write("de"),
+	},
+	{
+		Name: "no suggestion with pending code",
+		Given: NewCodeArea(CodeAreaSpec{
+			Autosuggester: suggest(" more"),
+			State: CodeAreaState{
+				Buffer:  CodeBuffer{Content: "code", Dot: 4},
+				Pending: PendingCode{From: 4, To: 4, Content: "x"}}}),
+		Width: 10, Height: 24,
+		Want: bb(10).Write("code").WriteStringSGR("x", "4").SetDotHere(),
+	},
+	{
+		Name: "suggestion explicitly hidden",
+		Given: NewCodeArea(CodeAreaSpec{
+			Autosuggester: suggest(" more"),
+			State: CodeAreaState{
+				Buffer: CodeBuffer{Content: "code", Dot: 4}, HideSuggestion: true}}),
+		Width: 10, Height: 24,
+		Want: bb(10).Write("code").SetDotHere(),
+	},
 	{
 		Name: "prioritize lines before the cursor with small height",
 		Given: NewCodeArea(CodeAreaSpec{State: CodeAreaState{
@@ -138,14 +138,35 @@ func (c *client) NextCmd(from int, prefix string) (storedefs.Cmd, error) {
 	req := &api.NextCmdRequest{From: from, Prefix: prefix}
 	res := &api.NextCmdResponse{}
 	err := c.call("NextCmd", req, res)
-	return storedefs.Cmd{Text: res.Text, Seq: res.Seq}, err
+	return storedefs.Cmd{Text: res.Text, Seq: res.Seq, Meta: res.Meta}, err
 }
 
 func (c *client) PrevCmd(upto int, prefix string) (storedefs.Cmd, error) {
 	req := &api.PrevCmdRequest{Upto: upto, Prefix: prefix}
 	res := &api.PrevCmdResponse{}
 	err := c.call("PrevCmd", req, res)
-	return storedefs.Cmd{Text: res.Text, Seq: res.Seq}, err
+	return storedefs.Cmd{Text: res.Text, Seq: res.Seq, Meta: res.Meta}, err
+}
+
+func (c *client) AddCmds(cmds []storedefs.Cmd) error {
+	req := &api.AddCmdsRequest{Cmds: cmds}
+	res := &api.AddCmdsResponse{}
+	err := c.call("AddCmds", req, res)
+	return err
+}
+
+func (c *client) SetCmdMeta(seq int, meta storedefs.CmdMeta) error {
+	req := &api.SetCmdMetaRequest{Seq: seq, Meta: meta}
+	res := &api.SetCmdMetaResponse{}
+	err := c.call("SetCmdMeta", req, res)
+	return err
+}
+
+func (c *client) SearchCmds(q storedefs.CmdQuery) ([]storedefs.Cmd, error) {
+	req := &api.SearchCmdsRequest{Query: q}
+	res := &api.SearchCmdsResponse{}
+	err := c.call("SearchCmds", req, res)
+	return res.Cmds, err
 }
 
 func (c *client) AddDir(dir string, incFactor float64) error {
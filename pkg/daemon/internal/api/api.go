@@ -7,7 +7,7 @@ import (
 )
 
 // Version is the API version. It should be bumped any time the API changes.
-const Version = -93
+const Version = -90
 
 // ServiceName is the name of the RPC service exposed by the daemon.
 const ServiceName = "Daemon"
@@ -83,6 +83,7 @@ type NextCmdRequest struct {
 type NextCmdResponse struct {
 	Seq  int
 	Text string
+	Meta storedefs.CmdMeta
 }
 
 type PrevCmdRequest struct {
@@ -93,6 +94,28 @@ type PrevCmdRequest struct {
 type PrevCmdResponse struct {
 	Seq  int
 	Text string
+	Meta storedefs.CmdMeta
+}
+
+type AddCmdsRequest struct {
+	Cmds []storedefs.Cmd
+}
+
+type AddCmdsResponse struct{}
+
+type SetCmdMetaRequest struct {
+	Seq  int
+	Meta storedefs.CmdMeta
+}
+
+type SetCmdMetaResponse struct{}
+
+type SearchCmdsRequest struct {
+	Query storedefs.CmdQuery
+}
+
+type SearchCmdsResponse struct {
+	Cmds []storedefs.Cmd
 }
 
 // Dir requests.
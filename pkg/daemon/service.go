@@ -77,7 +77,7 @@ func (s *service) NextCmd(req *api.NextCmdRequest, res *api.NextCmdResponse) err
 		return s.err
 	}
 	cmd, err := s.store.NextCmd(req.From, req.Prefix)
-	res.Seq, res.Text = cmd.Seq, cmd.Text
+	res.Seq, res.Text, res.Meta = cmd.Seq, cmd.Text, cmd.Meta
 	return err
 }
 
@@ -86,7 +86,30 @@ func (s *service) PrevCmd(req *api.PrevCmdRequest, res *api.PrevCmdResponse) err
 		return s.err
 	}
 	cmd, err := s.store.PrevCmd(req.Upto, req.Prefix)
-	res.Seq, res.Text = cmd.Seq, cmd.Text
+	res.Seq, res.Text, res.Meta = cmd.Seq, cmd.Text, cmd.Meta
+	return err
+}
+
+func (s *service) AddCmds(req *api.AddCmdsRequest, res *api.AddCmdsResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.store.AddCmds(req.Cmds)
+}
+
+func (s *service) SetCmdMeta(req *api.SetCmdMetaRequest, res *api.SetCmdMetaResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.store.SetCmdMeta(req.Seq, req.Meta)
+}
+
+func (s *service) SearchCmds(req *api.SearchCmdsRequest, res *api.SearchCmdsResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	cmds, err := s.store.SearchCmds(req.Query)
+	res.Cmds = cmds
 	return err
 }
 
@@ -0,0 +1,99 @@
+package edit
+
+import (
+	"sync"
+
+	"src.elv.sh/pkg/cli"
+	"src.elv.sh/pkg/cli/tk"
+	"src.elv.sh/pkg/eval"
+)
+
+func initAutosuggest(appSpec *cli.AppSpec, ed *Editor, hs *histStore, nb eval.NsBuilder) {
+	enabled := newBoolVar(true)
+	var cache suggestionCache
+	suggest := func(code string) string {
+		if !enabled.GetRaw().(bool) {
+			return ""
+		}
+		return cache.get(hs, code)
+	}
+	appSpec.Autosuggester = suggest
+
+	acceptAll := func(buffer string, dot int) int { return len(buffer) }
+	nb.AddNs("autosuggest",
+		eval.BuildNsNamed("edit:autosuggest").
+			AddVar("enabled", enabled).
+			AddGoFns(map[string]any{
+				"accept":      func() { acceptSuggestion(ed.app, suggest, acceptAll) },
+				"accept-word": func() { acceptSuggestion(ed.app, suggest, moveDotRightWord) },
+			}))
+}
+
+// Caches the suggestion for the last code, since the suggestion is needed
+// whenever the code area is rendered, and looking it up scans the history.
+type suggestionCache struct {
+	mu         sync.Mutex
+	valid      bool
+	code       string
+	version    int
+	suggestion string
+}
+
+// Returns the suggestion for code, looking it up only if the code or the
+// history has changed since the last lookup.
+func (c *suggestionCache) get(hs *histStore, code string) string {
+	version := hs.Version()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.valid || c.code != code || c.version != version {
+		c.valid, c.code, c.version = true, code, version
+		c.suggestion = suggestFromHistory(hs, code)
+	}
+	return c.suggestion
+}
+
+// Returns the rest of the most recent command in the history that starts with
+// the given code and is not the same as it.
+func suggestFromHistory(hs *histStore, code string) string {
+	if code == "" {
+		return ""
+	}
+	c := hs.Cursor(code)
+	for {
+		c.Prev()
+		cmd, err := c.Get()
+		if err != nil {
+			return ""
+		}
+		if cmd.Text != code {
+			return cmd.Text[len(code):]
+		}
+	}
+}
+
+// Inserts part of the current suggestion into the main code area, up to where
+// the mover would move the dot if the suggestion were part of the buffer. Does
+// nothing if there is no suggestion.
+func acceptSuggestion(app cli.App, suggest func(string) string, m pureMover) {
+	if len(app.CopyState().Addons) > 0 {
+		// Suggestions are not shown when there are addons.
+		return
+	}
+	codeArea, ok := app.ActiveWidget().(tk.CodeArea)
+	if !ok {
+		return
+	}
+	codeArea.MutateState(func(s *tk.CodeAreaState) {
+		buf := &s.Buffer
+		if buf.Dot != len(buf.Content) || s.Pending != (tk.PendingCode{}) {
+			return
+		}
+		suggestion := suggest(buf.Content)
+		if suggestion == "" {
+			return
+		}
+		full := buf.Content + suggestion
+		buf.Dot = m(full, buf.Dot)
+		buf.Content = full[:buf.Dot]
+	})
+}
@@ -0,0 +1,123 @@
+package edit
+
+import (
+	"testing"
+
+	"src.elv.sh/pkg/cli/term"
+	"src.elv.sh/pkg/must"
+	"src.elv.sh/pkg/store"
+	"src.elv.sh/pkg/store/storedefs"
+	"src.elv.sh/pkg/ui"
+)
+
+func TestAutosuggest_ShowsMostRecentMatch(t *testing.T) {
+	f := startAutosuggestTest(t)
+
+	feedInput(f.TTYCtrl, "ec")
+	f.TestTTY(t,
+		"~> ec", Styles,
+		"   !!", term.DotHere,
+		"ho bar", Styles,
+		"dddddd",
+	)
+
+	// The entry that is the same as the buffer is skipped.
+	feedInput(f.TTYCtrl, "ho bar")
+	f.TestTTY(t,
+		"~> echo bar", Styles,
+		"   vvvv    ", term.DotHere,
+		" baz", Styles,
+		"dddd",
+	)
+}
+
+func TestAutosuggest_NoSuggestionWithDotNotAtEnd(t *testing.T) {
+	f := startAutosuggestTest(t)
+
+	feedInput(f.TTYCtrl, "ec")
+	f.TTYCtrl.Inject(term.K(ui.Left))
+	f.TestTTY(t,
+		"~> e", Styles,
+		"   !", term.DotHere,
+		"c", Styles,
+		"!",
+	)
+}
+
+func TestAutosuggest_Disabled(t *testing.T) {
+	f := startAutosuggestTest(t)
+
+	evals(f.Evaler, `set edit:autosuggest:enabled = $false`)
+	feedInput(f.TTYCtrl, "ec")
+	f.TestTTY(t,
+		"~> ec", Styles,
+		"   !!", term.DotHere,
+	)
+}
+
+func TestAutosuggest_Accept(t *testing.T) {
+	f := startAutosuggestTest(t)
+
+	feedInput(f.TTYCtrl, "ec")
+	f.TTYCtrl.Inject(term.K(ui.Right))
+	// The accepted code has a new suggestion.
+	f.TestTTY(t,
+		"~> echo bar", Styles,
+		"   vvvv    ", term.DotHere,
+		" baz", Styles,
+		"dddd",
+	)
+}
+
+func TestAutosuggest_AcceptWord(t *testing.T) {
+	f := startAutosuggestTest(t)
+
+	feedInput(f.TTYCtrl, "ec")
+	f.TTYCtrl.Inject(term.K('f', ui.Alt))
+	f.TestTTY(t,
+		"~> echo ", Styles,
+		"   vvvv ", term.DotHere,
+		"bar", Styles,
+		"ddd",
+	)
+	f.TTYCtrl.Inject(term.K('f', ui.Alt))
+	f.TestTTY(t,
+		"~> echo bar", Styles,
+		"   vvvv    ", term.DotHere,
+		" baz", Styles,
+		"dddd",
+	)
+}
+
+func TestAutosuggest_AcceptWithoutSuggestionMovesDot(t *testing.T) {
+	f := startAutosuggestTest(t)
+
+	feedInput(f.TTYCtrl, "ls")
+	f.TTYCtrl.Inject(term.K(ui.Left), term.K(ui.Right))
+	f.TestTTY(t,
+		"~> ls", Styles,
+		"   !!", term.DotHere,
+	)
+}
+
+func TestSuggestionCache_InvalidatedByHistoryChange(t *testing.T) {
+	st := store.MustTempStore(t)
+	st.AddCmd("echo foo")
+	hs := must.OK1(newHistStore(st))
+	var c suggestionCache
+
+	if s := c.get(hs, "ec"); s != "ho foo" {
+		t.Errorf("got %q, want %q", s, "ho foo")
+	}
+	hs.AddCmd(storedefs.Cmd{Text: "echo bar"})
+	if s := c.get(hs, "ec"); s != "ho bar" {
+		t.Errorf("got %q, want %q", s, "ho bar")
+	}
+}
+
+func startAutosuggestTest(t *testing.T) *fixture {
+	return setup(t, storeOp(func(s storedefs.Store) {
+		s.AddCmd("echo bar baz")
+		s.AddCmd("echo bar")
+	}))
+}
@@ -25,7 +25,9 @@ type Config struct {
 	ArgGenerator ArgGenerator
 }
 
-// Filterer is the type of functions that filter raw candidates.
+// Filterer is the type of functions that filter raw candidates. The candidates
+// are sorted when passed to the Filterer, and are used in the order returned,
+// so a Filterer may also rank them.
 type Filterer func(ctxName, seed string, rawItems []RawItem) []RawItem
 
 // ArgGenerator is the type of functions that generate raw candidates for a
@@ -75,10 +77,12 @@ func Complete(code CodeBuffer, ev *eval.Evaler, cfg Config) (*Result, error) {
 		if err == errNoCompletion {
 			continue
 		}
-		rawItems = cfg.Filterer(ctx.name, ctx.seed, rawItems)
+		// Sort before filtering, so that filterers that rank the items can
+		// keep ties in order.
 		sort.Slice(rawItems, func(i, j int) bool {
 			return rawItems[i].String() < rawItems[j].String()
 		})
+		rawItems = cfg.Filterer(ctx.name, ctx.seed, rawItems)
 		items := make([]modes.CompletionItem, len(rawItems))
 		for i, rawCand := range rawItems {
 			items[i] = rawCand.Cook(ctx.quote)
@@ -0,0 +1,276 @@
+package complete
+
+import (
+	"bufio"
+	"bytes"
+	gocontext "context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"src.elv.sh/pkg/ui"
+)
+
+// Generators that get candidates from the completion systems of other shells
+// and tools. They run external programs, so they are slower than the native
+// generators, and are only used when explicitly requested.
+
+var errBadCarapaceOutput = errors.New("bad output from carapace")
+
+// How long an external command run by the generators may take before it is
+// killed, so that a hanging completion script doesn't hang the editor.
+var foreignTimeout = 5 * time.Second
+
+// Runs an external command and returns its standard output, or an error if it
+// doesn't finish within foreignTimeout. Mocked in tests.
+var outputOf = func(name string, args ...string) ([]byte, error) {
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), foreignTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, name, args...)
+	// Don't wait for processes started by the command that still have its
+	// output open after it's killed.
+	cmd.WaitDelay = foreignTimeout / 10
+	out, err := cmd.Output()
+	if ctx.Err() == gocontext.DeadlineExceeded {
+		return nil, fmt.Errorf("%s didn't finish within %v", name, foreignTimeout)
+	}
+	return out, err
+}
+
+// GenerateFromFish returns candidates from the completions of fish, by running
+// "fish" to complete the command line formed by args. Descriptions provided by
+// fish are kept.
+func GenerateFromFish(args []string) ([]RawItem, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	// When the last argument is empty, the command line ends with a space,
+	// which is what fish expects.
+	escaped := make([]string, len(args))
+	for i, arg := range args {
+		escaped[i] = fishEscape(arg)
+	}
+	out, err := outputOf("fish",
+		"-c", "complete --do-complete=$argv[1]", strings.Join(escaped, " "))
+	if err != nil {
+		return nil, err
+	}
+	return parseFishOutput(out), nil
+}
+
+// Escapes s for fish with backslashes. Quotes are not used, since fish only
+// completes the content of quoted words and doesn't quote the candidates.
+func fishEscape(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if !isFishBareRune(r) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func isFishBareRune(r rune) bool {
+	return r >= 0x80 || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') ||
+		('0' <= r && r <= '9') || strings.ContainsRune("_-./=:,+@%^", r)
+}
+
+// Parses the output of "complete --do-complete", which has one candidate per
+// line, optionally followed by a tab and a description.
+func parseFishOutput(out []byte) []RawItem {
+	var items []RawItem
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		stem, desc, _ := strings.Cut(scanner.Text(), "\t")
+		if stem == "" {
+			continue
+		}
+		items = append(items, ComplexItem{
+			Stem: stem, CodeSuffix: suffixFor(stem), Description: desc})
+	}
+	return items
+}
+
+// The bash script used to call a completion function. The arguments, starting
+// from $1, are the path of a script to source (may be empty), the command line
+// and the words to complete.
+//
+// Completions defined with "complete -W" or other options are not supported;
+// only completion functions are called.
+const bashCompletionScript = `
+script=$1
+line=$2
+shift 2
+if [ -n "$script" ]; then
+	. "$script" >/dev/null 2>&1
+fi
+spec=$(complete -p -- "$1" 2>/dev/null)
+if [ -z "$spec" ] && declare -F _completion_loader >/dev/null; then
+	_completion_loader "$1" >/dev/null 2>&1
+	spec=$(complete -p -- "$1" 2>/dev/null)
+fi
+[[ $spec =~ -F\ ([^ ]+) ]] || exit 0
+COMP_WORDS=("$@")
+COMP_CWORD=$(($# - 1))
+COMP_LINE=$line
+COMP_POINT=${#COMP_LINE}
+COMP_TYPE=9
+COMP_KEY=9
+COMPREPLY=()
+"${BASH_REMATCH[1]}" "$1" "${COMP_WORDS[COMP_CWORD]}" "${COMP_WORDS[COMP_CWORD-1]}" \
+	>/dev/null 2>&1
+printf '%s\n' "${COMPREPLY[@]}"
+`
+
+// Characters of the default COMP_WORDBREAKS of bash that can appear in Elvish
+// arguments. Bash splits words at them before calling completion functions,
+// which many functions rely on to complete values after "--opt=" or "host:".
+const bashWordbreaks = "=:"
+
+// Splits args into words at bashWordbreaks like bash, with each run of
+// wordbreak characters becoming a word of its own. Also returns the part of
+// the last argument up to and including its last wordbreak character, which
+// bash doesn't pass to completion functions as part of the current word.
+func splitBashWords(args []string) ([]string, string) {
+	var words []string
+	for _, arg := range args {
+		for arg != "" {
+			i := strings.IndexAny(arg, bashWordbreaks)
+			if i == -1 {
+				i = len(arg)
+			} else if i == 0 {
+				i = len(arg) - len(strings.TrimLeft(arg, bashWordbreaks))
+			}
+			words = append(words, arg[:i])
+			arg = arg[i:]
+		}
+	}
+	last := args[len(args)-1]
+	if last == "" {
+		// The current word is empty.
+		words = append(words, "")
+	}
+	prefix := last[:strings.LastIndexAny(last, bashWordbreaks)+1]
+	return words, prefix
+}
+
+// Paths of the main script of the bash-completion project, which sets up
+// on-demand loading of completions for most commands.
+var bashCompletionPaths = []string{
+	"/usr/share/bash-completion/bash_completion",
+	"/usr/local/share/bash-completion/bash_completion",
+	"/opt/homebrew/share/bash-completion/bash_completion",
+	"/etc/bash_completion",
+}
+
+// GenerateFromBash returns candidates from the completion function registered
+// for the command in bash with "complete -F". It runs "bash", sources the given
+// script (if not empty) to set up completions, and then calls the completion
+// function with the arguments as COMP_WORDS, split at "=" and ":" like bash
+// does.
+//
+// If the script is empty, the main script of bash-completion is sourced if it
+// can be found.
+func GenerateFromBash(args []string, script string) ([]RawItem, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	if script == "" {
+		for _, path := range bashCompletionPaths {
+			if _, err := os.Stat(path); err == nil {
+				script = path
+				break
+			}
+		}
+	}
+	words, prefix := splitBashWords(args)
+	bashArgs := append([]string{"-c", bashCompletionScript, "bash",
+		script, strings.Join(args, " ")}, words...)
+	out, err := outputOf("bash", bashArgs...)
+	if err != nil {
+		return nil, err
+	}
+	var items []RawItem
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		// Some completion functions add a trailing space themselves.
+		reply := strings.TrimRight(scanner.Text(), " ")
+		if reply == "" {
+			continue
+		}
+		// The replies only complete the part after the last wordbreak.
+		stem := prefix + reply
+		items = append(items, ComplexItem{Stem: stem, CodeSuffix: suffixFor(stem)})
+	}
+	return items, nil
+}
+
+// The output of "carapace <command> export".
+type carapaceExport struct {
+	Nospace string
+	Values  []struct {
+		Value       string
+		Display     string
+		Description string
+	}
+}
+
+// GenerateFromCarapace returns candidates from carapace, by running "carapace
+// <command> export" with the arguments. This supports both the completers
+// built into carapace and user-defined specs, since carapace loads the
+// latter itself.
+func GenerateFromCarapace(args []string) ([]RawItem, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	out, err := outputOf("carapace", append([]string{args[0], "export"}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	var export carapaceExport
+	if err := json.Unmarshal(out, &export); err != nil {
+		return nil, errBadCarapaceOutput
+	}
+	var items []RawItem
+	for _, v := range export.Values {
+		if v.Value == "" {
+			continue
+		}
+		item := ComplexItem{Stem: v.Value, Description: v.Description}
+		if v.Display != "" && v.Display != v.Value {
+			item.Display = ui.T(v.Display)
+		}
+		if !carapaceNospace(export.Nospace, v.Value) {
+			item.CodeSuffix = " "
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// Reports whether no space should be added after a value, according to the
+// "nospace" field of carapace, which contains the characters after which no
+// space is added, or "*" for all values.
+func carapaceNospace(nospace, value string) bool {
+	if nospace == "*" {
+		return true
+	}
+	r, _ := utf8.DecodeLastRuneInString(value)
+	return value != "" && strings.ContainsRune(nospace, r)
+}
+
+// Returns the code suffix for a candidate from a shell that doesn't tell when
+// to add a space: a space is added unless the candidate looks incomplete,
+// like a directory or an option that takes a value.
+func suffixFor(stem string) string {
+	if strings.HasSuffix(stem, "/") || strings.HasSuffix(stem, "=") {
+		return ""
+	}
+	return " "
+}
@@ -0,0 +1,209 @@
+package complete
+
+import (
+	"errors"
+	"os/exec"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+
+	"src.elv.sh/pkg/testutil"
+	"src.elv.sh/pkg/tt"
+	"src.elv.sh/pkg/ui"
+)
+
+// Mocks outputOf, returning the given output and recording the command line
+// it's called with.
+func mockOutputOf(t *testing.T, out string, err error) *[]string {
+	var cmdline []string
+	testutil.Set(t, &outputOf, func(name string, args ...string) ([]byte, error) {
+		cmdline = append([]string{name}, args...)
+		return []byte(out), err
+	})
+	return &cmdline
+}
+
+func TestGenerateFromFish(t *testing.T) {
+	cmdline := mockOutputOf(t,
+		"checkout\tSwitch branches\n"+
+			"src/\n"+
+			"--format=\tPretty-print format\n", nil)
+
+	items, err := GenerateFromFish([]string{"git", "a b", ""})
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	wantCmdline := []string{
+		"fish", "-c", "complete --do-complete=$argv[1]", `git a\ b `}
+	if !reflect.DeepEqual(*cmdline, wantCmdline) {
+		t.Errorf("got command line %q, want %q", *cmdline, wantCmdline)
+	}
+	wantItems := []RawItem{
+		ComplexItem{Stem: "checkout", CodeSuffix: " ", Description: "Switch branches"},
+		ComplexItem{Stem: "src/"},
+		ComplexItem{Stem: "--format=", Description: "Pretty-print format"},
+	}
+	if !reflect.DeepEqual(items, wantItems) {
+		t.Errorf("got items %v, want %v", items, wantItems)
+	}
+}
+
+func TestGenerateFromFish_Error(t *testing.T) {
+	errMock := errors.New("mock error")
+	mockOutputOf(t, "", errMock)
+
+	_, err := GenerateFromFish([]string{"git", ""})
+	if err != errMock {
+		t.Errorf("got error %v, want %v", err, errMock)
+	}
+}
+
+func TestFishEscape(t *testing.T) {
+	tt.Test(t, fishEscape,
+		Args("foo-bar/1.txt").Rets("foo-bar/1.txt"),
+		Args("a b").Rets(`a\ b`),
+		Args(`$x'"`).Rets(`\$x\'\"`),
+		Args("你好").Rets("你好"),
+	)
+}
+
+func TestGenerateFromBash(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("bash completion is not tested on Windows")
+	}
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+	testutil.InTempDir(t)
+	testutil.ApplyDir(testutil.Dir{
+		"foo.bash": `
+_foo() {
+	if [ "$3" = --file ]; then
+		COMPREPLY=(src/)
+	else
+		COMPREPLY=($(compgen -W "bar baz --file --opt=" -- "$2"))
+	fi
+}
+complete -F _foo foo
+`,
+	})
+
+	tt.Test(t, tt.Fn(GenerateFromBash).Named("GenerateFromBash"),
+		Args([]string{"foo", ""}, "./foo.bash").Rets([]RawItem{
+			ComplexItem{Stem: "bar", CodeSuffix: " "},
+			ComplexItem{Stem: "baz", CodeSuffix: " "},
+			ComplexItem{Stem: "--file", CodeSuffix: " "},
+			ComplexItem{Stem: "--opt="},
+		}, nil),
+		Args([]string{"foo", "x", "b"}, "./foo.bash").Rets([]RawItem{
+			ComplexItem{Stem: "bar", CodeSuffix: " "},
+			ComplexItem{Stem: "baz", CodeSuffix: " "},
+		}, nil),
+		Args([]string{"foo", "--file", ""}, "./foo.bash").Rets([]RawItem{
+			ComplexItem{Stem: "src/"},
+		}, nil),
+		// Words are split at "=" and ":", and the part before the current
+		// word is added back.
+		Args([]string{"foo", "--opt=b"}, "./foo.bash").Rets([]RawItem{
+			ComplexItem{Stem: "--opt=bar", CodeSuffix: " "},
+			ComplexItem{Stem: "--opt=baz", CodeSuffix: " "},
+		}, nil),
+		Args([]string{"foo", "host:path:b"}, "./foo.bash").Rets([]RawItem{
+			ComplexItem{Stem: "host:path:bar", CodeSuffix: " "},
+			ComplexItem{Stem: "host:path:baz", CodeSuffix: " "},
+		}, nil),
+		// No completion function.
+		Args([]string{"bar", ""}, "./foo.bash").Rets([]RawItem(nil), nil),
+	)
+}
+
+func TestGenerateFromBash_Timeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("bash completion is not tested on Windows")
+	}
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+	testutil.Set(t, &foreignTimeout, 100*time.Millisecond)
+	testutil.InTempDir(t)
+	testutil.ApplyDir(testutil.Dir{
+		"foo.bash": "_foo() { sleep 10; }\ncomplete -F _foo foo\n",
+	})
+
+	_, err := GenerateFromBash([]string{"foo", ""}, "./foo.bash")
+	if want := "bash didn't finish within 100ms"; err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %v", err, want)
+	}
+}
+
+func TestSplitBashWords(t *testing.T) {
+	tt.Test(t, splitBashWords,
+		Args([]string{"foo", ""}).Rets([]string{"foo", ""}, ""),
+		Args([]string{"foo", "--opt=val"}).Rets([]string{"foo", "--opt", "=", "val"}, "--opt="),
+		Args([]string{"foo", "--opt="}).Rets([]string{"foo", "--opt", "="}, "--opt="),
+		Args([]string{"foo", "a:=b", "c"}).Rets([]string{"foo", "a", ":=", "b", "c"}, ""),
+	)
+}
+
+func TestGenerateFromCarapace(t *testing.T) {
+	cmdline := mockOutputOf(t, `{
+		"version": "v1.0.0",
+		"nospace": "/",
+		"values": [
+			{"value": "checkout", "display": "checkout", "description": "Switch branches"},
+			{"value": "src/", "display": "src/"},
+			{"value": "origin/main", "display": "main"}
+		]
+	}`, nil)
+
+	items, err := GenerateFromCarapace([]string{"git", ""})
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	wantCmdline := []string{"carapace", "git", "export", "git", ""}
+	if !reflect.DeepEqual(*cmdline, wantCmdline) {
+		t.Errorf("got command line %q, want %q", *cmdline, wantCmdline)
+	}
+	wantItems := []RawItem{
+		ComplexItem{Stem: "checkout", CodeSuffix: " ", Description: "Switch branches"},
+		ComplexItem{Stem: "src/"},
+		ComplexItem{Stem: "origin/main", CodeSuffix: " ", Display: ui.T("main")},
+	}
+	if !reflect.DeepEqual(items, wantItems) {
+		t.Errorf("got items %v, want %v", items, wantItems)
+	}
+}
+
+func TestGenerateFromCarapace_NospaceForAll(t *testing.T) {
+	mockOutputOf(t, `{"nospace": "*", "values": [{"value": "foo"}]}`, nil)
+
+	items, _ := GenerateFromCarapace([]string{"foo", ""})
+	wantItems := []RawItem{ComplexItem{Stem: "foo"}}
+	if !reflect.DeepEqual(items, wantItems) {
+		t.Errorf("got items %v, want %v", items, wantItems)
+	}
+}
+
+func TestGenerateFromCarapace_BadOutput(t *testing.T) {
+	mockOutputOf(t, "not json", nil)
+
+	_, err := GenerateFromCarapace([]string{"foo", ""})
+	if err != errBadCarapaceOutput {
+		t.Errorf("got error %v, want %v", err, errBadCarapaceOutput)
+	}
+}
+
+func TestForeignGenerators_NoArgs(t *testing.T) {
+	mockOutputOf(t, "", errors.New("should not be called"))
+	for _, gen := range []ArgGenerator{
+		GenerateFromFish,
+		GenerateFromCarapace,
+		func(args []string) ([]RawItem, error) { return GenerateFromBash(args, "") },
+	} {
+		items, err := gen(nil)
+		if items != nil || err != nil {
+			t.Errorf("got (%v, %v), want (nil, nil)", items, err)
+		}
+	}
+}
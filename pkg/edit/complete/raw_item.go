@@ -30,9 +30,10 @@ func (nq noQuoteItem) Cook(parse.PrimaryType) modes.CompletionItem {
 
 // ComplexItem is an implementation of RawItem that offers customization options.
 type ComplexItem struct {
-	Stem       string  // Used in the code and the menu.
-	CodeSuffix string  // Appended to the code.
-	Display    ui.Text // How the item is displayed. If empty, defaults to ui.T(Stem).
+	Stem        string  // Used in the code and the menu.
+	CodeSuffix  string  // Appended to the code.
+	Display     ui.Text // How the item is displayed. If empty, defaults to ui.T(Stem).
+	Description string  // Shown after the item in the menu. Optional.
 }
 
 func (c ComplexItem) String() string { return c.Stem }
@@ -44,7 +45,8 @@ func (c ComplexItem) Cook(q parse.PrimaryType) modes.CompletionItem {
 		display = ui.T(c.Stem)
 	}
 	return modes.CompletionItem{
-		ToInsert: quoted + c.CodeSuffix,
-		ToShow:   display,
+		ToInsert:    quoted + c.CodeSuffix,
+		ToShow:      display,
+		Description: c.Description,
 	}
 }
@@ -0,0 +1,19 @@
+package edit
+
+import (
+	"src.elv.sh/pkg/edit/complete"
+	"src.elv.sh/pkg/eval"
+)
+
+type completeBashOpts struct {
+	Script string
+}
+
+func (*completeBashOpts) SetDefaultOptions() {}
+
+func completeBash(fm *eval.Frame, opts completeBashOpts, args ...string) error {
+	gen := func(args []string) ([]complete.RawItem, error) {
+		return complete.GenerateFromBash(args, opts.Script)
+	}
+	return wrapArgGenerator(gen)(fm, args...)
+}
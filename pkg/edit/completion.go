@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"unicode/utf8"
 
+	"src.elv.sh/pkg/cli/fuzzy"
 	"src.elv.sh/pkg/cli/modes"
 	"src.elv.sh/pkg/cli/tk"
 	"src.elv.sh/pkg/edit/complete"
@@ -23,8 +25,9 @@ import (
 )
 
 type complexCandidateOpts struct {
-	CodeSuffix string
-	Display    any
+	CodeSuffix  string
+	Display     any
+	Description string
 }
 
 func (*complexCandidateOpts) SetDefaultOptions() {}
@@ -43,9 +46,10 @@ func complexCandidate(fm *eval.Frame, opts complexCandidateOpts, stem string) (c
 			Valid: "string or styled", Actual: vals.ReprPlain(displayOpt)}
 	}
 	return complexItem{
-		Stem:       stem,
-		CodeSuffix: opts.CodeSuffix,
-		Display:    display,
+		Stem:        stem,
+		CodeSuffix:  opts.CodeSuffix,
+		Display:     display,
+		Description: opts.Description,
 	}, nil
 }
 
@@ -95,7 +99,7 @@ func completionStart(ed *Editor, bindings tk.Bindings, ev *eval.Evaler, cfg comp
 	}
 	w, err := modes.NewCompletion(ed.app, modes.CompletionSpec{
 		Name: result.Name, Replace: result.Replace, Items: result.Items,
-		Filter: filterSpec, Bindings: bindings,
+		Filter: ed.filterSpec, Bindings: bindings,
 	})
 	if w != nil {
 		ed.app.PushAddon(w)
@@ -126,10 +130,14 @@ func initCompletion(ed *Editor, ev *eval.Evaler, nb eval.NsBuilder) {
 		"complete-dirname":  wrapArgGenerator(complete.GenerateDirNames),
 		"complete-getopt":   completeGetopt,
 		"complete-sudo":     wrapArgGenerator(generateForSudo),
+		"complete-bash":     completeBash,
+		"complete-fish":     wrapArgGenerator(complete.GenerateFromFish),
+		"complete-carapace": wrapArgGenerator(complete.GenerateFromCarapace),
 		"complex-candidate": complexCandidate,
 		"match-prefix":      wrapMatcher(strings.HasPrefix),
 		"match-subseq":      wrapMatcher(strutil.HasSubseq),
 		"match-substr":      wrapMatcher(strings.Contains),
+		"match-fuzzy":       matchFuzzy,
 	})
 	app := ed.app
 	nb.AddNs("completion",
@@ -163,12 +171,14 @@ func (c complexItem) Index(k any) (any, bool) {
 		return c.CodeSuffix, true
 	case "display":
 		return c.Display, true
+	case "description":
+		return c.Description, true
 	}
 	return nil, false
 }
 
 func (c complexItem) IterateKeys(f func(any) bool) {
-	vals.Feed(f, "stem", "code-suffix", "display")
+	vals.Feed(f, "stem", "code-suffix", "display", "description")
 }
 
 func (c complexItem) Kind() string { return "map" }
@@ -176,21 +186,29 @@ func (c complexItem) Kind() string { return "map" }
 func (c complexItem) Equal(a any) bool {
 	rhs, ok := a.(complexItem)
 	return ok && c.Stem == rhs.Stem &&
-		c.CodeSuffix == rhs.CodeSuffix && reflect.DeepEqual(c.Display, rhs.Display)
+		c.CodeSuffix == rhs.CodeSuffix && reflect.DeepEqual(c.Display, rhs.Display) &&
+		c.Description == rhs.Description
 }
 
 func (c complexItem) Hash() uint32 {
 	h := hash.DJBInit
 	h = hash.DJBCombine(h, hash.String(c.Stem))
 	h = hash.DJBCombine(h, hash.String(c.CodeSuffix))
+	h = hash.DJBCombine(h, hash.String(c.Description))
 	// TODO: Add c.Display
 	return h
 }
 
 func (c complexItem) Repr(indent int) string {
 	// TODO(xiaq): Pretty-print when indent >= 0
-	return fmt.Sprintf("(edit:complex-candidate %s &code-suffix=%s &display=%s)",
-		parse.Quote(c.Stem), parse.Quote(c.CodeSuffix), vals.Repr(c.Display, indent+1))
+	var description string
+	if c.Description != "" {
+		// Only shown when non-empty to keep the common case short.
+		description = " &description=" + parse.Quote(c.Description)
+	}
+	return fmt.Sprintf("(edit:complex-candidate %s &code-suffix=%s &display=%s%s)",
+		parse.Quote(c.Stem), parse.Quote(c.CodeSuffix), vals.Repr(c.Display, indent+1),
+		description)
 }
 
 type wrappedArgGenerator func(*eval.Frame, ...string) error
@@ -284,6 +302,33 @@ func wrapMatcher(m matcher) wrappedMatcher {
 	}
 }
 
+// Outputs the fuzzy matching score of each input, or $false if it doesn't
+// match. The scores are used by adaptMatcherMap to rank the candidates.
+func matchFuzzy(fm *eval.Frame, opts matcherOpts, seed string, inputs eval.Inputs) error {
+	out := fm.ValueOutput()
+	if opts.IgnoreCase {
+		seed = strings.ToLower(seed)
+	}
+	// The fuzzy matcher already matches lower-case seeds case-insensitively,
+	// so &smart-case needs no handling.
+	var errOut error
+	inputs(func(v any) {
+		if errOut != nil {
+			return
+		}
+		text := vals.ToString(v)
+		if opts.IgnoreCase {
+			text = strings.ToLower(text)
+		}
+		if score, _, ok := fuzzy.Match(seed, text); ok {
+			errOut = out.Put(score)
+		} else {
+			errOut = out.Put(false)
+		}
+	})
+	return errOut
+}
+
 // Adapts $edit:completion:matcher into a Filterer.
 func adaptMatcherMap(nt notifier, ev *eval.Evaler, m vals.Map) complete.Filterer {
 	return func(ctxName, seed string, rawItems []complete.RawItem) []complete.RawItem {
@@ -333,11 +378,33 @@ func adaptMatcherMap(nt notifier, ev *eval.Evaler, m vals.Map) complete.Filterer
 				"matcher has output %v values, not equal to %v inputs",
 				len(outputs), len(rawItems))
 		}
-		filtered := []complete.RawItem{}
+		// A number output keeps the candidate and gives it a score; candidates
+		// are then ranked by their scores, with other kept candidates having a
+		// score of 0.
+		type scoredItem struct {
+			item  complete.RawItem
+			score float64
+		}
+		var kept []scoredItem
+		ranked := false
 		for i := 0; i < len(rawItems) && i < len(outputs); i++ {
-			if vals.Bool(outputs[i]) {
-				filtered = append(filtered, rawItems[i])
+			var score float64
+			if vals.Kind(outputs[i]) == "number" {
+				ranked = true
+				vals.ScanToGo(outputs[i], &score)
+			} else if !vals.Bool(outputs[i]) {
+				continue
 			}
+			kept = append(kept, scoredItem{rawItems[i], score})
+		}
+		if ranked {
+			sort.SliceStable(kept, func(i, j int) bool {
+				return kept[i].score > kept[j].score
+			})
+		}
+		filtered := make([]complete.RawItem, len(kept))
+		for i, k := range kept {
+			filtered[i] = k.item
 		}
 		return filtered
 	}
@@ -121,6 +121,32 @@ func TestCompletionArgCompleter_ArgsAndValueOutput(t *testing.T) {
 		"foo-args", vals.MakeList("foo", "foo1", "foo2", ""))
 }
 
+func TestCompletionArgCompleter_Descriptions(t *testing.T) {
+	f := setup(t)
+	styles := ui.RuneStylesheet{
+		'+': Styles['+'],
+		'd': Styles['d'],
+		'D': ui.Stylings(ui.Inverse, ui.Dim),
+	}
+
+	evals(f.Evaler,
+		`fn foo { }`,
+		`set edit:completion:arg-completer[foo] = {|@args|
+		   edit:complex-candidate bar &description='the bar'
+		   edit:complex-candidate lorem &description=ipsum
+		 }`)
+
+	feedInput(f.TTYCtrl, "foo \t")
+	f.TestTTY(t,
+		"~> foo bar\n", Styles,
+		"   vvv ___",
+		" COMPLETING argument  ", Styles,
+		"********************* ", term.DotHere, "\n",
+		"bar    the bar  lorem    ipsum", styles,
+		"+++++++DDDDDDD           ddddd",
+	)
+}
+
 func TestCompletionArgCompleter_BytesOutput(t *testing.T) {
 	f := setup(t)
 
@@ -172,6 +198,23 @@ func TestCompletionMatcher(t *testing.T) {
 	)
 }
 
+func TestCompletionMatcher_Ranking(t *testing.T) {
+	f := setup(t)
+
+	testutil.ApplyDir(testutil.Dir{"agcob": "", "git-checkout": "", "gcc": ""})
+
+	evals(f.Evaler, `set edit:completion:matcher[''] = $edit:match-fuzzy~`)
+	feedInput(f.TTYCtrl, "echo gco\t")
+	f.TestTTY(t,
+		"~> echo git-checkout \n", Styles,
+		"   vvvv _____________",
+		" COMPLETING argument  ", Styles,
+		"********************* ", term.DotHere, "\n",
+		"git-checkout  agcob", Styles,
+		"++++++++++++       ",
+	)
+}
+
 func TestBuiltinMatchers(t *testing.T) {
 	f := setup(t)
 
@@ -189,11 +232,26 @@ func TestBuiltinMatchers(t *testing.T) {
 	testThatOutputErrorIsBubbled(t, f, "edit:match-prefix ab [ab]")
 }
 
+func TestMatchFuzzy(t *testing.T) {
+	f := setup(t)
+
+	evals(f.Evaler,
+		`var @kinds = (edit:match-fuzzy gco [git-checkout gcc] | each $kind-of~)`,
+		`var @ignore-case = (edit:match-fuzzy &ignore-case GCO [git-checkout] | each $kind-of~)`,
+	)
+	testGlobals(t, f.Evaler, map[string]any{
+		"kinds":       vals.MakeList("number", "bool"),
+		"ignore-case": vals.MakeList("number"),
+	})
+
+	testThatOutputErrorIsBubbled(t, f, "edit:match-fuzzy ab [ab]")
+}
+
 func TestBuiltinMatchers_Options(t *testing.T) {
 	f := setup(t)
 
-	// The two options work identically on all the builtin matchers, so we only
-	// test for match-prefix for simplicity.
+	// The two options work identically on all the builtin matchers except
+	// match-fuzzy, so we only test for match-prefix for simplicity.
 	evals(f.Evaler,
 		`var @a = (edit:match-prefix &ignore-case ab [abc aBc AbC])`,
 		`var @b = (edit:match-prefix &ignore-case aB [abc aBc AbC])`,
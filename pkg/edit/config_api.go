@@ -41,19 +41,26 @@ func initAfterReadline(appSpec *cli.AppSpec, ev *eval.Evaler, nb eval.NsBuilder)
 	})
 }
 
-func initAddCmdFilters(appSpec *cli.AppSpec, ev *eval.Evaler, nb eval.NsBuilder, s histutil.Store) {
+func initAddCmdFilters(appSpec *cli.AppSpec, ed *Editor, ev *eval.Evaler, nb eval.NsBuilder, s histutil.Store) {
 	ignoreLeadingSpace := eval.NewGoFn("<ignore-cmd-with-leading-space>",
 		func(s string) bool { return !strings.HasPrefix(s, " ") })
 	filters := newListVar(vals.MakeList(ignoreLeadingSpace))
 	nb.AddVar("add-cmd-filters", filters)
 
 	appSpec.AfterReadline = append(appSpec.AfterReadline, func(code string) {
+		ed.runningCmd = storedefs.Cmd{Seq: -1}
 		if code != "" &&
 			callFilters(ev, "$<edit>:add-cmd-filters",
 				filters.Get().(vals.List), code) {
-			s.AddCmd(storedefs.Cmd{Text: code, Seq: -1})
+			cmd := storedefs.Cmd{Text: code, Seq: -1, Meta: ed.startCmdMeta()}
+			seq, err := s.AddCmd(cmd)
+			if err != nil {
+				ed.notifyError("history", err)
+				return
+			}
+			cmd.Seq = seq
+			ed.runningCmd = cmd
 		}
-		// TODO(xiaq): Handle the error.
 	})
 }
 
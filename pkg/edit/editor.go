@@ -12,6 +12,7 @@ import (
 	"sync/atomic"
 
 	"src.elv.sh/pkg/cli"
+	"src.elv.sh/pkg/cli/modes"
 	"src.elv.sh/pkg/eval"
 	"src.elv.sh/pkg/eval/vals"
 	"src.elv.sh/pkg/eval/vars"
@@ -33,10 +34,20 @@ type Editor struct {
 	// edit:completion:smart-start to apply the autofix easily. This field is
 	// set in initHighlighter.
 	applyAutofix func()
+	// Configuration for the filter of listing modes, set in initFilter.
+	filterSpec modes.FilterSpec
 
 	// Maybe move this to another type that represents the REPL cycle as a whole, not just the
 	// read/edit portion represented by the Editor type.
 	AfterCommand []func(src parse.Source, duration float64, err error)
+
+	// ID of the interactive session, recorded in the metadata of command
+	// history entries.
+	sessionID string
+	// The command history entry of the command being run. Its metadata is
+	// completed and saved when the command finishes. The Seq field is -1 when
+	// there is no such entry.
+	runningCmd storedefs.Cmd
 }
 
 // An interface that wraps notifyf and notifyError. It is only implemented by
@@ -53,7 +64,8 @@ type notifier interface {
 func NewEditor(tty cli.TTY, ev *eval.Evaler, st storedefs.Store) *Editor {
 	// Declare the Editor with a nil App first; some initialization functions
 	// require a notifier as an argument, but does not use it immediately.
-	ed := &Editor{excList: vals.EmptyList}
+	ed := &Editor{excList: vals.EmptyList,
+		sessionID: newSessionID(), runningCmd: storedefs.Cmd{Seq: -1}}
 	ed.autofix.Store("")
 	nb := eval.BuildNsNamed("edit")
 	appSpec := cli.AppSpec{TTY: tty}
@@ -65,16 +77,18 @@ func NewEditor(tty cli.TTY, ev *eval.Evaler, st storedefs.Store) *Editor {
 
 	initMaxHeight(&appSpec, nb)
 	initReadlineHooks(&appSpec, ev, nb)
-	initAddCmdFilters(&appSpec, ev, nb, hs)
+	initAddCmdFilters(&appSpec, ed, ev, nb, hs)
 	initGlobalBindings(&appSpec, ed, ev, nb)
 	initInsertAPI(&appSpec, ed, ev, nb)
 	initHighlighter(&appSpec, ed, ev, nb)
 	initPrompts(&appSpec, ed, ev, nb)
+	initAutosuggest(&appSpec, ed, hs, nb)
 	ed.app = cli.NewApp(appSpec)
 
 	initExceptionsAPI(ed, nb)
 	initVarsAPI(nb)
 	initCommandAPI(ed, ev, nb)
+	initFilter(ed, nb)
 	initListings(ed, ev, st, hs, nb)
 	initNavigation(ed, ev, nb)
 	initCompletion(ed, ev, nb)
@@ -82,7 +96,7 @@ func NewEditor(tty cli.TTY, ev *eval.Evaler, st storedefs.Store) *Editor {
 	initInstant(ed, ev, nb)
 	initMinibuf(ed, ev, nb)
 
-	initRepl(ed, ev, nb)
+	initRepl(ed, ev, st, nb)
 	initBufferBuiltins(ed.app, nb)
 	initTTYBuiltins(ed.app, tty, nb)
 	initMiscBuiltins(ed, nb)
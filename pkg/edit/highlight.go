@@ -4,16 +4,20 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
 
 	"src.elv.sh/pkg/cli"
 	"src.elv.sh/pkg/edit/highlight"
 	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/errs"
+	"src.elv.sh/pkg/eval/vals"
 	"src.elv.sh/pkg/fsutil"
 	"src.elv.sh/pkg/parse"
 	"src.elv.sh/pkg/ui"
 )
 
 func initHighlighter(appSpec *cli.AppSpec, ed *Editor, ev *eval.Evaler, nb eval.NsBuilder) {
+	theme := newThemeVar()
 	hl := highlight.NewHighlighter(highlight.Config{
 		Check: func(t parse.Tree) (string, []*eval.CompilationError) {
 			autofixes, err := ev.CheckTree(t, nil)
@@ -27,7 +31,9 @@ func initHighlighter(appSpec *cli.AppSpec, ed *Editor, ev *eval.Evaler, nb eval.
 				bindingTip("autofix: "+autofix, "apply-autofix"),
 				bindingTip("autofix first", "smart-enter", "completion:smart-start"))
 		},
+		Theme: theme.Theme,
 	})
+	theme.onSet = hl.InvalidateCache
 	appSpec.Highlighter = hl
 	ed.applyAutofix = func() {
 		code := ed.autofix.Load().(string)
@@ -42,6 +48,85 @@ func initHighlighter(appSpec *cli.AppSpec, ed *Editor, ev *eval.Evaler, nb eval.
 		hl.InvalidateCache()
 	}
 	nb.AddGoFn("apply-autofix", ed.applyAutofix)
+	nb.AddNs("highlight",
+		eval.BuildNsNamed("edit:highlight").
+			AddVar("theme", theme))
+}
+
+// The variable $edit:highlight:theme. It only accepts maps from the names of
+// region types to styling specs, and keeps the parsed theme.
+type themeVar struct {
+	mutex sync.RWMutex
+	m     vals.Map
+	theme highlight.Theme
+	onSet func()
+}
+
+func newThemeVar() *themeVar {
+	m := vals.EmptyMap
+	for name, spec := range highlight.DefaultTheme {
+		m = m.Assoc(name, spec)
+	}
+	theme, _ := parseTheme(m)
+	return &themeVar{m: m, theme: theme}
+}
+
+func (v *themeVar) Get() any {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	return v.m
+}
+
+func (v *themeVar) Set(val any) error {
+	m, ok := val.(vals.Map)
+	if !ok {
+		return errs.BadValue{What: "theme", Valid: "map", Actual: vals.Kind(val)}
+	}
+	theme, err := parseTheme(m)
+	if err != nil {
+		return err
+	}
+	v.mutex.Lock()
+	v.m, v.theme = m, theme
+	v.mutex.Unlock()
+	if v.onSet != nil {
+		v.onSet()
+	}
+	return nil
+}
+
+// Theme returns the parsed theme.
+func (v *themeVar) Theme() highlight.Theme {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	return v.theme
+}
+
+func parseTheme(m vals.Map) (highlight.Theme, error) {
+	theme := make(highlight.Theme, m.Len())
+	for it := m.Iterator(); it.HasElem(); it.Next() {
+		k, v := it.Elem()
+		name, ok := k.(string)
+		if _, known := highlight.DefaultTheme[name]; !ok || !known {
+			return nil, errs.BadValue{What: "key of theme",
+				Valid: "name of a region type", Actual: vals.ReprPlain(k)}
+		}
+		spec, ok := v.(string)
+		if !ok {
+			return nil, errs.BadValue{What: "styling of " + name,
+				Valid: "string", Actual: vals.Kind(v)}
+		}
+		if spec == "" {
+			continue
+		}
+		styling := ui.ParseStyling(spec)
+		if styling == nil {
+			return nil, errs.BadValue{What: "styling of " + name,
+				Valid: "valid styling spec", Actual: parse.Quote(spec)}
+		}
+		theme[name] = styling
+	}
+	return theme, nil
 }
 
 func hasCommand(ev *eval.Evaler, cmd string) bool {
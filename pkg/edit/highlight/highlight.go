@@ -15,6 +15,8 @@ type Config struct {
 	Check      func(n parse.Tree) (string, []*eval.CompilationError)
 	HasCommand func(name string) bool
 	AutofixTip func(autofix string) ui.Text
+	// Returns the theme to use. If nil, the default theme is used.
+	Theme func() Theme
 }
 
 // Information collected about a command region, used for asynchronous
@@ -56,6 +58,11 @@ func highlight(code string, cfg Config, lateCb func(ui.Text)) (ui.Text, []ui.Tex
 		}
 	}
 
+	theme := defaultTheme
+	if cfg.Theme != nil {
+		theme = cfg.Theme()
+	}
+
 	var text ui.Text
 	regions := getRegionsInner(tree.Root)
 	regions = append(regions, errorRegions...)
@@ -78,10 +85,10 @@ func highlight(code string, cfg Config, lateCb func(ui.Text)) (ui.Text, []ui.Tex
 				cmdRegions = append(cmdRegions, cmdRegion{len(text), regionCode})
 			} else {
 				// Treat all commands as good commands.
-				styling = stylingForGoodCommand
+				styling = theme["good-command"]
 			}
 		} else {
-			styling = stylingFor[r.Type]
+			styling = theme.stylingFor(r.Type)
 		}
 		seg := &ui.Segment{Text: regionCode}
 		if styling != nil {
@@ -104,9 +111,9 @@ func highlight(code string, cfg Config, lateCb func(ui.Text)) (ui.Text, []ui.Tex
 			for _, cmdRegion := range cmdRegions {
 				var styling ui.Styling
 				if cfg.HasCommand(cmdRegion.cmd) {
-					styling = stylingForGoodCommand
+					styling = theme["good-command"]
 				} else {
-					styling = stylingForBadCommand
+					styling = theme["bad-command"]
 				}
 				seg := &newText[cmdRegion.seg]
 				*seg = ui.StyleSegment(*seg, styling)
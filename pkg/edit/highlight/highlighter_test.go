@@ -117,6 +117,45 @@ func TestHighlighter_AutofixesAndCheckErrors(t *testing.T) {
 	)
 }
 
+func TestHighlighter_Theme(t *testing.T) {
+	testutil.Set(t, &maxBlockForLate, testutil.Scaled(100*time.Millisecond))
+	hl := NewHighlighter(Config{
+		HasCommand: func(name string) bool { return name == "ls" },
+		Theme: func() Theme {
+			return Theme{
+				"variable":      ui.FgBlue,
+				"single-quoted": ui.Stylings(ui.FgBlue, ui.Bold),
+				"redirection":   ui.Italic,
+				"bad-command":   ui.Underlined,
+			}
+		},
+	})
+	styles := ui.RuneStylesheet{
+		'$':  ui.FgBlue,
+		'\'': ui.Stylings(ui.FgBlue, ui.Bold),
+		'>':  ui.Italic,
+		'_':  ui.Underlined,
+	}
+
+	// Unstyled text may be split into multiple segments, so compare the VT
+	// strings instead.
+	get := func(code string) string {
+		text, _ := hl.Get(code)
+		return text.VTString()
+	}
+	tt.Test(t, tt.Fn(get).Named("get"),
+		// Region types not in the theme are not styled.
+		Args("ls $x 'y' > f # c").Rets(
+			ui.MarkLines(
+				"ls $x 'y' > f # c", styles,
+				"   $$ ''' >      ").VTString()),
+		Args("bad").Rets(
+			ui.MarkLines(
+				"bad", styles,
+				"___").VTString()),
+	)
+}
+
 type c struct {
 	given       string
 	wantInitial ui.Text
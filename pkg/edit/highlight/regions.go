@@ -4,6 +4,7 @@ import (
 	"sort"
 	"strings"
 
+	"src.elv.sh/pkg/eval"
 	"src.elv.sh/pkg/parse"
 	"src.elv.sh/pkg/parse/cmpd"
 )
@@ -188,8 +189,8 @@ func emitRegionsInFor(n *parse.Form, f func(parse.Node, regionKind, string)) {
 }
 
 func emitRegionsInTry(n *parse.Form, f func(parse.Node, regionKind, string)) {
-	// Highlight "except", the exception variable after it, "else" and
-	// "finally".
+	// Highlight "except", the filter type and exception variable after it,
+	// "else" and "finally".
 	i := 1
 	matchKW := func(text string) bool {
 		if i < len(n.Args) && sourceText(n.Args[i]) == text {
@@ -198,7 +199,14 @@ func emitRegionsInTry(n *parse.Form, f func(parse.Node, regionKind, string)) {
 		}
 		return false
 	}
-	if matchKW("except") || matchKW("catch") {
+	for matchKW("except") || matchKW("catch") {
+		// This follows the rule used by the compiler: a filter type is only
+		// recognized when it is followed by at least two arguments.
+		if i+3 < len(n.Args) && eval.IsCatchFilterType[sourceText(n.Args[i+1])] &&
+			!tryKeywords[sourceText(n.Args[i+3])] {
+			f(n.Args[i+1], semanticRegion, keywordRegion)
+			i += 2
+		}
 		if i+1 < len(n.Args) && isStringLiteral(n.Args[i+1]) {
 			f(n.Args[i+1], semanticRegion, variableRegion)
 			i += 3
@@ -212,6 +220,8 @@ func emitRegionsInTry(n *parse.Form, f func(parse.Node, regionKind, string)) {
 	matchKW("finally")
 }
 
+var tryKeywords = map[string]bool{"catch": true, "else": true, "finally": true}
+
 func isStringLiteral(n *parse.Compound) bool {
 	_, ok := cmpd.StringLiteral(n)
 	return ok
@@ -246,3 +256,60 @@ func emitRegionsInSep(n *parse.Sep, f func(parse.Node, regionKind, string)) {
 		f(n, lexicalRegion, text)
 	}
 }
+
+// Token is a region of code classified in the same way as the highlighting in
+// the editor, for use by other packages like the language server.
+type Token struct {
+	From, To int
+	Type     TokenType
+}
+
+// TokenType is the type of a Token.
+type TokenType int
+
+// Possible values of TokenType.
+const (
+	CommentToken TokenType = iota
+	StringToken
+	VariableToken
+	// A string literal appearing as a command, including the head of special
+	// forms.
+	CommandToken
+	// Keywords within special forms, like "else" in an "if" form.
+	KeywordToken
+	// Pipes and redirections.
+	OperatorToken
+)
+
+var tokenTypeFor = map[string]TokenType{
+	commentRegion:      CommentToken,
+	singleQuotedRegion: StringToken,
+	doubleQuotedRegion: StringToken,
+	variableRegion:     VariableToken,
+	commandRegion:      CommandToken,
+	keywordRegion:      KeywordToken,
+	"|":                OperatorToken,
+	">":                OperatorToken,
+	">>":               OperatorToken,
+	"<":                OperatorToken,
+	"<>":               OperatorToken,
+}
+
+// Tokens returns the tokens in a parse tree, in source order. Regions that are
+// not highlighted in the editor, like barewords and brackets, are omitted.
+func Tokens(tree parse.Tree) []Token {
+	var tokens []Token
+	for _, r := range getRegions(tree.Root) {
+		typ, ok := tokenTypeFor[r.Type]
+		if !ok {
+			continue
+		}
+		if typ == CommentToken {
+			// Comment regions include the whitespace before the comment.
+			text := tree.Source.Code[r.Begin:r.End]
+			r.Begin += len(text) - len(strings.TrimLeftFunc(text, parse.IsWhitespace))
+		}
+		tokens = append(tokens, Token{r.Begin, r.End, typ})
+	}
+	return tokens
+}
@@ -191,6 +191,22 @@ func TestGetRegions(t *testing.T) {
 			{27, 28, lexicalRegion, "}"},
 		}),
 
+		Args("try { } catch fail x e { } catch e { }").Rets([]region{
+			{0, 3, semanticRegion, commandRegion}, // try
+			{4, 5, lexicalRegion, "{"},
+			{6, 7, lexicalRegion, "}"},
+			{8, 13, semanticRegion, keywordRegion},   // catch
+			{14, 18, semanticRegion, keywordRegion},  // fail
+			{19, 20, lexicalRegion, barewordRegion},  // x
+			{21, 22, semanticRegion, variableRegion}, // e
+			{23, 24, lexicalRegion, "{"},
+			{25, 26, lexicalRegion, "}"},
+			{27, 32, semanticRegion, keywordRegion},  // catch
+			{33, 34, semanticRegion, variableRegion}, // e
+			{35, 36, lexicalRegion, "{"},
+			{37, 38, lexicalRegion, "}"},
+		}),
+
 		// Regression test for b.elv.sh/1358.
 		Args("try { } catch { }").Rets([]region{
 			{0, 3, semanticRegion, commandRegion}, // try
@@ -217,3 +233,29 @@ func getRegionsFromString(code string) []region {
 	tree, _ := parse.Parse(parse.SourceForTest(code), parse.Config{})
 	return getRegions(tree.Root)
 }
+
+func TestTokens(t *testing.T) {
+	tt.Test(t, tokensFromString,
+		//    0123456789012345678901234567890
+		Args("var x = 'a' # c").Rets([]Token{
+			{0, 3, CommandToken},
+			{4, 5, VariableToken},
+			{6, 7, KeywordToken},
+			{8, 11, StringToken},
+			{12, 15, CommentToken},
+		}),
+		// Barewords and brackets are omitted.
+		Args("echo [a] $x | wc > out").Rets([]Token{
+			{0, 4, CommandToken},
+			{9, 11, VariableToken},
+			{12, 13, OperatorToken},
+			{14, 16, CommandToken},
+			{17, 18, OperatorToken},
+		}),
+	)
+}
+
+func tokensFromString(code string) []Token {
+	tree, _ := parse.Parse(parse.SourceForTest(code), parse.Config{})
+	return Tokens(tree)
+}
@@ -4,36 +4,61 @@ import (
 	"src.elv.sh/pkg/ui"
 )
 
-var stylingFor = map[string]ui.Styling{
-	barewordRegion:     nil,
-	singleQuotedRegion: ui.FgYellow,
-	doubleQuotedRegion: ui.FgYellow,
-	variableRegion:     ui.FgMagenta,
-	wildcardRegion:     nil,
-	tildeRegion:        nil,
+// Theme maps the names of region types to their stylings. The names are the
+// keys of DefaultTheme; names not in a Theme are not styled.
+type Theme map[string]ui.Styling
 
-	commentRegion: ui.FgCyan,
+// DefaultTheme contains the default styling of each region type, in the syntax
+// accepted by ui.ParseStyling. An empty string means no styling.
+var DefaultTheme = map[string]string{
+	"bareword":      "",
+	"single-quoted": "yellow",
+	"double-quoted": "yellow",
+	"variable":      "magenta",
+	"wildcard":      "",
+	"tilde":         "",
+	"comment":       "cyan",
+	"redirection":   "green",
+	"pipe":          "green",
+	"punctuation":   "bold",
+	"keyword":       "yellow",
+	"good-command":  "green",
+	"bad-command":   "red",
+	"error":         "bright-white bg-red",
+}
 
-	">":  ui.FgGreen,
-	">>": ui.FgGreen,
-	"<":  ui.FgGreen,
-	"?>": ui.FgGreen,
-	"|":  ui.FgGreen,
-	"?(": ui.Bold,
-	"(":  ui.Bold,
-	")":  ui.Bold,
-	"[":  ui.Bold,
-	"]":  ui.Bold,
-	"{":  ui.Bold,
-	"}":  ui.Bold,
-	"&":  ui.Bold,
+var defaultTheme = parseDefaultTheme()
 
-	commandRegion: ui.FgGreen,
-	keywordRegion: ui.FgYellow,
-	errorRegion:   ui.Stylings(ui.FgBrightWhite, ui.BgRed),
+func parseDefaultTheme() Theme {
+	theme := make(Theme, len(DefaultTheme))
+	for name, spec := range DefaultTheme {
+		if spec != "" {
+			theme[name] = ui.ParseStyling(spec)
+		}
+	}
+	return theme
 }
 
-var (
-	stylingForGoodCommand = ui.FgGreen
-	stylingForBadCommand  = ui.FgRed
-)
+// Names of lexical regions for punctuations, which are grouped in the theme.
+var themeNameFor = map[string]string{
+	">":  "redirection",
+	">>": "redirection",
+	"<":  "redirection",
+	"?>": "redirection",
+	"|":  "pipe",
+	"?(": "punctuation",
+	"(":  "punctuation",
+	")":  "punctuation",
+	"[":  "punctuation",
+	"]":  "punctuation",
+	"{":  "punctuation",
+	"}":  "punctuation",
+	"&":  "punctuation",
+}
+
+func (t Theme) stylingFor(regionType string) ui.Styling {
+	if name, ok := themeNameFor[regionType]; ok {
+		return t[name]
+	}
+	return t[regionType]
+}
@@ -9,6 +9,7 @@ import (
 	"src.elv.sh/pkg/cli/term"
 	"src.elv.sh/pkg/env"
 	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/parse"
 	"src.elv.sh/pkg/testutil"
 	"src.elv.sh/pkg/tt"
 	"src.elv.sh/pkg/ui"
@@ -54,6 +55,40 @@ func TestHighlighter_Autofix(t *testing.T) {
 	)
 }
 
+func TestHighlighter_Theme(t *testing.T) {
+	f := setup(t)
+
+	feedInput(f.TTYCtrl, "put $true")
+	f.TestTTY(t,
+		"~> put $true", Styles,
+		"   vvv $$$$$", term.DotHere,
+	)
+
+	evals(f.Evaler, `set edit:highlight:theme[variable] = blue`)
+	// The new theme takes effect on the next redraw.
+	feedInput(f.TTYCtrl, " ")
+	f.TestTTY(t,
+		"~> put $true ", Styles,
+		"   vvv ///// ", term.DotHere,
+	)
+}
+
+func TestHighlighter_Theme_BadValues(t *testing.T) {
+	f := setup(t)
+
+	for _, code := range []string{
+		`set edit:highlight:theme = foo`,
+		`set edit:highlight:theme[bad-region] = blue`,
+		`set edit:highlight:theme[variable] = [blue]`,
+		`set edit:highlight:theme[variable] = bad-color`,
+	} {
+		err := f.Evaler.Eval(parse.Source{Name: "[test]", Code: code}, eval.EvalCfg{})
+		if err == nil {
+			t.Errorf("%s: got no error", code)
+		}
+	}
+}
+
 // Fine-grained tests against the highlighter.
 
 const colonInFilenameOk = runtime.GOOS != "windows"
@@ -13,6 +13,9 @@ type histStore struct {
 	m  sync.Mutex
 	db storedefs.Store
 	hs histutil.Store
+	// Incremented whenever the history changes, so that results derived from
+	// it can be cached.
+	version int
 }
 
 func newHistStore(db storedefs.Store) (*histStore, error) {
@@ -23,6 +26,7 @@ func newHistStore(db storedefs.Store) (*histStore, error) {
 func (s *histStore) AddCmd(cmd storedefs.Cmd) (int, error) {
 	s.m.Lock()
 	defer s.m.Unlock()
+	s.version++
 	return s.hs.AddCmd(cmd)
 }
 
@@ -44,9 +48,17 @@ func (s *histStore) FastForward() error {
 	defer s.m.Unlock()
 	hs, err := histutil.NewHybridStore(s.db)
 	s.hs = hs
+	s.version++
 	return err
 }
 
+// Version returns a number that changes whenever the history changes.
+func (s *histStore) Version() int {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.version
+}
+
 type cursor struct {
 	m *sync.Mutex
 	c histutil.Cursor
@@ -7,10 +7,10 @@ import (
 	"src.elv.sh/pkg/cli/histutil"
 	"src.elv.sh/pkg/cli/modes"
 	"src.elv.sh/pkg/cli/tk"
-	"src.elv.sh/pkg/edit/filter"
 	"src.elv.sh/pkg/eval"
 	"src.elv.sh/pkg/eval/vals"
 	"src.elv.sh/pkg/eval/vars"
+	"src.elv.sh/pkg/filter"
 	"src.elv.sh/pkg/store/storedefs"
 	"src.elv.sh/pkg/ui"
 )
@@ -39,35 +39,53 @@ func initListings(ed *Editor, ev *eval.Evaler, st storedefs.Store, histStore his
 	initLocation(ed, ev, st, bindingVar, nb)
 }
 
-var filterSpec = modes.FilterSpec{
-	Maker: func(f string) func(string) bool {
-		q, _ := filter.Compile(f)
-		if q == nil {
-			return func(string) bool { return true }
-		}
-		return q.Match
-	},
-	Highlighter: filter.Highlight,
+func initFilter(ed *Editor, nb eval.NsBuilder) {
+	fuzzy := newBoolVar(false)
+	isFuzzy := func() bool { return fuzzy.GetRaw().(bool) }
+	ed.filterSpec = modes.FilterSpec{
+		Maker: func(f string) func(string) bool {
+			q, _ := filter.Compile(f)
+			if q == nil {
+				return func(string) bool { return true }
+			}
+			return q.Match
+		},
+		Highlighter: func(f string) (ui.Text, []ui.Text) {
+			if isFuzzy() {
+				// Fuzzy patterns don't use the filter DSL.
+				return ui.T(f), nil
+			}
+			return filter.Highlight(f)
+		},
+		Fuzzy: isFuzzy,
+	}
+	nb.AddVar("fuzzy-filter", fuzzy)
 }
 
 func initHistlist(ed *Editor, ev *eval.Evaler, histStore histutil.Store, commonBindingVar vars.PtrVar, nb eval.NsBuilder) {
 	bindingVar := newBindingVar(emptyBindingsMap)
 	bindings := newMapBindings(ed, ev, bindingVar, commonBindingVar)
 	dedup := newBoolVar(true)
+	scope := modes.HistlistScopeGlobal
 	ns := eval.BuildNsNamed("edit:histlist").
 		AddVar("binding", bindingVar).
 		AddGoFns(map[string]any{
 			"start": func() {
+				dir, _ := os.Getwd()
 				w, err := modes.NewHistlist(ed.app, modes.HistlistSpec{
 					Bindings: bindings,
 					AllCmds:  histStore.AllCmds,
 					Dedup: func() bool {
 						return dedup.Get().(bool)
 					},
-					Filter: filterSpec,
+					Scope:     func() modes.HistlistScope { return scope },
+					SessionID: ed.sessionID,
+					Dir:       dir,
+					Filter:    ed.filterSpec,
 					CodeAreaRPrompt: func() ui.Text {
 						return bindingTips(ed.ns, "histlist:binding",
-							bindingTip("dedup", "histlist:toggle-dedup"))
+							bindingTip("dedup", "histlist:toggle-dedup"),
+							bindingTip("scope", "histlist:cycle-scope"))
 					},
 				})
 				startMode(ed.app, w, err)
@@ -77,6 +95,11 @@ func initHistlist(ed *Editor, ev *eval.Evaler, histStore histutil.Store, commonB
 				listingRefilter(ed.app)
 				ed.app.Redraw()
 			},
+			"cycle-scope": func() {
+				scope = scope.Next()
+				listingRefilter(ed.app)
+				ed.app.Redraw()
+			},
 		}).Ns()
 	nb.AddNs("histlist", ns)
 }
@@ -119,7 +142,7 @@ func initLocation(ed *Editor, ev *eval.Evaler, st storedefs.Store, commonBinding
 					IteratePinned:     adaptToIterateString(pinnedVar),
 					IterateHidden:     adaptToIterateString(hiddenVar),
 					IterateWorkspaces: workspaceIterator,
-					Filter:            filterSpec,
+					Filter:            ed.filterSpec,
 				})
 				startMode(ed.app, w, err)
 			}))
@@ -3,9 +3,11 @@ package edit
 import (
 	"bufio"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 
+	"src.elv.sh/pkg/cli/fuzzy"
 	"src.elv.sh/pkg/cli/modes"
 	"src.elv.sh/pkg/cli/tk"
 	"src.elv.sh/pkg/eval"
@@ -68,6 +70,9 @@ func listingStartCustom(ed *Editor, fm *eval.Frame, opts customListingOpts, item
 		}
 	} else {
 		getItems = func(q string) []modes.ListingItem {
+			if ed.filterSpec.Fuzzy() && strings.TrimSpace(q) != "" {
+				return fuzzyFilterListingItems(items, q, opts.KeepBottom)
+			}
 			convertedItems := []modes.ListingItem{}
 			vals.Iterate(items, func(v any) bool {
 				toFilter, toFilterOk := getToFilter(v)
@@ -103,6 +108,45 @@ func listingStartCustom(ed *Editor, fm *eval.Frame, opts customListingOpts, item
 	startMode(ed.app, w, err)
 }
 
+// Filters the items with a fuzzy pattern, and ranks them from the best match to
+// the worst, or the other way around if keepBottom is true. Items that match
+// equally well keep their original order.
+func fuzzyFilterListingItems(items any, q string, keepBottom bool) []modes.ListingItem {
+	type match struct {
+		item  modes.ListingItem
+		score int
+	}
+	var matches []match
+	vals.Iterate(items, func(v any) bool {
+		toFilter, toFilterOk := getToFilter(v)
+		item, itemOk := getListingItem(v)
+		if !toFilterOk || !itemOk {
+			return true
+		}
+		score, positions, ok := fuzzy.Match(q, toFilter)
+		if !ok {
+			return true
+		}
+		// Only highlight the matched characters when they are shown as is.
+		if toShow, _ := vals.Index(v, "to-show"); toShow == toFilter {
+			item.ToShow = fuzzy.Highlight(item.ToShow, positions)
+		}
+		matches = append(matches, match{item, score})
+		return true
+	})
+	sort.SliceStable(matches, func(i, j int) bool {
+		if keepBottom {
+			return matches[i].score < matches[j].score
+		}
+		return matches[i].score > matches[j].score
+	})
+	ranked := make([]modes.ListingItem, len(matches))
+	for i, m := range matches {
+		ranked[i] = m.item
+	}
+	return ranked
+}
+
 func getToFilter(v any) (string, bool) {
 	toFilterValue, _ := vals.Index(v, "to-filter")
 	toFilter, toFilterOk := toFilterValue.(string)
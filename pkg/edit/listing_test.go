@@ -1,6 +1,7 @@
 package edit
 
 import (
+	"os"
 	"testing"
 
 	"src.elv.sh/pkg/cli/term"
@@ -85,8 +86,8 @@ func TestHistlistAddon(t *testing.T) {
 		"~> \n",
 		" HISTORY (dedup on)  ", Styles,
 		"******************** ", term.DotHere,
-		"                 Ctrl-D dedup\n", Styles,
-		"                 ++++++      ",
+		"    Ctrl-D dedup Ctrl-S scope\n", Styles,
+		"    ++++++       ++++++      ",
 		"   2 echo\n",
 		"   3 ls\n",
 		"   4 LS                                           ", Styles,
@@ -98,8 +99,8 @@ func TestHistlistAddon(t *testing.T) {
 		"~> \n",
 		" HISTORY  ", Styles,
 		"********* ", term.DotHere,
-		"                            Ctrl-D dedup\n", Styles,
-		"                            ++++++      ",
+		"               Ctrl-D dedup Ctrl-S scope\n", Styles,
+		"               ++++++       ++++++      ",
 		"   1 ls\n",
 		"   2 echo\n",
 		"   3 ls\n",
@@ -115,8 +116,8 @@ func TestHistlistAddon(t *testing.T) {
 		"~> \n",
 		" HISTORY (dedup on)  l", Styles,
 		"********************  ", term.DotHere,
-		"                Ctrl-D dedup\n", Styles,
-		"                ++++++      ",
+		"   Ctrl-D dedup Ctrl-S scope\n", Styles,
+		"   ++++++       ++++++      ",
 		"   3 ls\n",
 		"   4 LS                                           ", Styles,
 		"++++++++++++++++++++++++++++++++++++++++++++++++++",
@@ -128,13 +129,57 @@ func TestHistlistAddon(t *testing.T) {
 		"~> \n",
 		" HISTORY (dedup on)  L", Styles,
 		"********************  ", term.DotHere,
-		"                Ctrl-D dedup\n", Styles,
-		"                ++++++      ",
+		"   Ctrl-D dedup Ctrl-S scope\n", Styles,
+		"   ++++++       ++++++      ",
 		"   4 LS                                           ", Styles,
 		"++++++++++++++++++++++++++++++++++++++++++++++++++",
 	)
 }
 
+func TestHistlistAddon_CycleScope(t *testing.T) {
+	f := setup(t,
+		// Remove the binding tips to keep the caption short.
+		rc(`set edit:histlist:binding = (edit:binding-table [&])`),
+		func(f *fixture) {
+			wd, _ := os.Getwd()
+			f.Store.AddCmd("ls")
+			f.Store.SetCmdMeta(1, storedefs.CmdMeta{Session: f.Editor.sessionID})
+			f.Store.AddCmd("echo")
+			f.Store.SetCmdMeta(2, storedefs.CmdMeta{Dir: wd})
+			f.Store.AddCmd("pwd")
+			evals(f.Evaler, "edit:history:fast-forward")
+		})
+
+	f.TTYCtrl.Inject(term.K('R', ui.Ctrl))
+	f.TestTTY(t,
+		"~> \n",
+		" HISTORY (dedup on)  ", Styles,
+		"******************** ", term.DotHere, "\n",
+		"   1 ls\n",
+		"   2 echo\n",
+		"   3 pwd                                          ", Styles,
+		"++++++++++++++++++++++++++++++++++++++++++++++++++",
+	)
+
+	evals(f.Evaler, `edit:histlist:cycle-scope`)
+	f.TestTTY(t,
+		"~> \n",
+		" HISTORY (session) (dedup on)  ", Styles,
+		"****************************** ", term.DotHere, "\n",
+		"   1 ls                                           ", Styles,
+		"++++++++++++++++++++++++++++++++++++++++++++++++++",
+	)
+
+	evals(f.Evaler, `edit:histlist:cycle-scope`)
+	f.TestTTY(t,
+		"~> \n",
+		" HISTORY (dir) (dedup on)  ", Styles,
+		"************************** ", term.DotHere, "\n",
+		"   2 echo                                         ", Styles,
+		"++++++++++++++++++++++++++++++++++++++++++++++++++",
+	)
+}
+
 func TestLastCmdAddon(t *testing.T) {
 	f := setup(t, storeOp(func(s storedefs.Store) {
 		s.AddCmd("echo hello world")
@@ -222,3 +267,28 @@ func TestCustomListing_PassingBytesCallback(t *testing.T) {
 		"~> # x", Styles,
 		"   ccc", term.DotHere)
 }
+
+func TestCustomListing_FuzzyFilter(t *testing.T) {
+	f := setup(t)
+	styles := ui.RuneStylesheet{
+		'*': Styles['*'],
+		'+': Styles['+'],
+		'b': Styles['b'],
+		'u': ui.Stylings(ui.Inverse, ui.Bold),
+	}
+
+	evals(f.Evaler,
+		`set edit:fuzzy-filter = $true`,
+		`fn item {|x| put [&to-show=$x &to-accept=$x &to-filter=$x] }`,
+		`edit:listing:start-custom [(item fxxxb) (item foo-bar) (item ls)] &caption=A`)
+	f.TTYCtrl.Inject(term.K('f'), term.K('b'))
+	f.TestTTY(t,
+		"~> \n",
+		"A fb", styles,
+		"*   ", term.DotHere, "\n",
+		"foo-bar                                           \n", styles,
+		"u+++u+++++++++++++++++++++++++++++++++++++++++++++",
+		"fxxxb                                             ", styles,
+		"b   bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb",
+	)
+}
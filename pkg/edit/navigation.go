@@ -9,6 +9,7 @@ import (
 	"src.elv.sh/pkg/eval"
 	"src.elv.sh/pkg/eval/vals"
 	"src.elv.sh/pkg/eval/vars"
+	"src.elv.sh/pkg/filter"
 	"src.elv.sh/pkg/parse"
 	"src.elv.sh/pkg/ui"
 )
@@ -98,7 +99,9 @@ func initNavigation(ed *Editor, ev *eval.Evaler, nb eval.NsBuilder) {
 					WidthRatio: func() [3]int {
 						return convertNavWidthRatio(widthRatioVar.Get())
 					},
-					Filter: filterSpec,
+					// The navigation mode doesn't support fuzzy matching.
+					Filter: modes.FilterSpec{
+						Maker: ed.filterSpec.Maker, Highlighter: filter.Highlight},
 					CodeAreaRPrompt: func() ui.Text {
 						return bindingTips(ed.ns, "navigation:binding",
 							bindingTip("hidden", "navigation:trigger-shown-hidden"),
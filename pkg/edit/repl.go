@@ -4,17 +4,23 @@ package edit
 // information about the most recently executed interactive command.
 
 import (
+	"fmt"
+	"os"
+	"time"
+
 	"src.elv.sh/pkg/eval"
 	"src.elv.sh/pkg/eval/vals"
 	"src.elv.sh/pkg/eval/vars"
 	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/store/storedefs"
 )
 
-func initRepl(ed *Editor, ev *eval.Evaler, nb eval.NsBuilder) {
+func initRepl(ed *Editor, ev *eval.Evaler, st storedefs.Store, nb eval.NsBuilder) {
 	var commandDuration float64
 	// TODO: Ensure that this variable can only be written from the Elvish code
 	// in elv_init.go.
 	nb.AddVar("command-duration", vars.FromPtr(&commandDuration))
+	nb.AddVar("session-id", vars.NewReadOnly(ed.sessionID))
 
 	afterCommandHook := newListVar(vals.EmptyList)
 	nb.AddVar("after-command", afterCommandHook)
@@ -22,5 +28,51 @@ func initRepl(ed *Editor, ev *eval.Evaler, nb eval.NsBuilder) {
 		func(src parse.Source, duration float64, err error) {
 			m := vals.MakeMap("src", src, "duration", duration, "error", err)
 			eval.CallHook(ev, nil, "$<edit>:after-command", afterCommandHook.Get().(vals.List), m)
+		},
+		func(src parse.Source, duration float64, err error) {
+			cmd := ed.runningCmd
+			ed.runningCmd = storedefs.Cmd{Seq: -1}
+			if st == nil || cmd.Seq < 0 || cmd.Text != src.Code {
+				return
+			}
+			cmd.Meta.Duration = duration
+			cmd.Meta.Status = cmdStatus(err)
+			if err := st.SetCmdMeta(cmd.Seq, cmd.Meta); err != nil {
+				ed.notifyError("history", err)
+			}
 		})
+
+	afterBgJobHook := newListVar(vals.EmptyList)
+	nb.AddVar("after-bg-job", afterBgJobHook)
+	ev.AfterBgJob = append(ev.AfterBgJob, func(m vals.Map) {
+		eval.CallHook(ev, nil, "$<edit>:after-bg-job", afterBgJobHook.Get().(vals.List), m)
+	})
+}
+
+func newSessionID() string {
+	return fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano())
+}
+
+// Returns the metadata of a command that is about to start. The Duration and
+// Status fields are filled in when the command finishes.
+func (ed *Editor) startCmdMeta() storedefs.CmdMeta {
+	dir, _ := os.Getwd()
+	return storedefs.CmdMeta{
+		Start:   float64(time.Now().UnixNano()) / 1e9,
+		Dir:     dir,
+		Session: ed.sessionID,
+	}
+}
+
+// Returns "ok" if err is nil, or the type of the exception's reason otherwise.
+func cmdStatus(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	if t, err := vals.Index(eval.Reason(err), "type"); err == nil {
+		if s, ok := t.(string); ok {
+			return s
+		}
+	}
+	return "unknown"
 }
@@ -0,0 +1,91 @@
+package edit
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/store"
+	"src.elv.sh/pkg/store/storedefs"
+)
+
+func TestAfterCommand_RecordsCmdMeta(t *testing.T) {
+	f := setup(t)
+
+	feedInput(f.TTYCtrl, "echo\n")
+	code, _ := f.Wait()
+	f.Editor.RunAfterCommandHooks(parse.Source{Name: "[tty]", Code: code}, 1.5,
+		eval.NewException(eval.FailError{Content: "bad"}, nil))
+
+	cmds, err := f.Store.CmdsWithSeq(0, 1024)
+	if err != nil {
+		panic(err)
+	}
+	if len(cmds) != 1 {
+		t.Fatalf("got cmds %v, want 1 command", cmds)
+	}
+	meta := cmds[0].Meta
+	wd, _ := os.Getwd()
+	if meta.Duration != 1.5 || meta.Status != "fail" || meta.Dir != wd ||
+		meta.Session != f.Editor.sessionID || meta.Start == 0 {
+		t.Errorf("got meta %+v", meta)
+	}
+}
+
+func TestAfterCommand_DoesNotRecordCmdMetaForOtherCode(t *testing.T) {
+	f := setup(t)
+
+	feedInput(f.TTYCtrl, "echo\n")
+	f.Wait()
+	f.Editor.RunAfterCommandHooks(parse.Source{Name: "rc.elv", Code: "other"}, 1.5, nil)
+
+	testCommands(t, f.Store, storedefs.Cmd{Text: "echo", Seq: 1})
+}
+
+func TestCmdStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, "ok"},
+		{eval.NewException(eval.FailError{Content: "x"}, nil), "fail"},
+		{eval.NewException(eval.Break, nil), "flow"},
+		{eval.NewException(errors.New("x"), nil), "unknown"},
+	}
+	for _, test := range tests {
+		if got := cmdStatus(test.err); got != test.want {
+			t.Errorf("cmdStatus(%v) -> %q, want %q", test.err, got, test.want)
+		}
+	}
+}
+
+func TestAfterBgJob(t *testing.T) {
+	f := setup(t)
+
+	evals(f.Evaler,
+		`var job = $nil`,
+		`set edit:after-bg-job = [{|j| set job = $j }]`,
+		`fail bad &`,
+		`wait`,
+		`var result = [$job[id] $job[cmd] $job[state] $job[exception][reason][content]]`)
+
+	testGlobal(t, f.Evaler, "result", vals.MakeList(1, "fail bad &", "done", "bad"))
+}
+
+func TestAfterCommand_NotifiesCmdMetaError(t *testing.T) {
+	f := setup(t)
+
+	feedInput(f.TTYCtrl, "echo\n")
+	code, _ := f.Wait()
+	f.Store.(store.DBStore).Close()
+	f.Editor.RunAfterCommandHooks(parse.Source{Name: "[tty]", Code: code}, 1.5, nil)
+
+	notes := f.Editor.app.CopyState().Notes
+	if len(notes) != 1 || !strings.Contains(notes[0].String(), "[history error] ") {
+		t.Errorf("got notes %v, want a history error", notes)
+	}
+}
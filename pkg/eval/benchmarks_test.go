@@ -30,7 +30,7 @@ func BenchmarkEval(b *testing.B) {
 			if err != nil {
 				panic(err)
 			}
-			op, _, err := compile(ev.builtin.static(), ev.global.static(), nil, tree, nil)
+			op, _, err := compile(ev.builtin.static(), ev.global.static(), tree, compileOpts{})
 			if err != nil {
 				panic(err)
 			}
@@ -9,8 +9,6 @@ import (
 
 // Command and process control.
 
-// TODO(xiaq): Document "fg".
-
 func init() {
 	addBuiltinFns(map[string]any{
 		// Command resolution
@@ -19,9 +17,13 @@ func init() {
 		"search-external": searchExternal,
 
 		// Process control
-		"fg":   fg,
-		"exec": execFn,
-		"exit": exit,
+		"jobs":   jobsFn,
+		"fg":     fg,
+		"bg":     bg,
+		"wait":   wait,
+		"disown": disown,
+		"exec":   execFn,
+		"exit":   exit,
 	})
 }
 
@@ -4,19 +4,20 @@ package eval
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"os/exec"
 	"strconv"
 	"syscall"
 
 	"src.elv.sh/pkg/env"
-	"src.elv.sh/pkg/eval/errs"
 	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/sys"
 	"src.elv.sh/pkg/sys/eunix"
 )
 
-// ErrNotInSameProcessGroup is thrown when the process IDs passed to fg are not
-// in the same process group.
+// ErrNotInSameProcessGroup is thrown when the arguments passed to fg refer to
+// more than one job, or process IDs in more than one process group.
 var ErrNotInSameProcessGroup = errors.New("not in the same process group")
 
 // Reference to syscall.Exec. Can be overridden in tests.
@@ -55,10 +56,51 @@ func decSHLVL() {
 	os.Setenv(env.SHLVL, strconv.Itoa(i-1))
 }
 
-func fg(pids ...int) error {
-	if len(pids) == 0 {
-		return errs.ArityMismatch{What: "arguments", ValidLow: 1, ValidHigh: -1, Actual: len(pids)}
+func fg(fm *Frame, args ...any) error {
+	jobs, err := fm.Evaler.jobs.findAll(args)
+	if err == ErrNoSuchJob {
+		if pids, ok := scanPids(args); ok {
+			return fgProcesses(pids)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	j := jobs[0]
+	for _, j2 := range jobs[1:] {
+		if j2 != j {
+			return ErrNotInSameProcessGroup
+		}
+	}
+	err = j.cont(true)
+	if err != nil {
+		return err
+	}
+	if fm.Evaler.waitForeground(fm.Context(), j) {
+		fmt.Fprintln(fm.ErrorFile(), j.describe())
+		return nil
 	}
+	return j.err
+}
+
+// Converts args to process IDs, if they are all process IDs.
+func scanPids(args []any) ([]int, bool) {
+	if len(args) == 0 {
+		return nil, false
+	}
+	pids := make([]int, len(args))
+	for i, arg := range args {
+		if err := vals.ScanToGo(arg, &pids[i]); err != nil {
+			return nil, false
+		}
+	}
+	return pids, true
+}
+
+// Continues processes that are not in the job table in the foreground and
+// waits for them to finish or stop. This supports processes that are not
+// started as jobs, like those started before job control was available.
+func fgProcesses(pids []int) error {
 	var thepgid int
 	for i, pid := range pids {
 		pgid, err := syscall.Getpgid(pid)
@@ -72,34 +114,47 @@ func fg(pids ...int) error {
 		}
 	}
 
-	err := eunix.Tcsetpgrp(0, thepgid)
-	if err != nil {
-		return err
+	if sys.IsATTY(os.Stdin.Fd()) {
+		err := eunix.Tcsetpgrp(0, thepgid)
+		if err != nil {
+			return err
+		}
+		defer putSelfInFg()
 	}
 
 	errors := make([]Exception, len(pids))
-
 	for i, pid := range pids {
 		err := syscall.Kill(pid, syscall.SIGCONT)
 		if err != nil {
 			errors[i] = &exception{err, nil}
 		}
 	}
-
 	for i, pid := range pids {
 		if errors[i] != nil {
 			continue
 		}
 		var ws syscall.WaitStatus
-		_, err = syscall.Wait4(pid, &ws, syscall.WUNTRACED, nil)
+		_, err := syscall.Wait4(pid, &ws, syscall.WUNTRACED, nil)
 		if err != nil {
 			errors[i] = &exception{err, nil}
 		} else {
-			// TODO find command name
 			errors[i] = &exception{NewExternalCmdExit(
 				"[pid "+strconv.Itoa(pid)+"]", ws, pid), nil}
 		}
 	}
-
 	return MakePipelineError(errors)
 }
+
+func bg(fm *Frame, args ...any) error {
+	jobs, err := fm.Evaler.jobs.findAll(args)
+	if err != nil {
+		return err
+	}
+	for _, j := range jobs {
+		err := j.cont(false)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
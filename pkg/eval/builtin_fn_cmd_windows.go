@@ -8,6 +8,10 @@ func execFn(...any) error {
 	return errNotSupportedOnWindows
 }
 
-func fg(...int) error {
+func fg(*Frame, ...any) error {
+	return errNotSupportedOnWindows
+}
+
+func bg(*Frame, ...any) error {
 	return errNotSupportedOnWindows
 }
@@ -3,6 +3,7 @@ package eval
 import (
 	"errors"
 	"fmt"
+	"sort"
 
 	"src.elv.sh/pkg/eval/errs"
 	"src.elv.sh/pkg/eval/vals"
@@ -17,6 +18,8 @@ func init() {
 
 		"make-map": makeMap,
 
+		"record-type": recordTypeFn,
+
 		"conj":   conj,
 		"assoc":  assoc,
 		"dissoc": dissoc,
@@ -75,6 +78,58 @@ func makeMap(input Inputs) (vals.Map, error) {
 	return m, errMakeMap
 }
 
+// Kinds of builtin values, which record types can't be named after, since the
+// kind of a record is the name of its type.
+var builtinKinds = map[string]bool{
+	"nil": true, "bool": true, "string": true, "number": true, "list": true,
+	"map": true, "fn": true, "file": true, "exception": true, "ns": true,
+}
+
+type recordTypeOpts struct{ Predicates vals.Map }
+
+func (o *recordTypeOpts) SetDefaultOptions() { o.Predicates = vals.EmptyMap }
+
+func recordTypeFn(fm *Frame, opts recordTypeOpts, name string, fields vals.Map) (*recordType, error) {
+	if name == "" {
+		return nil, errs.BadValue{What: "name of record type",
+			Valid: "non-empty string", Actual: "empty string"}
+	}
+	if builtinKinds[name] {
+		return nil, errs.BadValue{What: "name of record type",
+			Valid: "not the kind of a builtin type", Actual: vals.ReprPlain(name)}
+	}
+	t := &recordType{ev: fm.Evaler, name: name}
+	for it := fields.Iterator(); it.HasElem(); it.Next() {
+		k, _ := it.Elem()
+		kstring, ok := k.(string)
+		if !ok {
+			return nil, errs.BadValue{What: "field name",
+				Valid: "string", Actual: vals.Kind(k)}
+		}
+		t.keys = append(t.keys, kstring)
+	}
+	sort.Strings(t.keys)
+	t.defaults = make([]any, len(t.keys))
+	t.predicates = make([]Callable, len(t.keys))
+	for i, k := range t.keys {
+		t.defaults[i], _ = fields.Index(k)
+	}
+	for it := opts.Predicates.Iterator(); it.HasElem(); it.Next() {
+		k, v := it.Elem()
+		i, err := t.fieldIndex(k)
+		if err != nil {
+			return nil, err
+		}
+		pred, ok := v.(Callable)
+		if !ok {
+			return nil, errs.BadValue{What: "predicate of field " + t.keys[i],
+				Valid: "fn", Actual: vals.Kind(v)}
+		}
+		t.predicates[i] = pred
+	}
+	return t, nil
+}
+
 func conj(li vals.List, more ...any) vals.List {
 	for _, val := range more {
 		li = li.Conj(val)
@@ -82,7 +137,12 @@ func conj(li vals.List, more ...any) vals.List {
 	return li
 }
 
-func assoc(a, k, v any) (any, error) {
+func assoc(fm *Frame, a, k, v any) (any, error) {
+	if r, ok := a.(*record); ok {
+		// Call the predicate in the caller's frame, so that it has access to
+		// the caller's ports.
+		return r.assoc(fm, k, v)
+	}
 	return vals.Assoc(a, k, v)
 }
 
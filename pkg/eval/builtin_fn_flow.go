@@ -4,6 +4,7 @@ import (
 	"errors"
 	"math"
 	"math/big"
+	"sort"
 	"sync"
 	"sync/atomic"
 
@@ -158,31 +159,144 @@ func parseNumWorkers(n vals.Num) (int, bool, error) {
 }
 
 // FailError is an error returned by the "fail" command.
-type FailError struct{ Content any }
+type FailError struct {
+	Content any
+	// The type of the error. An empty string means "fail".
+	Type string
+	// Additional fields, which may be nil.
+	Fields vals.Map
+	// The cause of the error, which may be nil. If the cause was given as an
+	// Exception, it is kept as is to preserve its stack trace.
+	Cause error
+}
 
-var _ vals.PseudoMap = FailError{}
+var (
+	_ vals.Indexer      = FailError{}
+	_ vals.KeysIterator = FailError{}
+)
 
 // Error returns the string representation of the cause.
 func (e FailError) Error() string { return vals.ToString(e.Content) }
 
+// Unwrap returns the cause.
+func (e FailError) Unwrap() error { return e.Cause }
+
 // Kind returns "fail-error".
 func (FailError) Kind() string { return "fail-error" }
 
-// Fields returns a [vals.MethodMap] for accessing fields from Elvish.
-func (e FailError) Fields() vals.MethodMap { return failFields{e} }
+// Repr returns a representation like "[^fail-error &content=foo &type=fail]".
+func (e FailError) Repr(indent int) string {
+	keys := e.keys()
+	values := make([]any, len(keys))
+	for i, k := range keys {
+		values[i], _ = e.Index(k)
+	}
+	return vals.ReprTaggedMap("fail-error", keys, values, indent)
+}
+
+// Index returns the content, type, cause or one of the additional fields.
+func (e FailError) Index(k any) (any, bool) {
+	switch k {
+	case "content":
+		return e.Content, true
+	case "type":
+		if e.Type == "" {
+			return "fail", true
+		}
+		return e.Type, true
+	case "cause":
+		if e.Cause != nil {
+			return e.Cause, true
+		}
+	}
+	if e.Fields == nil {
+		return nil, false
+	}
+	return e.Fields.Index(k)
+}
+
+// HasKey returns whether k is the name of a field.
+func (e FailError) HasKey(k any) bool {
+	_, ok := e.Index(k)
+	return ok
+}
+
+// IterateKeys calls f with the name of each field.
+func (e FailError) IterateKeys(f func(any) bool) {
+	for _, k := range e.keys() {
+		if !f(k) {
+			return
+		}
+	}
+}
+
+// Returns the names of all fields, sorted.
+func (e FailError) keys() []string {
+	keys := []string{"content", "type"}
+	if e.Cause != nil {
+		keys = append(keys, "cause")
+	}
+	if e.Fields != nil {
+		for it := e.Fields.Iterator(); it.HasElem(); it.Next() {
+			k, _ := it.Elem()
+			keys = append(keys, k.(string))
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
 
-type failFields struct{ e FailError }
+type failOpts struct {
+	Type   string
+	Fields vals.Map
+	Cause  any
+}
 
-func (f failFields) Type() string { return "fail" }
-func (f failFields) Content() any { return f.e.Content }
+func (o *failOpts) SetDefaultOptions() {
+	o.Type = "fail"
+	o.Fields = vals.EmptyMap
+}
 
-func fail(v any) error {
-	if e, ok := v.(error); ok {
-		// MAYBE TODO: if v is an exception, attach a "rethrown" stack trace,
-		// like Java
-		return e
+func fail(opts failOpts, v any) error {
+	if opts.Type == "fail" && opts.Fields.Len() == 0 && opts.Cause == nil {
+		if e, ok := v.(error); ok {
+			// MAYBE TODO: if v is an exception, attach a "rethrown" stack
+			// trace, like Java
+			return e
+		}
+		return FailError{Content: v}
+	}
+	e := FailError{Content: v}
+	if opts.Type == "" {
+		return errs.BadValue{What: "option &type",
+			Valid: "a non-empty string", Actual: "empty string"}
+	} else if opts.Type != "fail" {
+		e.Type = opts.Type
+	}
+	if opts.Fields.Len() > 0 {
+		for it := opts.Fields.Iterator(); it.HasElem(); it.Next() {
+			k, _ := it.Elem()
+			if s, ok := k.(string); !ok || s == "content" || s == "type" || s == "cause" {
+				return errs.BadValue{What: "key of option &fields",
+					Valid:  "a string other than content, type and cause",
+					Actual: vals.ReprPlain(k)}
+			}
+		}
+		e.Fields = opts.Fields
+	}
+	switch cause := opts.Cause.(type) {
+	case nil:
+	case Exception:
+		if cause.Reason() != nil {
+			e.Cause = cause
+		}
+	case error:
+		e.Cause = cause
+	default:
+		return errs.BadValue{What: "option &cause",
+			Valid: "exception or $nil", Actual: vals.Kind(cause)}
 	}
-	return FailError{v}
+	return e
 }
 
 func multiErrorFn(excs ...Exception) error {
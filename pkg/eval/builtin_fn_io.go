@@ -2,18 +2,26 @@ package eval
 
 import (
 	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math/big"
+	"sort"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"src.elv.sh/pkg/diag"
 	"src.elv.sh/pkg/eval/errs"
 	"src.elv.sh/pkg/eval/vals"
 	"src.elv.sh/pkg/parse"
 	"src.elv.sh/pkg/strutil"
+	"src.elv.sh/pkg/toml"
+	"src.elv.sh/pkg/yaml"
 )
 
 // Input and output.
@@ -50,11 +58,20 @@ func init() {
 		"slurp":           slurp,
 		"from-lines":      fromLines,
 		"from-json":       fromJSON,
+		"from-jsonl":      fromJSONL,
+		"from-csv":        fromCSV,
+		"from-tsv":        fromTSV,
+		"from-toml":       fromTOML,
+		"from-yaml":       fromYAML,
 		"from-terminated": fromTerminated,
 
 		// Value to bytes
 		"to-lines":      toLines,
 		"to-json":       toJSON,
+		"to-jsonl":      toJSON,
+		"to-csv":        toCSV,
+		"to-toml":       toTOML,
+		"to-yaml":       toYAML,
 		"to-terminated": toTerminated,
 	})
 }
@@ -350,7 +367,7 @@ func fromLines(fm *Frame) error {
 }
 
 func fromJSON(fm *Frame) error {
-	in := fm.InputFile()
+	in := &countingReader{r: fm.InputFile()}
 	out := fm.ValueOutput()
 
 	dec := json.NewDecoder(in)
@@ -363,9 +380,10 @@ func fromJSON(fm *Frame) error {
 			if err == io.EOF {
 				return nil
 			}
-			return err
+			return fmt.Errorf("%w (at byte offset %d)",
+				err, jsonErrorOffset(err, in.n))
 		}
-		converted, err := fromJSONInterface(v)
+		converted, err := fromDecoded(v)
 		if err != nil {
 			return err
 		}
@@ -376,8 +394,87 @@ func fromJSON(fm *Frame) error {
 	}
 }
 
-// Converts a interface{} that results from json.Unmarshal to an Elvish value.
-func fromJSONInterface(v any) (any, error) {
+func fromJSONL(fm *Frame) error {
+	filein := bufio.NewReader(fm.InputFile())
+	out := fm.ValueOutput()
+	var lineStart int64
+	for lineNo := 1; ; lineNo++ {
+		line, err := filein.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			v, offset, errDecode := decodeJSONLine(line)
+			if errDecode != nil {
+				return fmt.Errorf("line %d: %w (at byte offset %d)",
+					lineNo, errDecode, lineStart+offset)
+			}
+			converted, errConvert := fromDecoded(v)
+			if errConvert != nil {
+				return errConvert
+			}
+			errPut := out.Put(converted)
+			if errPut != nil {
+				return errPut
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		lineStart += int64(len(line))
+	}
+}
+
+var errJSONTrailingData = errors.New("unexpected data after JSON value")
+
+// Decodes a line that must contain exactly one JSON value. On error, also
+// returns the offset of the error within the line.
+func decodeJSONLine(line []byte) (any, int64, error) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	var v any
+	err := dec.Decode(&v)
+	if err != nil {
+		return nil, jsonErrorOffset(err, int64(len(line))), err
+	}
+	end := dec.InputOffset()
+	rest := line[end:]
+	end += int64(len(rest) - len(bytes.TrimLeft(rest, " \t\r\n")))
+	var extra any
+	if dec.Decode(&extra) != io.EOF {
+		return nil, end, errJSONTrailingData
+	}
+	return v, 0, nil
+}
+
+// Returns the offset of a JSON decoding error. For syntax errors, this is the
+// offset of the offending byte; other errors like unexpected EOF are assumed
+// to happen at end.
+func jsonErrorOffset(err error, end int64) int64 {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) && syntaxErr.Offset > 0 {
+		// The Offset field is the number of bytes read when the error is
+		// found, including the offending byte.
+		return syntaxErr.Offset - 1
+	}
+	return end
+}
+
+// Wraps an io.Reader and counts the number of bytes read.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+// Converts a interface{} that results from json.Unmarshal, toml.Decode or
+// yaml.Decode to an Elvish value.
+func fromDecoded(v any) (any, error) {
 	switch v := v.(type) {
 	case nil, bool, string:
 		return v, nil
@@ -392,12 +489,16 @@ func fromJSONInterface(v any) (any, error) {
 		// Parse as float64 instead. This can error if the number is not an
 		// integer and exceeds the range of float64.
 		return strconv.ParseFloat(v.String(), 64)
+	case int64:
+		return vals.Int64ToNum(v), nil
+	case *big.Int:
+		return vals.NormalizeBigInt(v), nil
 	case float64:
 		return v, nil
 	case []any:
 		vec := vals.EmptyList
 		for _, elem := range v {
-			converted, err := fromJSONInterface(elem)
+			converted, err := fromDecoded(elem)
 			if err != nil {
 				return nil, err
 			}
@@ -407,7 +508,7 @@ func fromJSONInterface(v any) (any, error) {
 	case map[string]any:
 		m := vals.EmptyMap
 		for key, val := range v {
-			convertedVal, err := fromJSONInterface(val)
+			convertedVal, err := fromDecoded(val)
 			if err != nil {
 				return nil, err
 			}
@@ -415,8 +516,140 @@ func fromJSONInterface(v any) (any, error) {
 		}
 		return m, nil
 	default:
-		return nil, fmt.Errorf("unexpected json type: %T", v)
+		return nil, fmt.Errorf("unexpected decoded type: %T", v)
+	}
+}
+
+type csvOpts struct {
+	Header    bool
+	Delimiter string
+}
+
+func (o *csvOpts) SetDefaultOptions() { o.Delimiter = "," }
+
+func fromCSV(fm *Frame, opts csvOpts) error {
+	delimiter, err := checkDelimiter(opts.Delimiter)
+	if err != nil {
+		return err
+	}
+	r := csv.NewReader(fm.InputFile())
+	r.Comma = delimiter
+	return putRecords(fm, opts.Header, r.Read)
+}
+
+type tsvOpts struct{ Header bool }
+
+func (*tsvOpts) SetDefaultOptions() {}
+
+func fromTSV(fm *Frame, opts tsvOpts) error {
+	filein := bufio.NewReader(fm.InputFile())
+	lineNo, nFields := 0, -1
+	return putRecords(fm, opts.Header, func() ([]string, error) {
+		line, err := filein.ReadString('\n')
+		if line == "" {
+			return nil, err
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		lineNo++
+		fields := strings.Split(strutil.ChopLineEnding(line), "\t")
+		if nFields == -1 {
+			nFields = len(fields)
+		} else if len(fields) != nFields {
+			return nil, fmt.Errorf("record on line %d: wrong number of fields", lineNo)
+		}
+		return fields, nil
+	})
+}
+
+// Calls next until it returns an error, and outputs each record as a list, or
+// a map keyed by the first record if header is true. Returns nil if next
+// returns io.EOF.
+func putRecords(fm *Frame, header bool, next func() ([]string, error)) error {
+	out := fm.ValueOutput()
+	var keys []string
+	for {
+		record, err := next()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		var v any
+		if header {
+			if keys == nil {
+				keys = record
+				continue
+			}
+			m := vals.EmptyMap
+			for i, key := range keys {
+				m = m.Assoc(key, record[i])
+			}
+			v = m
+		} else {
+			list := vals.EmptyList
+			for _, field := range record {
+				list = list.Conj(field)
+			}
+			v = list
+		}
+		err = out.Put(v)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func checkDelimiter(s string) (rune, error) {
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 || size != len(s) || r == utf8.RuneError ||
+		r == '"' || r == '\r' || r == '\n' {
+		return 0, errs.BadValue{What: "delimiter",
+			Valid:  "a single character other than quote or newline",
+			Actual: parse.Quote(s)}
+	}
+	return r, nil
+}
+
+func fromTOML(fm *Frame) error {
+	b, err := io.ReadAll(fm.InputFile())
+	if err != nil {
+		return err
+	}
+	m, err := toml.Decode(string(b))
+	if err != nil {
+		return err
+	}
+	converted, err := fromDecoded(m)
+	if err != nil {
+		return err
+	}
+	return fm.ValueOutput().Put(converted)
+}
+
+func fromYAML(fm *Frame) error {
+	b, err := io.ReadAll(fm.InputFile())
+	if err != nil {
+		return err
+	}
+	docs, err := yaml.Decode(string(b))
+	if err != nil {
+		return err
+	}
+	out := fm.ValueOutput()
+	for _, doc := range docs {
+		converted, err := fromDecoded(doc)
+		if err != nil {
+			return err
+		}
+		err = out.Put(converted)
+		if err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 func fromTerminated(fm *Frame, terminator string) error {
@@ -486,3 +719,211 @@ func toJSON(fm *Frame, inputs Inputs) error {
 	})
 	return errEncode
 }
+
+type toCSVOpts struct{ Delimiter string }
+
+func (o *toCSVOpts) SetDefaultOptions() { o.Delimiter = "," }
+
+func toCSV(fm *Frame, opts toCSVOpts, inputs Inputs) error {
+	delimiter, err := checkDelimiter(opts.Delimiter)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(fm.ByteOutput())
+	w.Comma = delimiter
+
+	// Columns of map inputs, determined by the first map input.
+	var keys []any
+	var keySet vals.Map
+	var errOut error
+	inputs(func(v any) {
+		if errOut != nil {
+			return
+		}
+		var record []string
+		switch v := v.(type) {
+		case vals.List:
+			for it := v.Iterator(); it.HasElem(); it.Next() {
+				record = append(record, vals.ToString(it.Elem()))
+			}
+		case vals.Map:
+			if keys == nil {
+				keys = sortedMapKeys(v)
+				keySet = v
+				header := make([]string, len(keys))
+				for i, key := range keys {
+					header[i] = vals.ToString(key)
+				}
+				errOut = w.Write(header)
+				if errOut != nil {
+					return
+				}
+			}
+			// Keys not in the header can't be written.
+			for it := v.Iterator(); it.HasElem(); it.Next() {
+				key, _ := it.Elem()
+				if _, ok := keySet.Index(key); !ok {
+					errOut = errs.BadValue{What: "key of map input",
+						Valid: "a key of the first map input", Actual: vals.ReprPlain(key)}
+					return
+				}
+			}
+			for _, key := range keys {
+				field := ""
+				if val, ok := v.Index(key); ok {
+					field = vals.ToString(val)
+				}
+				record = append(record, field)
+			}
+		default:
+			errOut = errs.BadValue{What: "input",
+				Valid: "list or map", Actual: vals.ReprPlain(v)}
+			return
+		}
+		errOut = w.Write(record)
+	})
+	w.Flush()
+	if errOut != nil {
+		return errOut
+	}
+	return w.Error()
+}
+
+func toTOML(fm *Frame, inputs Inputs) error {
+	out := fm.ByteOutput()
+	var errOut error
+	inputs(func(v any) {
+		if errOut != nil {
+			return
+		}
+		var converted any
+		converted, errOut = toEncodable(v)
+		if errOut != nil {
+			return
+		}
+		m, ok := converted.(map[string]any)
+		if !ok {
+			errOut = errs.BadValue{What: "input",
+				Valid: "map", Actual: vals.ReprPlain(v)}
+			return
+		}
+		var s string
+		s, errOut = toml.Encode(m)
+		if errOut != nil {
+			return
+		}
+		_, errOut = out.WriteString(s)
+	})
+	return errOut
+}
+
+func toYAML(fm *Frame, inputs Inputs) error {
+	out := fm.ByteOutput()
+	first := true
+	var errOut error
+	inputs(func(v any) {
+		if errOut != nil {
+			return
+		}
+		var converted any
+		converted, errOut = toEncodable(v)
+		if errOut != nil {
+			return
+		}
+		var s string
+		s, errOut = yaml.Encode(converted)
+		if errOut != nil {
+			return
+		}
+		if !first {
+			s = "---\n" + s
+		}
+		first = false
+		_, errOut = out.WriteString(s)
+	})
+	return errOut
+}
+
+// Converts an Elvish value to a value understood by toml.Encode and
+// yaml.Encode.
+func toEncodable(v any) (any, error) {
+	switch v := v.(type) {
+	case nil, bool, string, int, *big.Int, float64:
+		return v, nil
+	case *big.Rat:
+		f, _ := v.Float64()
+		return f, nil
+	case vals.List:
+		list := make([]any, 0, v.Len())
+		for it := v.Iterator(); it.HasElem(); it.Next() {
+			converted, err := toEncodable(it.Elem())
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, converted)
+		}
+		return list, nil
+	case vals.Map:
+		m := make(map[string]any, v.Len())
+		for it := v.Iterator(); it.HasElem(); it.Next() {
+			key, val := it.Elem()
+			s, ok := key.(string)
+			if !ok {
+				return nil, errs.BadValue{What: "map key",
+					Valid: "string", Actual: vals.ReprPlain(key)}
+			}
+			converted, err := toEncodable(val)
+			if err != nil {
+				return nil, err
+			}
+			m[s] = converted
+		}
+		return m, nil
+	case encoding.TextMarshaler:
+		// Values with a text form, like time values, are written as strings
+		// rather than maps of their fields.
+		text, err := v.MarshalText()
+		return string(text), err
+	default:
+		if _, ok := v.(vals.PseudoMap); ok || vals.IsFieldMap(v) {
+			return fieldsToEncodable(v)
+		}
+		return nil, errs.BadValue{What: "value to encode",
+			Valid:  "nil, boolean, string, number, list or map",
+			Actual: vals.ReprPlain(v)}
+	}
+}
+
+// Converts a field map or pseudo map, whose keys are always strings.
+func fieldsToEncodable(v any) (any, error) {
+	m := make(map[string]any)
+	var errOut error
+	vals.IterateKeys(v, func(key any) bool {
+		val, err := vals.Index(v, key)
+		if err == nil {
+			val, err = toEncodable(val)
+		}
+		if err != nil {
+			errOut = err
+			return false
+		}
+		m[key.(string)] = val
+		return true
+	})
+	if errOut != nil {
+		return nil, errOut
+	}
+	return m, nil
+}
+
+func sortedMapKeys(m vals.Map) []any {
+	keys := make([]any, 0, m.Len())
+	for it := m.Iterator(); it.HasElem(); it.Next() {
+		key, _ := it.Elem()
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return vals.CmpTotal(keys[i], keys[j]) == vals.CmpLess
+	})
+	return keys
+}
@@ -21,10 +21,13 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"regexp"
+	"slices"
 	"strings"
 	"unicode/utf8"
 
 	"src.elv.sh/pkg/diag"
+	"src.elv.sh/pkg/eval/errs"
 	"src.elv.sh/pkg/eval/vals"
 	"src.elv.sh/pkg/eval/vars"
 	"src.elv.sh/pkg/parse"
@@ -79,6 +82,7 @@ func init() {
 		"while": compileWhile,
 		"for":   compileFor,
 		"try":   compileTry,
+		"match": compileMatch,
 
 		"pragma": compilePragma,
 	}
@@ -336,6 +340,7 @@ func compileFn(cp *compiler, fn *parse.Form) effectOp {
 	// Define the variable before compiling the body, so that the body may refer
 	// to the function itself.
 	index := cp.thisScope().add(name + FnSuffix)
+	cp.declareSymbol(cp.thisScope(), index, name+FnSuffix, fn.Args[0])
 	op := cp.lambda(bodyNode)
 
 	return fnOp{fn.Args[0].Range(), index, op}
@@ -388,7 +393,9 @@ func compileUse(cp *compiler, fn *parse.Form) effectOp {
 		return nil
 	}
 
-	return useOp{fn.Range(), cp.thisScope().add(name + NsSuffix), spec}
+	index := cp.thisScope().add(name + NsSuffix)
+	cp.declareSymbol(cp.thisScope(), index, name+NsSuffix, fn.Args[len(fn.Args)-1])
+	return useOp{fn.Range(), index, spec}
 }
 
 type useOp struct {
@@ -788,18 +795,29 @@ func compileTry(cp *compiler, fn *parse.Form) effectOp {
 	args := getArgs(cp, fn)
 	bodyNode := args.get(0, "try body").thunk()
 	i := 1
-	var catchVarNode *parse.Compound
-	var catchNode *parse.Primary
-	if args.hasKeyword(i, "catch") {
+	var catchNodes []catchNode
+	for args.hasKeyword(i, "catch") {
+		c := catchNode{keyword: fn.Args[i]}
 		i++
-		// Parse an optional lvalue into exceptVarNode.
+		if hasCatchFilter(args, i) {
+			c.filterType = args.get(i, "filter type").stringLiteral()
+			if c.filterType == "if" {
+				c.filterArg = args.get(i+1, "predicate").any()
+			} else {
+				patternArg := args.get(i+1, c.filterType+" pattern")
+				c.filterArg, c.pattern = patternArg.any(), patternArg.stringLiteral()
+			}
+			i += 2
+		}
+		// Parse an optional lvalue into varNode.
 		n := args.get(i, "variable or body").any()
 		if _, ok := cmpd.StringLiteral(n); ok {
-			catchVarNode = n
+			c.varNode = n
 			i++
 		}
-		catchNode = args.get(i, "catch body").thunk()
+		c.bodyNode = args.get(i, "catch body").thunk()
 		i++
+		catchNodes = append(catchNodes, c)
 	}
 	elseNode := args.optionalKeywordBody(i, "else")
 	if elseNode != nil {
@@ -810,20 +828,20 @@ func compileTry(cp *compiler, fn *parse.Form) effectOp {
 		return nil
 	}
 
-	if elseNode != nil && catchNode == nil {
+	if elseNode != nil && len(catchNodes) == 0 {
 		cp.errorpf(fn, "try with an else block requires a catch block")
-	} else if catchNode == nil && finallyNode == nil {
+	} else if len(catchNodes) == 0 && finallyNode == nil {
 		cp.errorpfPartial(fn, "try must be followed by a catch block or a finally block")
 	}
 
-	var catchVar lvalue
-	var bodyOp, catchOp, elseOp, finallyOp valuesOp
-	bodyOp = cp.primaryOp(bodyNode)
-	if catchVarNode != nil {
-		catchVar = cp.compileOneLValue(catchVarNode, setLValue|newLValue)
-	}
-	if catchNode != nil {
-		catchOp = cp.primaryOp(catchNode)
+	var elseOp, finallyOp valuesOp
+	bodyOp := cp.primaryOp(bodyNode)
+	catches := make([]*catchClause, len(catchNodes))
+	for i, c := range catchNodes {
+		if i > 0 && catchNodes[i-1].filterType == "" {
+			cp.errorpf(c.keyword, "unreachable catch clause")
+		}
+		catches[i] = c.compile(cp)
 	}
 	if elseNode != nil {
 		elseOp = cp.primaryOp(elseNode)
@@ -832,42 +850,109 @@ func compileTry(cp *compiler, fn *parse.Form) effectOp {
 		finallyOp = cp.primaryOp(finallyNode)
 	}
 
-	return &tryOp{fn.Range(), bodyOp, catchVar, catchOp, elseOp, finallyOp}
+	return &tryOp{fn.Range(), bodyOp, catches, elseOp, finallyOp}
+}
+
+// IsCatchFilterType is the set of the filter types of catch clauses in try. It
+// is intended for external consumption, e.g. the syntax highlighter.
+var IsCatchFilterType = map[string]bool{"type": true, "kind": true, "fail": true, "if": true}
+
+// Returns whether the catch clause continuing at the i-th argument has a
+// filter. A filter is a filter type followed by an argument; if there are no
+// more arguments after that, or the next argument is a keyword that starts
+// another clause, the filter type is instead the variable.
+func hasCatchFilter(args *argsGetter, i int) bool {
+	if !args.has(i+2) || args.hasKeyword(i+2, "catch") ||
+		args.hasKeyword(i+2, "else") || args.hasKeyword(i+2, "finally") {
+		return false
+	}
+	typ, _ := cmpd.StringLiteral(args.fn.Args[i])
+	return IsCatchFilterType[typ]
+}
+
+// Nodes of a catch clause.
+type catchNode struct {
+	keyword    *parse.Compound
+	filterType string
+	filterArg  *parse.Compound
+	pattern    string
+	varNode    *parse.Compound
+	bodyNode   *parse.Primary
+}
+
+func (c catchNode) compile(cp *compiler) *catchClause {
+	clause := &catchClause{filterType: c.filterType}
+	switch c.filterType {
+	case "type", "fail":
+		clause.pattern = regexp.MustCompile(globToRegexp(c.pattern))
+	case "kind":
+		clause.kind = c.pattern
+	case "if":
+		clause.predOp = cp.compoundOp(c.filterArg)
+	}
+	if c.varNode != nil {
+		clause.lvalue = cp.compileOneLValue(c.varNode, setLValue|newLValue)
+	}
+	clause.bodyOp = cp.primaryOp(c.bodyNode)
+	return clause
 }
 
 type tryOp struct {
 	diag.Ranging
 	bodyOp    valuesOp
-	catchVar  lvalue
-	catchOp   valuesOp
+	catches   []*catchClause
 	elseOp    valuesOp
 	finallyOp valuesOp
 }
 
+type catchClause struct {
+	// One of "", "type", "kind", "fail" and "if".
+	filterType string
+	// Compiled glob pattern of type and fail filters.
+	pattern *regexp.Regexp
+	kind    string
+	predOp  valuesOp
+	lvalue  lvalue
+	bodyOp  valuesOp
+}
+
 func (op *tryOp) exec(fm *Frame) Exception {
 	body := execLambdaOp(fm, op.bodyOp)
-	var exceptVar vars.Var
-	if op.catchVar.ref != nil {
-		var err error
-		exceptVar, err = derefLValue(fm, op.catchVar)
-		if err != nil {
-			return fm.errorp(op, err)
+	exceptVars := make([]vars.Var, len(op.catches))
+	catches := make([]Callable, len(op.catches))
+	for i, c := range op.catches {
+		if c.lvalue.ref != nil {
+			var err error
+			exceptVars[i], err = derefLValue(fm, c.lvalue)
+			if err != nil {
+				return fm.errorp(op, err)
+			}
 		}
+		catches[i] = execLambdaOp(fm, c.bodyOp)
 	}
-	catch := execLambdaOp(fm, op.catchOp)
 	elseFn := execLambdaOp(fm, op.elseOp)
 	finally := execLambdaOp(fm, op.finallyOp)
 
 	err := body.Call(fm.Fork(), NoArgs, NoOpts)
 	if err != nil {
-		if catch != nil {
-			if exceptVar != nil {
-				err := exceptVar.Set(err.(Exception))
+		exc := err.(Exception)
+		for i, c := range op.catches {
+			matched, errFilter := c.match(fm, exc)
+			if errFilter != nil {
+				err = errFilter
+				break
+			}
+			if !matched {
+				continue
+			}
+			if exceptVars[i] != nil {
+				err := exceptVars[i].Set(exc)
 				if err != nil {
-					return fm.errorp(op.catchVar, err)
+					return fm.errorp(c.lvalue, err)
 				}
 			}
-			err = catch.Call(fm.Fork(), NoArgs, NoOpts)
+			err = catches[i].Call(fm.Fork(), NoArgs, NoOpts)
+			break
 		}
 	} else {
 		if elseFn != nil {
@@ -885,6 +970,329 @@ func (op *tryOp) exec(fm *Frame) Exception {
 	return fm.errorp(op, err)
 }
 
+// Returns whether the exception is caught by the catch clause.
+func (c *catchClause) match(fm *Frame, exc Exception) (bool, error) {
+	reason := exc.Reason()
+	switch c.filterType {
+	case "type":
+		typ, err := vals.Index(reason, "type")
+		if err != nil {
+			return false, nil
+		}
+		s, ok := typ.(string)
+		return ok && c.pattern.MatchString(s), nil
+	case "kind":
+		return vals.Kind(reason) == c.kind, nil
+	case "fail":
+		failErr, ok := reason.(FailError)
+		return ok && c.pattern.MatchString(vals.ToString(failErr.Content)), nil
+	case "if":
+		pred, errPred := evalForValue(fm, c.predOp, "predicate")
+		if errPred != nil {
+			return false, errPred
+		}
+		predFn, ok := pred.(Callable)
+		if !ok {
+			return false, fm.errorp(c.predOp, errs.BadValue{What: "predicate",
+				Valid: "callable", Actual: vals.Kind(pred)})
+		}
+		outputs, err := fm.CaptureOutput(func(fm *Frame) error {
+			return predFn.Call(fm, []any{exc}, NoOpts)
+		})
+		if err != nil {
+			return false, fm.errorp(c.predOp, err)
+		}
+		if len(outputs) != 1 {
+			return false, fm.errorp(c.predOp, errs.ArityMismatch{
+				What: "number of outputs of the predicate", ValidLow: 1, ValidHigh: 1,
+				Actual: len(outputs)})
+		}
+		ok, isBool := outputs[0].(bool)
+		if !isBool {
+			return false, fm.errorp(c.predOp, errs.BadValue{What: "output of the predicate",
+				Valid: "boolean", Actual: vals.Kind(outputs[0])})
+		}
+		return ok, nil
+	}
+	return true, nil
+}
+
+// MatchForm = 'match' Compound { MatchArm }
+// MatchArm = ( 'eq' | 'glob' | 're' | 'kind' ) Compound Lambda
+// MatchArm = ( 'list' | 'map' | 'else' ) Lambda
+func compileMatch(cp *compiler, fn *parse.Form) effectOp {
+	args := getArgs(cp, fn)
+	valueNode := args.get(0, "value").any()
+	var arms []*matchArm
+	var bodyNodes []*parse.Primary
+	for i := 1; args.has(i); {
+		typeArg := args.get(i, "arm type")
+		typeNode, typ := typeArg.any(), typeArg.stringLiteral()
+		arm := &matchArm{typ: typ}
+		var body *parse.Primary
+		switch typ {
+		case "eq", "glob", "re", "kind":
+			arm.pattern = args.get(i+1, typ+" pattern").stringLiteral()
+			body = args.get(i+2, typ+" body").lambda()
+			i += 3
+		case "list", "map":
+			body = args.get(i+1, typ+" body").lambda()
+			i += 2
+		case "else":
+			body = args.get(i+1, "else body").thunk()
+			i += 2
+		default:
+			args.errorpf(typeNode,
+				"arm type must be one of eq, glob, re, kind, list, map and else, found %s",
+				parse.Quote(typ))
+		}
+		if body == nil {
+			break
+		}
+		arm.Ranging = diag.Ranging{From: typeNode.Range().From, To: body.Range().To}
+		arms = append(arms, arm)
+		bodyNodes = append(bodyNodes, body)
+	}
+	if !args.finish() {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	for i, arm := range arms {
+		arm.compile(cp, bodyNodes[i])
+		// A duplicate arm is also unreachable; only report the more specific
+		// error.
+		if key := arm.key(); seen[key] {
+			cp.errorpf(arm, "duplicate arm")
+		} else {
+			seen[key] = true
+			for _, earlier := range arms[:i] {
+				if earlier.covers(arm) {
+					cp.errorpf(arm, "unreachable arm")
+					break
+				}
+			}
+		}
+		arm.bodyOp = cp.primaryOp(bodyNodes[i])
+	}
+
+	return &matchOp{fn.Range(), cp.compoundOp(valueNode), arms}
+}
+
+type matchOp struct {
+	diag.Ranging
+	valueOp valuesOp
+	arms    []*matchArm
+}
+
+type matchArm struct {
+	diag.Ranging
+	typ     string
+	pattern string
+	// Compiled pattern of glob and re arms.
+	re *regexp.Regexp
+	// Names of the arguments of the body, excluding the rest argument.
+	params []string
+	rest   bool
+	bodyOp valuesOp
+}
+
+// Parses the pattern and checks the signature of the body.
+func (arm *matchArm) compile(cp *compiler, body *parse.Primary) {
+	for _, elem := range body.Elements {
+		if s, ok := cmpd.StringLiteral(elem); ok {
+			if sigil, qname := SplitSigil(s); sigil == "@" {
+				arm.rest = true
+			} else {
+				arm.params = append(arm.params, qname)
+			}
+		}
+	}
+	if len(body.MapPairs) > 0 {
+		cp.errorpf(body, "%s body must not have options", arm.typ)
+	}
+	switch arm.typ {
+	case "eq", "glob", "kind":
+		if len(arm.params) > 1 {
+			cp.errorpf(body, "%s body must take at most one argument", arm.typ)
+		}
+		if arm.typ == "glob" {
+			arm.re = regexp.MustCompile(globToRegexp(arm.pattern))
+		}
+	case "re":
+		re, err := regexp.Compile(arm.pattern)
+		if err != nil {
+			cp.errorpf(arm, "bad regular expression: %v", err)
+			return
+		}
+		arm.re = re
+		n := re.NumSubexp() + 1
+		if len(arm.params) > n || !arm.rest && len(arm.params) != 0 && len(arm.params) != n {
+			cp.errorpf(body, "re body must take no arguments or %d arguments", n)
+		}
+	case "map":
+		if len(arm.params) == 0 || arm.rest {
+			cp.errorpf(body, "map body must take at least one argument and no rest argument")
+		}
+	}
+}
+
+// Converts a glob pattern to an anchored regular expression, where * and **
+// match any string, and ? matches any single character.
+func globToRegexp(p string) string {
+	var sb strings.Builder
+	sb.WriteString("^(?s:")
+	for _, r := range p {
+		switch r {
+		case '*':
+			if !strings.HasSuffix(sb.String(), ".*") {
+				sb.WriteString(".*")
+			}
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString(")$")
+	return sb.String()
+}
+
+// Returns a string that is the same for two arms iff they match the same
+// values.
+func (arm *matchArm) key() string {
+	switch arm.typ {
+	case "list":
+		return fmt.Sprintf("list %d %v", len(arm.params), arm.rest)
+	case "map":
+		params := slices.Clone(arm.params)
+		slices.Sort(params)
+		return "map " + strings.Join(params, " ")
+	default:
+		return arm.typ + " " + arm.pattern
+	}
+}
+
+// Returns whether all values matched by later are also matched by arm.
+func (arm *matchArm) covers(later *matchArm) bool {
+	switch arm.typ {
+	case "else":
+		return true
+	case "kind":
+		// A kind map arm doesn't cover map arms, since map arms also match
+		// map-like values of other kinds, like records.
+		return arm.pattern == "string" && (later.typ == "glob" || later.typ == "re") ||
+			arm.pattern == "list" && later.typ == "list"
+	case "glob":
+		return strings.Trim(arm.pattern, "*") == "" && arm.pattern != "" &&
+			(later.typ == "glob" || later.typ == "re")
+	case "list":
+		return later.typ == "list" && arm.rest && len(later.params) >= len(arm.params)
+	case "map":
+		if later.typ != "map" {
+			return false
+		}
+		for _, p := range arm.params {
+			if !slices.Contains(later.params, p) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// Matches v against the pattern of the arm, and returns the arguments to call
+// the body with.
+func (arm *matchArm) match(v any) ([]any, bool) {
+	switch arm.typ {
+	case "eq":
+		if s, ok := v.(string); ok {
+			return arm.valueArgs(v), s == arm.pattern
+		}
+		if _, ok := v.(vals.Num); ok {
+			n := vals.ParseNum(arm.pattern)
+			return arm.valueArgs(v), n != nil && vals.Cmp(v, n) == vals.CmpEqual
+		}
+	case "glob":
+		if s, ok := v.(string); ok {
+			return arm.valueArgs(v), arm.re.MatchString(s)
+		}
+	case "re":
+		if s, ok := v.(string); ok {
+			m := arm.re.FindStringSubmatch(s)
+			if m == nil {
+				return nil, false
+			}
+			if len(arm.params) == 0 && !arm.rest {
+				return NoArgs, true
+			}
+			args := make([]any, len(m))
+			for i, s := range m {
+				args[i] = s
+			}
+			return args, true
+		}
+	case "kind":
+		return arm.valueArgs(v), vals.Kind(v) == arm.pattern
+	case "list":
+		if vals.Kind(v) != "list" {
+			return nil, false
+		}
+		n := vals.Len(v)
+		if n < len(arm.params) || !arm.rest && n > len(arm.params) {
+			return nil, false
+		}
+		var args []any
+		err := vals.Iterate(v, func(e any) bool {
+			args = append(args, e)
+			return true
+		})
+		return args, err == nil
+	case "map":
+		if vals.IterateKeys(v, func(any) bool { return false }) != nil {
+			return nil, false
+		}
+		args := make([]any, len(arm.params))
+		for i, p := range arm.params {
+			if !vals.HasKey(v, p) {
+				return nil, false
+			}
+			var err error
+			args[i], err = vals.Index(v, p)
+			if err != nil {
+				return nil, false
+			}
+		}
+		return args, true
+	case "else":
+		return NoArgs, true
+	}
+	return nil, false
+}
+
+// Returns the arguments for a body that optionally takes the value itself.
+func (arm *matchArm) valueArgs(v any) []any {
+	if len(arm.params) == 0 && !arm.rest {
+		return NoArgs
+	}
+	return []any{v}
+}
+
+func (op *matchOp) exec(fm *Frame) Exception {
+	value, exc := evalForValue(fm, op.valueOp, "value being matched")
+	if exc != nil {
+		return exc
+	}
+	for _, arm := range op.arms {
+		if args, ok := arm.match(value); ok {
+			body := execLambdaOp(fm, arm.bodyOp)
+			return fm.errorp(arm, body.Call(fm.Fork(), args, NoOpts))
+		}
+	}
+	return nil
+}
+
 // PragmaForm = 'pragma' 'fallback-resolver' '=' { Compound }
 func compilePragma(cp *compiler, fn *parse.Form) effectOp {
 	args := getArgs(cp, fn)
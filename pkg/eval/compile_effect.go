@@ -1,7 +1,6 @@
 package eval
 
 import (
-	"context"
 	"fmt"
 	"os"
 	"sync"
@@ -71,11 +70,20 @@ func (op *pipelineOp) exec(fm *Frame) Exception {
 		return fm.errorp(op, ErrInterrupted)
 	}
 
-	if op.bg {
+	// Background pipelines are always jobs. Foreground pipelines are also jobs
+	// when job control is enabled and they are not part of another job.
+	var j *job
+	outerCtx := fm.ctx
+	if op.bg || (fm.job == nil && fm.jobControl) {
+		j = newJob(op.source, !op.bg, fm.jobControl)
 		fm = fm.Fork()
-		fm.ctx = context.Background()
-		fm.background = true
-		fm.Evaler.addNumBgJobs(1)
+		fm.ctx = j.ctx
+		fm.background = op.bg
+		fm.job = j
+		j.ownOutputs(fm)
+		if op.bg {
+			fm.Evaler.jobs.add(j)
+		}
 	}
 
 	nforms := len(op.subops)
@@ -130,33 +138,32 @@ func (op *pipelineOp) exec(fm *Frame) Exception {
 			newFm.Close()
 			wg.Done()
 		}
-		if i == nforms-1 && !op.bg {
+		// Jobs may be stopped, so all their forms run asynchronously.
+		if i == nforms-1 && j == nil {
 			f(formOp, &excs[i])
 		} else {
 			go f(formOp, &excs[i])
 		}
 	}
 
+	if j == nil {
+		wg.Wait()
+		return fm.errorp(op, MakePipelineError(excs))
+	}
+	go func() {
+		wg.Wait()
+		j.closeOutputs()
+		fm.Evaler.finishJob(j, fm.errorp(op, MakePipelineError(excs)))
+	}()
 	if op.bg {
-		// Background job, wait for form termination asynchronously.
-		go func() {
-			wg.Wait()
-			fm.Evaler.addNumBgJobs(-1)
-			if notify := fm.Evaler.BgJobNotify; notify != nil {
-				msg := "job " + op.source + " finished"
-				err := MakePipelineError(excs)
-				if err != nil {
-					msg += ", errors = " + err.Error()
-				}
-				if fm.Evaler.getNotifyBgJobSuccess() || err != nil {
-					notify(msg)
-				}
-			}
-		}()
 		return nil
 	}
-	wg.Wait()
-	return fm.errorp(op, MakePipelineError(excs))
+	if fm.Evaler.waitForeground(outerCtx, j) {
+		j.detachOutputs()
+		fmt.Fprintln(fm.ErrorFile(), j.describe())
+		return nil
+	}
+	return j.err
 }
 
 func isReaderGone(exc Exception) bool {
@@ -191,6 +198,7 @@ func (cp *compiler) formBody(n *parse.Form) formBody {
 		// Head is a literal string: resolve to function or external (special
 		// commands are already handled above).
 		if _, fnRef := resolveCmdHeadInternally(cp, head, n.Head); fnRef != nil {
+			cp.referenceSymbol(head+FnSuffix, n.Head)
 			headOp = variableOp{n.Head.Range(), false, head + FnSuffix, fnRef}
 		} else {
 			cp.autofixUnresolvedVar(head + FnSuffix)
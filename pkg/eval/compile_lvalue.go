@@ -70,6 +70,9 @@ func (cp *compiler) compileIndexingLValue(n *parse.Indexing, f lvalueFlag) lvalu
 	var ref *varRef
 	if f&setLValue != 0 {
 		ref = resolveVarRef(cp, qname, n)
+		if ref != nil {
+			cp.referenceSymbol(qname, n.Head)
+		}
 		if ref != nil && len(ref.subNames) == 0 && ref.info.readOnly {
 			cp.errorpf(n, "variable $%s is read-only", parse.Quote(qname))
 			return dummyLValuesGroup
@@ -89,8 +92,9 @@ func (cp *compiler) compileIndexingLValue(n *parse.Indexing, f lvalueFlag) lvalu
 		if len(segs) == 1 {
 			// Unqualified name - implicit local
 			name := segs[0]
-			ref = &varRef{localScope,
-				staticVarInfo{name, false, false}, cp.thisScope().add(name), nil}
+			index := cp.thisScope().add(name)
+			cp.declareSymbol(cp.thisScope(), index, name, n.Head)
+			ref = &varRef{localScope, staticVarInfo{name, false, false}, index, nil}
 		} else {
 			cp.errorpf(n, "cannot create variable $%s; "+
 				"new variables can only be created in the current scope",
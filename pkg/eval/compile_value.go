@@ -268,6 +268,8 @@ func (cp *compiler) primaryOp(n *parse.Primary) valuesOp {
 			// names to check if that's actually the case, but it's a bit
 			// expensive and let's call this good enough for now.
 			cp.errorpfPartial(n, "variable $%s not found", parse.Quote(qname))
+		} else {
+			cp.referenceSymbol(qname, n)
 		}
 		return &variableOp{n.Range(), sigil != "", qname, ref}
 	case parse.Wildcard:
@@ -434,11 +436,11 @@ func (cp *compiler) lambda(n *parse.Primary) valuesOp {
 	}
 
 	local, capture := cp.pushScope()
-	for _, argName := range argNames {
-		local.add(argName)
+	for i, argName := range argNames {
+		cp.declareSymbol(local, local.add(argName), argName, n.Elements[i])
 	}
-	for _, optName := range optNames {
-		local.add(optName)
+	for i, optName := range optNames {
+		cp.declareSymbol(local, local.add(optName), optName, n.MapPairs[i].Key)
 	}
 	scopeSizeInit := len(local.infos)
 	chunkOp := cp.chunkOp(n.Chunk)
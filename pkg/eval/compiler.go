@@ -35,19 +35,36 @@ type compiler struct {
 	errors []*CompilationError
 	// Suggested code to fix potential issues found during compilation.
 	autofixes []string
+	// Where to record symbols. Only non-nil when called from CheckAll.
+	symbols *symbolRecorder
+	// Where to record deprecations. Only non-nil when called from CheckAll.
+	deprecationsFound *[]Deprecation
+}
+
+// Options for compile. All fields are optional.
+type compileOpts struct {
+	// Names of internal modules, used to suggest autofixes.
+	modules []string
+	// Where to write deprecation messages.
+	warn io.Writer
+	// Where to record symbols.
+	symbols *symbolRecorder
+	// Where to record deprecations.
+	deprecations *[]Deprecation
 }
 
 type scopePragma struct {
 	unknownCommandIsExternal bool
 }
 
-func compile(b, g *staticNs, modules []string, tree parse.Tree, w io.Writer) (nsOp, []string, error) {
+func compile(b, g *staticNs, tree parse.Tree, opts compileOpts) (nsOp, []string, error) {
 	g = g.clone()
 	cp := &compiler{
 		b, []*staticNs{g}, []*staticUpNs{new(staticUpNs)},
 		[]*scopePragma{{unknownCommandIsExternal: true}},
-		modules,
-		w, newDeprecationRegistry(), tree.Source, nil, nil}
+		opts.modules,
+		opts.warn, newDeprecationRegistry(), tree.Source, nil, nil,
+		opts.symbols, opts.deprecations}
 	chunkOp := cp.chunkOp(tree.Root)
 	return nsOp{chunkOp, g}, cp.autofixes, diag.PackErrors(cp.errors)
 }
@@ -143,7 +160,7 @@ func (cp *compiler) popScope() {
 }
 
 func (cp *compiler) checkDeprecatedBuiltin(name string, r diag.Ranger) {
-	msg := ""
+	msg, replacement := "", ""
 	minLevel := 22
 	switch name {
 	// We don't have any deprecated builtins targeted for 0.22 yet, but keep
@@ -152,22 +169,32 @@ func (cp *compiler) checkDeprecatedBuiltin(name string, r diag.Ranger) {
 	// one here is harmless.
 	case "foo~":
 		msg = `the "foo" command is deprecated; use "bar" instead`
+		replacement = "bar"
 	default:
 		return
 	}
-	cp.deprecate(r, msg, minLevel)
+	cp.deprecate(r, msg, replacement, minLevel)
 }
 
 type deprecationTag struct{}
 
 func (deprecationTag) ErrorTag() string { return "deprecation" }
 
-func (cp *compiler) deprecate(r diag.Ranger, msg string, minLevel int) {
-	if cp.warn == nil || r == nil {
+// Reports a deprecation. If replacement is not empty, it is code that can
+// replace the code in r to fix the deprecation.
+func (cp *compiler) deprecate(r diag.Ranger, msg, replacement string, minLevel int) {
+	if r == nil || prog.DeprecationLevel < minLevel {
 		return
 	}
 	dep := deprecation{cp.src.Name, r.Range(), msg}
-	if prog.DeprecationLevel >= minLevel && cp.deprecations.register(dep) {
+	if !cp.deprecations.register(dep) {
+		return
+	}
+	if cp.deprecationsFound != nil {
+		*cp.deprecationsFound = append(*cp.deprecationsFound,
+			Deprecation{r.Range(), msg, replacement})
+	}
+	if cp.warn != nil {
 		err := diag.Error[deprecationTag]{
 			Message: msg,
 			Context: *diag.NewContext(cp.src.Name, cp.src.Code, r.Range())}
@@ -7,7 +7,10 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"src.elv.sh/pkg/diag"
 	. "src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/must"
 	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/prog"
+	"src.elv.sh/pkg/testutil"
 )
 
 var autofixTests = []struct {
@@ -67,6 +70,28 @@ func TestAutofix(t *testing.T) {
 	}
 }
 
+func TestCheckDeprecations(t *testing.T) {
+	ev := NewEvaler()
+	// "foo" is a fake deprecated builtin; see checkDeprecatedBuiltin.
+	ev.ExtendBuiltin(BuildNs().AddGoFn("foo", func() {}))
+	tree := must.OK1(parse.Parse(parse.Source{Name: "[test]", Code: "echo; foo"}, parse.Config{}))
+
+	testutil.Set(t, &prog.DeprecationLevel, 22)
+	want := []Deprecation{{
+		Range:       diag.Ranging{From: 6, To: 9},
+		Message:     `the "foo" command is deprecated; use "bar" instead`,
+		Replacement: "bar",
+	}}
+	if diff := cmp.Diff(want, ev.CheckDeprecations(tree)); diff != "" {
+		t.Errorf("deprecations (-want +got):\n%s", diff)
+	}
+
+	testutil.Set(t, &prog.DeprecationLevel, 21)
+	if deps := ev.CheckDeprecations(tree); deps != nil {
+		t.Errorf("got deprecations %v below deprecation level", deps)
+	}
+}
+
 // TODO: Turn this into a fuzz test.
 func TestPartialCompilationError(t *testing.T) {
 	for _, code := range transcriptCodes {
@@ -4,6 +4,15 @@ import (
 	"src.elv.sh/pkg/diag"
 )
 
+// Deprecation is a use of a deprecated feature found during compilation.
+type Deprecation struct {
+	Range   diag.Ranging
+	Message string
+	// If not empty, code that the deprecated code in Range can be replaced
+	// with.
+	Replacement string
+}
+
 type deprecationRegistry struct {
 	registered map[deprecation]struct{}
 }
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"sync"
 
@@ -55,6 +57,9 @@ type Evaler struct {
 	// Callback to notify the success or failure of background jobs. Must not be
 	// mutated once the Evaler is used to evaluate any code.
 	BgJobNotify func(string)
+	// Hooks to run when a background job finishes, called with a map
+	// describing the job. The edit module exposes them as $edit:after-bg-job.
+	AfterBgJob []func(vals.Map)
 	// Path to the rc file, and path to the rc file actually evaluated. These
 	// are not used by the Evaler itself right now; they are here so that they
 	// can be exposed to the runtime: module.
@@ -84,8 +89,10 @@ type Evaler struct {
 	// Whether to notify the success of background jobs, exposed as
 	// $notify-bg-job-sucess.
 	notifyBgJobSuccess bool
-	// The current number of background jobs, exposed as $num-bg-jobs.
-	numBgJobs int
+
+	// Jobs in the background, including stopped jobs. The number of jobs is
+	// exposed as $num-bg-jobs.
+	jobs jobTable
 }
 
 // NewEvaler creates a new Evaler.
@@ -108,7 +115,6 @@ func NewEvaler() *Evaler {
 
 		valuePrefix:        defaultValuePrefix,
 		notifyBgJobSuccess: defaultNotifyBgJobSuccess,
-		numBgJobs:          0,
 		Args:               vals.EmptyList,
 	}
 
@@ -132,7 +138,7 @@ func NewEvaler() *Evaler {
 		AddVar("notify-bg-job-success",
 			vars.FromPtrWithMutex(&ev.notifyBgJobSuccess, &ev.mu)).
 		AddVar("num-bg-jobs",
-			vars.FromGet(func() any { return strconv.Itoa(ev.getNumBgJobs()) })).
+			vars.FromGet(func() any { return strconv.Itoa(len(ev.jobs.list())) })).
 		AddVar("args", vars.FromGet(func() any { return ev.Args })))
 
 	// Install the "builtin" module after extension is complete.
@@ -227,18 +233,6 @@ func (ev *Evaler) getNotifyBgJobSuccess() bool {
 	return ev.notifyBgJobSuccess
 }
 
-func (ev *Evaler) getNumBgJobs() int {
-	ev.mu.RLock()
-	defer ev.mu.RUnlock()
-	return ev.numBgJobs
-}
-
-func (ev *Evaler) addNumBgJobs(delta int) {
-	ev.mu.Lock()
-	defer ev.mu.Unlock()
-	ev.numBgJobs += delta
-}
-
 // Chdir changes the current directory, and updates $E:PWD on success
 //
 // It runs the functions in beforeChdir immediately before changing the
@@ -280,6 +274,11 @@ type EvalCfg struct {
 	// Whether the Eval method should try to put the Elvish in the foreground
 	// after the code is executed.
 	PutInFg bool
+	// Whether to enable job control. When enabled, each pipeline evaluated at
+	// the top level runs as a job in its own process group, which is put in
+	// the foreground of the terminal and can be suspended. It should only be
+	// enabled for code entered in an interactive shell.
+	JobControl bool
 	// If not nil, used the given global namespace, instead of Evaler's own.
 	Global *Ns
 }
@@ -323,7 +322,7 @@ func (ev *Evaler) Eval(src parse.Source, cfg EvalCfg) error {
 		ev.mu.Unlock()
 	}
 
-	op, _, err := compile(b.static(), cfg.Global.static(), nil, tree, errFile)
+	op, _, err := compile(b.static(), cfg.Global.static(), tree, compileOpts{warn: errFile})
 	if err != nil {
 		if defaultGlobal {
 			ev.mu.Unlock()
@@ -382,7 +381,7 @@ func (ev *Evaler) prepareFrame(src parse.Source, cfg EvalCfg) (*Frame, func()) {
 
 	ports := fillDefaultDummyPorts(cfg.Ports)
 
-	fm := &Frame{ev, src, cfg.Global, new(Ns), nil, intCtx, ports, nil, false}
+	fm := &Frame{ev, src, cfg.Global, new(Ns), nil, intCtx, ports, nil, false, nil, cfg.JobControl}
 	return fm, func() {
 		if cfg.PutInFg {
 			err := putSelfInFg()
@@ -423,6 +422,59 @@ func (ev *Evaler) CheckTree(tree parse.Tree, w io.Writer) ([]string, error) {
 	ev.mu.RLock()
 	b, g, m := ev.builtin, ev.global, ev.modules
 	ev.mu.RUnlock()
-	_, autofixes, compileErr := compile(b.static(), g.static(), mapKeys(m), tree, w)
+	_, autofixes, compileErr := compile(b.static(), g.static(), tree,
+		compileOpts{modules: mapKeys(m), warn: w})
 	return autofixes, compileErr
 }
+
+// Analysis is the result of CheckAll.
+type Analysis struct {
+	Autofixes  []string
+	CompileErr error
+	// Variables declared in the tree, as returned by CheckSymbols.
+	Symbols []*Symbol
+	// Uses of deprecated features, as returned by CheckDeprecations.
+	Deprecations []Deprecation
+}
+
+// CheckAll compiles the given parsed source tree once, and returns everything
+// that CheckTree, CheckSymbols and CheckDeprecations would return.
+func (ev *Evaler) CheckAll(tree parse.Tree) Analysis {
+	ev.mu.RLock()
+	b, g, m := ev.builtin, ev.global, ev.modules
+	ev.mu.RUnlock()
+	s := &symbolRecorder{byVar: make(map[symbolKey]*Symbol)}
+	var deps []Deprecation
+	_, autofixes, compileErr := compile(b.static(), g.static(), tree,
+		compileOpts{modules: mapKeys(m), symbols: s, deprecations: &deps})
+	return Analysis{autofixes, compileErr, s.sorted(), deps}
+}
+
+// CheckDeprecations compiles the given parsed source tree like CheckTree, and
+// returns the uses of deprecated features that are deprecated as of the
+// current deprecation level.
+func (ev *Evaler) CheckDeprecations(tree parse.Tree) []Deprecation {
+	return ev.CheckAll(tree).Deprecations
+}
+
+// PredefinedModules returns the sorted specs of all modules that can be
+// imported without a file, which includes modules added with AddModule and
+// bundled modules.
+func (ev *Evaler) PredefinedModules() []string {
+	ev.mu.RLock()
+	defer ev.mu.RUnlock()
+	var specs []string
+	for spec := range ev.modules {
+		// Modules loaded from files are indexed by their absolute paths.
+		if !filepath.IsAbs(spec) {
+			specs = append(specs, spec)
+		}
+	}
+	for spec := range ev.BundledModules {
+		if _, ok := ev.modules[spec]; !ok {
+			specs = append(specs, spec)
+		}
+	}
+	sort.Strings(specs)
+	return specs
+}
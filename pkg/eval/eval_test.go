@@ -4,6 +4,7 @@ import (
 	"sync"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	. "src.elv.sh/pkg/eval"
 
 	"src.elv.sh/pkg/eval/vars"
@@ -90,6 +91,16 @@ var checkTests = []struct {
 		wantParseErr: true, wantCompileErr: true},
 }
 
+func TestPredefinedModules(t *testing.T) {
+	ev := NewEvaler()
+	ev.AddModule("mod", &Ns{})
+	ev.BundledModules["bundled"] = "echo bundled"
+	want := []string{"builtin", "bundled", "mod"}
+	if diff := cmp.Diff(want, ev.PredefinedModules()); diff != "" {
+		t.Errorf("predefined modules (-want +got):\n%s", diff)
+	}
+}
+
 func TestCheck(t *testing.T) {
 	ev := NewEvaler()
 	for _, test := range checkTests {
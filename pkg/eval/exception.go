@@ -80,20 +80,17 @@ var (
 func (exc *exception) Show(indent string) string {
 	buf := new(bytes.Buffer)
 
-	var causeDescription string
-	if shower, ok := exc.reason.(diag.Shower); ok {
-		causeDescription = shower.Show(indent)
-	} else if exc.reason == nil {
-		causeDescription = "ok"
-	} else {
-		causeDescription = exceptionCauseStartMarker + exc.reason.Error() + exceptionCauseEndMarker
-	}
-	fmt.Fprintf(buf, "Exception: %s", causeDescription)
+	fmt.Fprintf(buf, "Exception: %s", describeReason(exc.reason, indent))
+
+	exc.writeStackTrace(buf, indent)
 
-	if exc.stackTrace != nil {
-		for tb := exc.stackTrace; tb != nil; tb = tb.Next {
-			buf.WriteString("\n" + indent + "  ")
-			buf.WriteString(tb.Head.Show(indent + "  "))
+	for cause := causeOf(exc); cause != nil; cause = causeOf(cause) {
+		buf.WriteString("\n" + indent + "Caused by: ")
+		if causeExc, ok := cause.(*exception); ok {
+			buf.WriteString(describeReason(causeExc.reason, indent))
+			causeExc.writeStackTrace(buf, indent)
+		} else {
+			buf.WriteString(describeReason(cause, indent))
 		}
 	}
 
@@ -110,6 +107,34 @@ func (exc *exception) Show(indent string) string {
 	return buf.String()
 }
 
+func describeReason(reason error, indent string) string {
+	if shower, ok := reason.(diag.Shower); ok {
+		return shower.Show(indent)
+	} else if reason == nil {
+		return "ok"
+	}
+	return exceptionCauseStartMarker + reason.Error() + exceptionCauseEndMarker
+}
+
+func (exc *exception) writeStackTrace(buf *bytes.Buffer, indent string) {
+	for tb := exc.stackTrace; tb != nil; tb = tb.Next {
+		buf.WriteString("\n" + indent + "  ")
+		buf.WriteString(tb.Head.Show(indent + "  "))
+	}
+}
+
+// Returns the cause of an error raised by "fail", or an exception with such an
+// error as the reason. Returns nil for other errors.
+func causeOf(err error) error {
+	if exc, ok := err.(*exception); ok {
+		err = exc.reason
+	}
+	if e, ok := err.(FailError); ok {
+		return e.Cause
+	}
+	return nil
+}
+
 // Kind returns "exception".
 func (exc *exception) Kind() string {
 	return "exception"
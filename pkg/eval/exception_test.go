@@ -24,7 +24,7 @@ func TestReason(t *testing.T) {
 }
 
 func TestException(t *testing.T) {
-	err := FailError{"error"}
+	err := FailError{Content: "error"}
 	exc := makeException(err)
 	vals.TestValue(t, exc).
 		Kind("exception").
@@ -7,7 +7,6 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
-	"syscall"
 
 	"src.elv.sh/pkg/eval/errs"
 	"src.elv.sh/pkg/eval/vals"
@@ -105,13 +104,12 @@ func (e externalCmd) Call(fm *Frame, argVals []any, opts map[string]any) error {
 
 	args[0] = path
 
-	sys := makeSysProcAttr(fm.background)
-	proc, err := os.StartProcess(path, args, &os.ProcAttr{Files: files, Sys: sys})
+	proc, err := startProcess(fm, path, args, files)
 	if err != nil {
 		return err
 	}
 
-	state, err := proc.Wait()
+	ws, err := waitProcess(fm, proc)
 	if err != nil {
 		// This should be a can't happen situation. Nonetheless, treat it as a
 		// soft error rather than panicking since the Go documentation is not
@@ -119,12 +117,11 @@ func (e externalCmd) Call(fm *Frame, argVals []any, opts map[string]any) error {
 		// calling `Wait` twice on a particular process object.
 		return err
 	}
-	ws := state.Sys().(syscall.WaitStatus)
 	if ws.Signaled() && isSIGPIPE(ws.Signal()) {
 		readerGone := fm.ports[1].readerGone
 		if readerGone != nil && readerGone.Load() {
 			return errs.ReaderGone{}
 		}
 	}
-	return NewExternalCmdExit(e.Name, state.Sys().(syscall.WaitStatus), proc.Pid)
+	return NewExternalCmdExit(e.Name, ws, proc.Pid)
 }
@@ -41,6 +41,11 @@ type Frame struct {
 	traceback *StackTrace
 
 	background bool
+	// The job the frame is running, or nil if it is not running any job.
+	job *job
+	// Whether pipelines that are not part of a job are run as jobs under job
+	// control.
+	jobControl bool
 }
 
 // PrepareEval prepares a piece of code for evaluation in a copy of the current
@@ -66,8 +71,8 @@ func (fm *Frame) PrepareEval(src parse.Source, r diag.Ranger, ns *Ns) (*Ns, func
 		traceback = fm.addTraceback(r)
 	}
 	newFm := &Frame{
-		fm.Evaler, src, local, new(Ns), nil, fm.ctx, fm.ports, traceback, fm.background}
-	op, _, err := compile(fm.Evaler.Builtin().static(), local.static(), nil, tree, fm.ErrorFile())
+		fm.Evaler, src, local, new(Ns), nil, fm.ctx, fm.ports, traceback, fm.background, fm.job, fm.jobControl}
+	op, _, err := compile(fm.Evaler.Builtin().static(), local.static(), tree, compileOpts{warn: fm.ErrorFile()})
 	if err != nil {
 		return nil, nil, err
 	}
@@ -203,7 +208,7 @@ func (fm *Frame) Fork() *Frame {
 		fm.Evaler, fm.src,
 		fm.local, fm.up, fm.defers,
 		fm.ctx, newPorts,
-		fm.traceback, fm.background,
+		fm.traceback, fm.background, fm.job, fm.jobControl,
 	}
 }
 
@@ -0,0 +1,430 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"src.elv.sh/pkg/eval/errs"
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/parse"
+)
+
+// Job control.
+
+// ErrNoSuchJob is thrown when a job specifier or a process ID doesn't refer to
+// a job in the job table.
+var ErrNoSuchJob = errors.New("no such job")
+
+// A job is a pipeline evaluated in the background, or evaluated at the top
+// level in the foreground when job control is enabled, along with the external
+// processes it starts.
+type job struct {
+	source string
+	start  time.Time
+	// Whether the terminal is handed over to the job when it is in the
+	// foreground.
+	jobControl bool
+	// Context of the frames running the job. It is separate from the Context
+	// of the frame starting the job, so that interrupts only reach the job
+	// when it is in the foreground.
+	ctx    context.Context
+	cancel func()
+	// Closed when all the forms of the pipeline have finished.
+	done chan struct{}
+
+	mu sync.Mutex
+	// The ID in the job table, or 0 if the job is not in the table.
+	id    int
+	state jobState
+	// Closed when the job stops; replaced when the job is continued.
+	stopCh     chan struct{}
+	foreground bool
+	disowned   bool
+	// Process group of the job, or 0 if the job has no live external
+	// processes. Only used on Unix.
+	pgid int
+	// PIDs of the external processes of the job that haven't exited, in the
+	// order they were started. Exited processes are removed, so that the list
+	// doesn't grow in long-running jobs and PIDs that have been reused by
+	// other processes are never looked up.
+	pids []int
+	// Exception of the pipeline, only valid after done is closed.
+	err Exception
+
+	// Value outputs owned by the job; see ownOutputs.
+	outputsMu sync.Mutex
+	detached  bool
+	outputs   []jobOutput
+}
+
+type jobOutput struct {
+	ch   chan any
+	done chan struct{}
+}
+
+type jobState int
+
+const (
+	jobRunning jobState = iota
+	jobStopped
+	jobDone
+)
+
+func (s jobState) String() string {
+	switch s {
+	case jobRunning:
+		return "running"
+	case jobStopped:
+		return "stopped"
+	default:
+		return "done"
+	}
+}
+
+func newJob(source string, foreground, jobControl bool) *job {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &job{
+		source: source, start: time.Now(), jobControl: jobControl,
+		ctx: ctx, cancel: cancel, done: make(chan struct{}),
+		stopCh: make(chan struct{}), foreground: foreground,
+	}
+}
+
+// Marks the job as stopped or continued. Called when the state of one of its
+// processes changes, and when the job is continued with bg or fg.
+func (j *job) setStopped(stopped bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if stopped && j.state == jobRunning {
+		j.state = jobStopped
+		close(j.stopCh)
+	} else if !stopped && j.state == jobStopped {
+		j.state = jobRunning
+		j.stopCh = make(chan struct{})
+	}
+}
+
+// Removes the PID of an exited process. Must be called with j.mu held.
+func (j *job) removePid(pid int) {
+	for i, p := range j.pids {
+		if p == pid {
+			j.pids = append(j.pids[:i], j.pids[i+1:]...)
+			return
+		}
+	}
+}
+
+// Replaces the value channels of the output ports of fm with channels owned
+// by the job.
+//
+// The original channels may be closed by their owners once the pipeline that
+// started the job returns, which happens before the job finishes if the job is
+// in the background or gets stopped. Values written by the job are relayed to
+// the original channels until detachOutputs is called, and written to the
+// files of the ports afterwards, with the value prefix of the Evaler. A job in
+// the background is detached from the start.
+func (j *job) ownOutputs(fm *Frame) {
+	j.detached = fm.background
+	prefix := fm.Evaler.ValuePrefix()
+	for i, p := range fm.ports {
+		if i == 0 || p == nil || p.Chan == nil || p.Chan == ClosedChan || p.Chan == BlackholeChan {
+			continue
+		}
+		out := jobOutput{make(chan any, pipelineChanBufferSize), make(chan struct{})}
+		j.outputs = append(j.outputs, out)
+		go j.relayOutput(out, p, prefix)
+		fm.ports[i] = &Port{
+			File: p.File, Chan: out.ch,
+			sendStop: p.sendStop, sendError: p.sendError, readerGone: p.readerGone}
+	}
+}
+
+func (j *job) relayOutput(out jobOutput, dest *Port, prefix string) {
+	defer close(out.done)
+	for v := range out.ch {
+		j.outputsMu.Lock()
+		if !j.detached {
+			select {
+			case dest.Chan <- v:
+			case <-dest.sendStop:
+			}
+			j.outputsMu.Unlock()
+			continue
+		}
+		j.outputsMu.Unlock()
+		dest.File.WriteString(prefix + vals.ReprPlain(v) + "\n")
+	}
+}
+
+// Stops relaying values to the original channels, waiting for any value being
+// relayed. Must be called before the pipeline that started the job returns.
+func (j *job) detachOutputs() {
+	j.outputsMu.Lock()
+	defer j.outputsMu.Unlock()
+	j.detached = true
+}
+
+// Closes the channels owned by the job and waits for the values in them to be
+// relayed. Called when all the forms of the job have finished.
+func (j *job) closeOutputs() {
+	for _, out := range j.outputs {
+		close(out.ch)
+		<-out.done
+	}
+}
+
+func (j *job) describe() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return fmt.Sprintf("[%d] %s  %s", j.id, j.state, j.source)
+}
+
+// Returns a map describing the job, which is output by the jobs command and
+// passed to the hooks run when a background job finishes.
+func (j *job) toMap() vals.Map {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	pids := vals.EmptyList
+	for _, pid := range j.pids {
+		pids = pids.Conj(pid)
+	}
+	return vals.MakeMap(
+		"id", j.id, "pids", pids, "cmd", j.source, "state", j.state.String(),
+		"start", float64(j.start.UnixNano())/1e9, "exception", j.err)
+}
+
+// Waits for a job in the foreground to finish or stop, forwarding interrupts
+// from ctx to the job. If the job stops, it is moved to the background and
+// added to the job table, and the shell is put back in the foreground.
+func (ev *Evaler) waitForeground(ctx context.Context, j *job) (stopped bool) {
+	j.mu.Lock()
+	stopCh := j.stopCh
+	j.mu.Unlock()
+	for {
+		select {
+		case <-j.done:
+			return false
+		case <-stopCh:
+			j.mu.Lock()
+			j.foreground = false
+			j.mu.Unlock()
+			ev.jobs.add(j)
+			if j.jobControl {
+				putSelfInFg()
+			}
+			return true
+		case <-ctx.Done():
+			j.cancel()
+			// Keep waiting for the job to actually finish.
+			ctx = context.Background()
+		}
+	}
+}
+
+// Records the result of a job when all its forms have finished, and removes it
+// from the job table. If the job is in the background, the AfterBgJob hooks
+// are run and its result is notified before it is removed, so that waiting for
+// the job also waits for them.
+func (ev *Evaler) finishJob(j *job, err Exception) {
+	j.mu.Lock()
+	j.state, j.err = jobDone, err
+	notify := !j.foreground && !j.disowned
+	j.mu.Unlock()
+
+	if notify {
+		if len(ev.AfterBgJob) > 0 {
+			m := j.toMap()
+			for _, hook := range ev.AfterBgJob {
+				hook(m)
+			}
+		}
+		if ev.BgJobNotify != nil {
+			msg := "job " + j.source + " finished"
+			if err != nil {
+				msg += ", errors = " + err.Error()
+			}
+			if ev.getNotifyBgJobSuccess() || err != nil {
+				ev.BgJobNotify(msg)
+			}
+		}
+	}
+	ev.jobs.remove(j)
+	close(j.done)
+	j.cancel()
+}
+
+// The job table, which contains jobs in the background, including stopped
+// jobs.
+type jobTable struct {
+	mu sync.Mutex
+	// Sorted by ID.
+	jobs []*job
+}
+
+// Adds a job to the table if it's not already in it. A new job gets an ID
+// that is one more than the largest ID in the table.
+func (t *jobTable) add(j *job) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.id != 0 || j.state == jobDone {
+		return
+	}
+	j.id = 1
+	if len(t.jobs) > 0 {
+		last := t.jobs[len(t.jobs)-1]
+		last.mu.Lock()
+		j.id = last.id + 1
+		last.mu.Unlock()
+	}
+	t.jobs = append(t.jobs, j)
+}
+
+func (t *jobTable) remove(j *job) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, j2 := range t.jobs {
+		if j2 == j {
+			t.jobs = append(t.jobs[:i:i], t.jobs[i+1:]...)
+			return
+		}
+	}
+}
+
+func (t *jobTable) list() []*job {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*job(nil), t.jobs...)
+}
+
+// Finds the job specified by a job specifier or a PID. Job specifiers are
+// "%n" for the job with ID n, "%%" or "%+" for the current job, which is the
+// job with the largest ID, and "%-" for the previous job.
+func (t *jobTable) find(arg any) (*job, error) {
+	jobs := t.list()
+	if spec, ok := arg.(string); ok && strings.HasPrefix(spec, "%") {
+		switch spec {
+		case "%", "%%", "%+":
+			if len(jobs) > 0 {
+				return jobs[len(jobs)-1], nil
+			}
+		case "%-":
+			if len(jobs) > 1 {
+				return jobs[len(jobs)-2], nil
+			}
+		default:
+			id, err := strconv.Atoi(spec[1:])
+			if err != nil {
+				return nil, errs.BadValue{What: "job specifier",
+					Valid: "%n, %%, %+ or %-", Actual: parse.Quote(spec)}
+			}
+			for _, j := range jobs {
+				j.mu.Lock()
+				found := j.id == id
+				j.mu.Unlock()
+				if found {
+					return j, nil
+				}
+			}
+		}
+		return nil, ErrNoSuchJob
+	}
+	var pid int
+	if err := vals.ScanToGo(arg, &pid); err != nil {
+		return nil, errs.BadValue{What: "argument",
+			Valid: "job specifier or process ID", Actual: vals.ReprPlain(arg)}
+	}
+	for _, j := range jobs {
+		j.mu.Lock()
+		for _, p := range j.pids {
+			if p == pid {
+				j.mu.Unlock()
+				return j, nil
+			}
+		}
+		j.mu.Unlock()
+	}
+	return nil, ErrNoSuchJob
+}
+
+// Finds the jobs specified by args, defaulting to the current job.
+func (t *jobTable) findAll(args []any) ([]*job, error) {
+	if len(args) == 0 {
+		args = []any{"%%"}
+	}
+	jobs := make([]*job, len(args))
+	for i, arg := range args {
+		j, err := t.find(arg)
+		if err != nil {
+			return nil, err
+		}
+		jobs[i] = j
+	}
+	return jobs, nil
+}
+
+func jobsFn(fm *Frame) error {
+	out := fm.ValueOutput()
+	for _, j := range fm.Evaler.jobs.list() {
+		err := out.Put(j.toMap())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func wait(fm *Frame, args ...any) error {
+	var jobs []*job
+	if len(args) == 0 {
+		// Stopped jobs would never finish without being continued.
+		for _, j := range fm.Evaler.jobs.list() {
+			j.mu.Lock()
+			if j.state != jobStopped {
+				jobs = append(jobs, j)
+			}
+			j.mu.Unlock()
+		}
+	} else {
+		var err error
+		jobs, err = fm.Evaler.jobs.findAll(args)
+		if err != nil {
+			return err
+		}
+	}
+	for _, j := range jobs {
+		select {
+		case <-j.done:
+		case <-fm.Context().Done():
+			return ErrInterrupted
+		}
+	}
+	if len(args) > 0 {
+		for _, j := range jobs {
+			if j.err != nil {
+				return j.err
+			}
+		}
+	}
+	return nil
+}
+
+func disown(fm *Frame, args ...any) error {
+	jobs, err := fm.Evaler.jobs.findAll(args)
+	if err != nil {
+		return err
+	}
+	for _, j := range jobs {
+		j.mu.Lock()
+		j.disowned = true
+		j.mu.Unlock()
+		fm.Evaler.jobs.remove(j)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+//go:build unix
+
+package eval
+
+import (
+	"os"
+	"syscall"
+
+	"src.elv.sh/pkg/sys"
+	"src.elv.sh/pkg/sys/eunix"
+)
+
+// Starts an external process. If the frame is running a job, the process is
+// put in the process group of the job, and if the job is in the foreground
+// with job control, the process group is also put in the foreground.
+func startProcess(fm *Frame, path string, args []string, files []*os.File) (*os.Process, error) {
+	j := fm.job
+	if j == nil {
+		return os.StartProcess(path, args,
+			&os.ProcAttr{Files: files, Sys: makeSysProcAttr(fm.background)})
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	attr := &syscall.SysProcAttr{Setpgid: true, Pgid: j.pgid}
+	if j.foreground && j.jobControl && sys.IsATTY(os.Stdin.Fd()) {
+		attr.Foreground = true
+		attr.Ctty = int(os.Stdin.Fd())
+	}
+	proc, err := os.StartProcess(path, args, &os.ProcAttr{Files: files, Sys: attr})
+	if err != nil {
+		return nil, err
+	}
+	if j.pgid == 0 {
+		j.pgid = proc.Pid
+	}
+	j.pids = append(j.pids, proc.Pid)
+	return proc, nil
+}
+
+// Waits for an external process to exit. If the frame is running a job, the
+// job is also marked as stopped or continued when the process is.
+func waitProcess(fm *Frame, proc *os.Process) (syscall.WaitStatus, error) {
+	j := fm.job
+	if j == nil {
+		state, err := proc.Wait()
+		if err != nil {
+			return 0, err
+		}
+		return state.Sys().(syscall.WaitStatus), nil
+	}
+	defer proc.Release()
+	for {
+		var ws syscall.WaitStatus
+		_, err := syscall.Wait4(proc.Pid, &ws, syscall.WUNTRACED|syscall.WCONTINUED, nil)
+		switch {
+		case err == syscall.EINTR:
+		case err != nil:
+			j.processExited(proc.Pid)
+			return 0, err
+		case ws.Stopped():
+			j.setStopped(true)
+		case ws.Continued():
+			j.setStopped(false)
+		default:
+			j.processExited(proc.Pid)
+			return ws, nil
+		}
+	}
+}
+
+// Records that a process of the job has exited. When the last process has
+// exited, the process group no longer exists, so the shell takes back the
+// terminal until another process is started.
+func (j *job) processExited(pid int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.removePid(pid)
+	if len(j.pids) == 0 {
+		j.pgid = 0
+		if j.foreground && j.jobControl {
+			putSelfInFg()
+		}
+	}
+}
+
+// Continues a job in the foreground or background. When continuing in the
+// foreground with job control, the terminal is handed over to the job.
+func (j *job) cont(foreground bool) error {
+	j.setStopped(false)
+	j.mu.Lock()
+	j.foreground = foreground
+	pgid := j.pgid
+	j.mu.Unlock()
+	if pgid == 0 {
+		return nil
+	}
+	if foreground && j.jobControl && sys.IsATTY(os.Stdin.Fd()) {
+		err := eunix.Tcsetpgrp(0, pgid)
+		if err != nil {
+			return err
+		}
+	}
+	return syscall.Kill(-pgid, syscall.SIGCONT)
+}
@@ -0,0 +1,164 @@
+//go:build unix
+
+package eval_test
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"testing"
+
+	. "src.elv.sh/pkg/eval"
+
+	"src.elv.sh/pkg/must"
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/testutil"
+)
+
+// Output of the stopped command is redirected, since CapturePort waits for all
+// writers of the output to be closed.
+const stopOnce = "sh -c 'kill -STOP $$; exit 3' > /dev/null 2>&1"
+
+func TestJobControl_Fg(t *testing.T) {
+	ev := NewEvaler()
+
+	out, err := evalWithJobControl(ev, stopOnce)
+	if want := "[1] stopped  " + stopOnce + "\n"; out != want || err != nil {
+		t.Errorf("got (%q, %v), want (%q, nil)", out, err, want)
+	}
+	out, _ = evalWithJobControl(ev, "jobs | each {|j| echo $j[id] $j[state] }; put $num-bg-jobs")
+	if want := "1 stopped\n1\n"; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+
+	_, err = evalWithJobControl(ev, "fg %1")
+	if err == nil || err.Error() != "sh exited with 3" {
+		t.Errorf("got error %v, want sh exited with 3", err)
+	}
+	out, _ = evalWithJobControl(ev, "put $num-bg-jobs")
+	if want := "0\n"; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestJobControl_Bg(t *testing.T) {
+	ev := NewEvaler()
+	noteCh := make(chan string, 1)
+	ev.BgJobNotify = func(s string) { noteCh <- s }
+
+	evalWithJobControl(ev, stopOnce)
+	out, err := evalWithJobControl(ev, "bg %1; wait; jobs")
+	if out != "" || err != nil {
+		t.Errorf("got (%q, %v), want empty output and no error", out, err)
+	}
+	want := "job " + stopOnce + " finished, errors = sh exited with 3"
+	if note := <-noteCh; note != want {
+		t.Errorf("got notification %q, want %q", note, want)
+	}
+}
+
+func TestJobControl_Pids(t *testing.T) {
+	ev := NewEvaler()
+
+	evalWithJobControl(ev, stopOnce)
+	out, err := evalWithJobControl(ev, "var j = (jobs); echo (count $j[pids]); fg $j[pids][0]")
+	if out != "1\n" || err == nil || err.Error() != "sh exited with 3" {
+		t.Errorf("got (%q, %v), want (%q, sh exited with 3)", out, err, "1\n")
+	}
+}
+
+func TestJobControl_PidsOfExitedProcesses(t *testing.T) {
+	ev := NewEvaler()
+
+	evalWithJobControl(ev, "sh -c 'exit 0'; "+stopOnce)
+	out, err := evalWithJobControl(ev, "var j = (jobs); echo (count $j[pids])")
+	if out != "1\n" || err != nil {
+		t.Errorf("got (%q, %v), want (%q, nil)", out, err, "1\n")
+	}
+	evalWithJobControl(ev, "fg")
+}
+
+func TestFg_ProcessNotInJobTable(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "kill -STOP $$; exit 3")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	must.OK(cmd.Start())
+	pid := cmd.Process.Pid
+	var ws syscall.WaitStatus
+	must.OK1(syscall.Wait4(pid, &ws, syscall.WUNTRACED, nil))
+
+	_, err := evalWithJobControl(NewEvaler(), "fg "+strconv.Itoa(pid))
+	if want := parse.Quote("[pid "+strconv.Itoa(pid)+"]") + " exited with 3"; err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %v", err, want)
+	}
+}
+
+func TestJobControl_StoppingAgain(t *testing.T) {
+	ev := NewEvaler()
+	code := "sh -c 'kill -STOP $$; kill -STOP $$' > /dev/null 2>&1"
+
+	evalWithJobControl(ev, code)
+	out, err := evalWithJobControl(ev, "fg")
+	if want := "[1] stopped  " + code + "\n"; out != want || err != nil {
+		t.Errorf("got (%q, %v), want (%q, nil)", out, err, want)
+	}
+	out, err = evalWithJobControl(ev, "fg; jobs")
+	if out != "" || err != nil {
+		t.Errorf("got (%q, %v), want empty output and no error", out, err)
+	}
+}
+
+func TestJobControl_OutputAfterFg(t *testing.T) {
+	ev := NewEvaler()
+	f := must.OK1(os.CreateTemp(testutil.TempDir(t), "out"))
+	defer f.Close()
+	code := "{ sh -c 'kill -STOP $$' > /dev/null 2>&1; put done }"
+
+	evalWithFilePorts(ev, f, code)
+	if err := evalWithFilePorts(ev, f, "fg %1"); err != nil {
+		t.Errorf("got error %v", err)
+	}
+	want := "[1] stopped  " + code + "\n" + ev.ValuePrefix() + "done\n"
+	if out := must.ReadFileString(f.Name()); out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestJobControl_OutputOfBgJob(t *testing.T) {
+	ev := NewEvaler()
+	f := must.OK1(os.CreateTemp(testutil.TempDir(t), "out"))
+	defer f.Close()
+
+	evalWithFilePorts(ev, f, "{ sleep 0.05; put done } &")
+	if err := evalWithFilePorts(ev, f, "wait"); err != nil {
+		t.Errorf("got error %v", err)
+	}
+	if out, want := must.ReadFileString(f.Name()), ev.ValuePrefix()+"done\n"; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+// Evaluates code with job control and output to f, like in the REPL. The ports
+// are closed when Eval returns, even if the code leaves jobs behind.
+func evalWithFilePorts(ev *Evaler, f *os.File, code string) error {
+	ports, cleanup := PortsFromFiles([3]*os.File{DevNull, f, f}, ev.ValuePrefix())
+	defer cleanup()
+	return ev.Eval(parse.Source{Name: "[test]", Code: code},
+		EvalCfg{Ports: ports, JobControl: true})
+}
+
+// Evaluates code with job control, and returns the output, with values
+// written on separate lines after bytes, followed by stderr.
+func evalWithJobControl(ev *Evaler, code string) (string, error) {
+	port1, collect1 := must.OK2(CapturePort())
+	port2, collect2 := must.OK2(CapturePort())
+	err := ev.Eval(parse.Source{Name: "[test]", Code: code},
+		EvalCfg{Ports: []*Port{DummyInputPort, port1, port2}, JobControl: true})
+	values, stdout := collect1()
+	_, stderr := collect2()
+	out := string(stdout)
+	for _, v := range values {
+		out += v.(string) + "\n"
+	}
+	return out + string(stderr), err
+}
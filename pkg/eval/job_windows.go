@@ -0,0 +1,33 @@
+package eval
+
+import (
+	"os"
+	"syscall"
+)
+
+// Job control is not supported on Windows; jobs only track background
+// pipelines.
+
+func startProcess(fm *Frame, path string, args []string, files []*os.File) (*os.Process, error) {
+	proc, err := os.StartProcess(path, args,
+		&os.ProcAttr{Files: files, Sys: makeSysProcAttr(fm.background)})
+	if err == nil && fm.job != nil {
+		fm.job.mu.Lock()
+		fm.job.pids = append(fm.job.pids, proc.Pid)
+		fm.job.mu.Unlock()
+	}
+	return proc, err
+}
+
+func waitProcess(fm *Frame, proc *os.Process) (syscall.WaitStatus, error) {
+	state, err := proc.Wait()
+	if fm.job != nil {
+		fm.job.mu.Lock()
+		fm.job.removePid(proc.Pid)
+		fm.job.mu.Unlock()
+	}
+	if err != nil {
+		return syscall.WaitStatus{}, err
+	}
+	return state.Sys().(syscall.WaitStatus), nil
+}
@@ -0,0 +1,251 @@
+package eval
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+	"unsafe"
+
+	"src.elv.sh/pkg/eval/errs"
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/persistent/hash"
+)
+
+// A record type created by the record-type builtin. Calling it constructs a
+// record.
+type recordType struct {
+	ev   *Evaler
+	name string
+	// Field names, sorted.
+	keys     []string
+	defaults []any
+	// Predicates of the fields, nil for fields without one.
+	predicates []Callable
+}
+
+var (
+	_ Callable       = &recordType{}
+	_ vals.PseudoMap = &recordType{}
+)
+
+// Kind returns "fn".
+func (*recordType) Kind() string { return "fn" }
+
+// Equal compares by address.
+func (t *recordType) Equal(rhs any) bool { return t == rhs }
+
+// Hash returns the hash of the address.
+func (t *recordType) Hash() uint32 { return hash.Pointer(unsafe.Pointer(t)) }
+
+// Repr returns an opaque representation "<record-type name>".
+func (t *recordType) Repr(int) string { return "<record-type " + t.name + ">" }
+
+// Call constructs a record. The fields are initialized from the defaults, the
+// map argument if there is one, and the options, in that order.
+func (t *recordType) Call(fm *Frame, args []any, opts map[string]any) error {
+	if len(args) > 1 {
+		return errs.ArityMismatch{What: "arguments",
+			ValidLow: 0, ValidHigh: 1, Actual: len(args)}
+	}
+	values := make([]any, len(t.keys))
+	copy(values, t.defaults)
+	if len(args) == 1 {
+		var errField error
+		err := vals.IterateKeys(args[0], func(k any) bool {
+			i, err := t.fieldIndex(k)
+			if err != nil {
+				errField = err
+				return false
+			}
+			values[i], errField = vals.Index(args[0], k)
+			return errField == nil
+		})
+		if err != nil {
+			return err
+		}
+		if errField != nil {
+			return errField
+		}
+	}
+	for k, v := range opts {
+		i, err := t.fieldIndex(k)
+		if err != nil {
+			return err
+		}
+		values[i] = v
+	}
+	for i, v := range values {
+		if err := t.check(fm, i, v); err != nil {
+			return err
+		}
+	}
+	return fm.ValueOutput().Put(&record{t, values})
+}
+
+// Returns the index of the field with the given name.
+func (t *recordType) fieldIndex(k any) (int, error) {
+	if s, ok := k.(string); ok {
+		if i := sort.SearchStrings(t.keys, s); i < len(t.keys) && t.keys[i] == s {
+			return i, nil
+		}
+	}
+	return 0, errs.BadValue{What: "field of record type " + t.name,
+		Valid: "one of " + strings.Join(t.keys, ", "), Actual: vals.ReprPlain(k)}
+}
+
+// Checks a value of the i-th field against its predicate, which must output a
+// single boolean. The predicate is called in a fork of fm, or with the Evaler
+// if fm is nil, which is the case when a field is changed with Assoc outside
+// the assoc builtin, like with set. In the latter case, the predicate has no
+// input, and its byte output on stderr goes to the stderr of the process.
+func (t *recordType) check(fm *Frame, i int, v any) error {
+	pred := t.predicates[i]
+	if pred == nil {
+		return nil
+	}
+	var outputs []any
+	var err error
+	if fm != nil {
+		outputs, err = fm.Fork().CaptureOutput(func(fm *Frame) error {
+			return pred.Call(fm, []any{v}, NoOpts)
+		})
+	} else {
+		var port *Port
+		var collect func() []any
+		port, collect, err = ValueCapturePort()
+		if err != nil {
+			return err
+		}
+		err = t.ev.Call(pred,
+			CallCfg{Args: []any{v}, From: "[record-type predicate]"},
+			EvalCfg{Ports: []*Port{
+				DummyInputPort, port, {File: os.Stderr, Chan: BlackholeChan}}})
+		outputs = collect()
+	}
+	if err != nil {
+		return err
+	}
+	if len(outputs) != 1 {
+		return errs.ArityMismatch{What: "number of outputs of the predicate",
+			ValidLow: 1, ValidHigh: 1, Actual: len(outputs)}
+	}
+	ok, isBool := outputs[0].(bool)
+	if !isBool {
+		return errs.BadValue{What: "output of the predicate",
+			Valid: "boolean", Actual: vals.Kind(outputs[0])}
+	}
+	if !ok {
+		return errs.BadValue{
+			What:  "field " + t.keys[i] + " of record type " + t.name,
+			Valid: "accepted by its predicate", Actual: vals.ReprPlain(v)}
+	}
+	return nil
+}
+
+func (t *recordType) Fields() vals.MethodMap { return recordTypeFields{t} }
+
+type recordTypeFields struct{ t *recordType }
+
+func (f recordTypeFields) Name() string { return f.t.name }
+
+func (f recordTypeFields) Defaults() vals.Map {
+	m := vals.EmptyMap
+	for i, k := range f.t.keys {
+		m = m.Assoc(k, f.t.defaults[i])
+	}
+	return m
+}
+
+// A record, an instance of a record type. It behaves like an immutable map
+// with a fixed set of keys.
+type record struct {
+	t      *recordType
+	values []any
+}
+
+// Kind returns the name of the record type.
+func (r *record) Kind() string { return r.t.name }
+
+// Repr returns a representation like "[^name &field=value]".
+func (r *record) Repr(indent int) string {
+	return vals.ReprTaggedMap(r.t.name, r.t.keys, r.values, indent)
+}
+
+// Equal returns whether rhs is a record of the same type with equal fields.
+func (r *record) Equal(rhs any) bool {
+	r2, ok := rhs.(*record)
+	if !ok || r.t != r2.t {
+		return false
+	}
+	for i, v := range r.values {
+		if !vals.Equal(v, r2.values[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Hash returns a hash of the record type and the fields.
+func (r *record) Hash() uint32 {
+	h := r.t.Hash()
+	for _, v := range r.values {
+		h = hash.DJBCombine(h, vals.Hash(v))
+	}
+	return h
+}
+
+// Len returns the number of fields.
+func (r *record) Len() int { return len(r.values) }
+
+// Index returns the value of a field.
+func (r *record) Index(k any) (any, bool) {
+	i, err := r.t.fieldIndex(k)
+	if err != nil {
+		return nil, false
+	}
+	return r.values[i], true
+}
+
+// HasKey returns whether k is the name of a field.
+func (r *record) HasKey(k any) bool {
+	_, err := r.t.fieldIndex(k)
+	return err == nil
+}
+
+// IterateKeys calls f with the name of each field.
+func (r *record) IterateKeys(f func(any) bool) {
+	for _, k := range r.t.keys {
+		if !f(k) {
+			return
+		}
+	}
+}
+
+// Assoc returns a copy of the record with a field changed. It is an error if
+// k is not the name of a field, or v is not accepted by the field's predicate.
+func (r *record) Assoc(k, v any) (any, error) { return r.assoc(nil, k, v) }
+
+// Like Assoc, but calls the predicate in fm if it is not nil.
+func (r *record) assoc(fm *Frame, k, v any) (any, error) {
+	i, err := r.t.fieldIndex(k)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.t.check(fm, i, v); err != nil {
+		return nil, err
+	}
+	values := make([]any, len(r.values))
+	copy(values, r.values)
+	values[i] = v
+	return &record{r.t, values}, nil
+}
+
+// MarshalJSON encodes the record as a JSON object.
+func (r *record) MarshalJSON() ([]byte, error) {
+	m := make(map[string]any, len(r.values))
+	for i, k := range r.t.keys {
+		m[k] = r.values[i]
+	}
+	return json.Marshal(m)
+}
@@ -0,0 +1,132 @@
+package eval
+
+import (
+	"sort"
+	"strings"
+
+	"src.elv.sh/pkg/diag"
+	"src.elv.sh/pkg/parse"
+)
+
+// SymbolKind is the kind of a Symbol.
+type SymbolKind int
+
+// Possible values of SymbolKind.
+const (
+	VariableSymbol SymbolKind = iota
+	FunctionSymbol
+	NamespaceSymbol
+)
+
+// Symbol records a variable declared in a source file and all the places where
+// it is referenced, as determined by the static scope analysis done by the
+// compiler.
+type Symbol struct {
+	// Name of the variable, without the "~" or ":" suffix.
+	Name string
+	Kind SymbolKind
+	// Range of the name where the variable is declared.
+	Decl diag.Ranging
+	// Ranges of the name where the variable is referenced, in source order.
+	Refs []diag.Ranging
+	// Whether the name can't be located exactly in some of the ranges, which
+	// happens when it's written with escape sequences. Those ranges cover the
+	// whole word instead, including any quotes.
+	Inexact bool
+}
+
+// CheckSymbols compiles the given parsed source tree like CheckTree, and
+// returns all the variables declared in it, in order of declaration. Variables
+// declared outside the tree, like builtins, are not included.
+func (ev *Evaler) CheckSymbols(tree parse.Tree) []*Symbol {
+	return ev.CheckAll(tree).Symbols
+}
+
+type symbolRecorder struct {
+	symbols []*Symbol
+	byVar   map[symbolKey]*Symbol
+}
+
+// Returns the recorded symbols, with the references of each sorted.
+func (s *symbolRecorder) sorted() []*Symbol {
+	for _, sym := range s.symbols {
+		sort.Slice(sym.Refs, func(i, j int) bool {
+			return sym.Refs[i].From < sym.Refs[j].From
+		})
+	}
+	return s.symbols
+}
+
+// Identifies a variable slot in a static namespace. Since slots are never
+// reused, this also identifies a declaration.
+type symbolKey struct {
+	ns    *staticNs
+	index int
+}
+
+// Records the declaration of the variable at the given index of ns. Does
+// nothing unless the compiler is recording symbols.
+func (cp *compiler) declareSymbol(ns *staticNs, index int, name string, r diag.Ranger) {
+	if cp.symbols == nil {
+		return
+	}
+	kind := VariableSymbol
+	if strings.HasSuffix(name, FnSuffix) {
+		kind, name = FunctionSymbol, name[:len(name)-len(FnSuffix)]
+	} else if strings.HasSuffix(name, NsSuffix) {
+		kind, name = NamespaceSymbol, name[:len(name)-len(NsSuffix)]
+	}
+	// The name of an imported module comes last in a module spec.
+	decl, exact := cp.nameRange(r, name, kind == NamespaceSymbol)
+	sym := &Symbol{Name: name, Kind: kind, Decl: decl, Inexact: !exact}
+	cp.symbols.symbols = append(cp.symbols.symbols, sym)
+	cp.symbols.byVar[symbolKey{ns, index}] = sym
+}
+
+// Records a reference to the variable qname, which has already been resolved
+// by the caller. Only the first segment of qname is considered, since that is
+// the part resolved statically. Does nothing
+// unless the compiler is recording symbols.
+func (cp *compiler) referenceSymbol(qname string, r diag.Ranger) {
+	if cp.symbols == nil {
+		return
+	}
+	first, _ := SplitQName(qname)
+	for i := len(cp.scopes) - 1; i >= 0; i-- {
+		if _, index := cp.scopes[i].lookup(first); index != -1 {
+			if sym := cp.symbols.byVar[symbolKey{cp.scopes[i], index}]; sym != nil {
+				ref, exact := cp.nameRange(r, sym.Name, false)
+				sym.Refs = append(sym.Refs, ref)
+				sym.Inexact = sym.Inexact || !exact
+			}
+			return
+		}
+	}
+}
+
+// Finds the range of name within r, searching from the end if last is true.
+// If the name can't be located exactly, returns the range of r and false. This
+// happens when the name is written with escape sequences, in which case it
+// may not appear in the source as is, or appear as part of an escape sequence.
+func (cp *compiler) nameRange(r diag.Ranger, name string, last bool) (diag.Ranging, bool) {
+	rg := r.Range()
+	text := cp.src.Code[rg.From:rg.To]
+	if name == "" || strings.Contains(text, `\`) || strings.Contains(text, "''") {
+		return rg, false
+	}
+	i := strings.Index(text, name)
+	if last {
+		i = strings.LastIndex(text, name)
+	}
+	if i == -1 || !isNameBoundary(text, i-1) || !isNameBoundary(text, i+len(name)) {
+		return rg, false
+	}
+	return diag.Ranging{From: rg.From + i, To: rg.From + i + len(name)}, true
+}
+
+// Reports whether text[i] can be next to a name, so that the name is a whole
+// segment of a qualified name and not part of a longer name. The sigil, the
+// quotes and the separators of qualified names and module specs qualify.
+func isNameBoundary(text string, i int) bool {
+	return i < 0 || i >= len(text) || strings.IndexByte("$@'\":/", text[i]) != -1
+}
@@ -0,0 +1,131 @@
+package eval_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"src.elv.sh/pkg/diag"
+	. "src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/parse"
+)
+
+func rg(from, to int) diag.Ranging { return diag.Ranging{From: from, To: to} }
+
+var symbolsTests = []struct {
+	Name string
+	Code string
+
+	WantSymbols []*Symbol
+}{
+	{
+		Name: "variable",
+		//     0123456789012345678
+		Code: "var x = 1; echo $x",
+
+		WantSymbols: []*Symbol{
+			{Name: "x", Kind: VariableSymbol, Decl: rg(4, 5), Refs: []diag.Ranging{rg(17, 18)}},
+		},
+	},
+	{
+		Name: "set is a reference",
+		//     012345678901234
+		Code: "var x; set x = 2",
+
+		WantSymbols: []*Symbol{
+			{Name: "x", Kind: VariableSymbol, Decl: rg(4, 5), Refs: []diag.Ranging{rg(11, 12)}},
+		},
+	},
+	{
+		Name: "function, with recursive reference",
+		//     0123456789012345678
+		Code: "fn f { f }; f",
+
+		WantSymbols: []*Symbol{
+			{Name: "f", Kind: FunctionSymbol, Decl: rg(3, 4), Refs: []diag.Ranging{rg(7, 8), rg(12, 13)}},
+		},
+	},
+	{
+		Name: "shadowing",
+		//     0123456789012345678901234
+		Code: "var x; var x; put $x",
+
+		WantSymbols: []*Symbol{
+			{Name: "x", Kind: VariableSymbol, Decl: rg(4, 5)},
+			{Name: "x", Kind: VariableSymbol, Decl: rg(11, 12), Refs: []diag.Ranging{rg(19, 20)}},
+		},
+	},
+	{
+		Name: "lambda argument and option, with capture",
+		//     012345678901234567890123456789
+		Code: "var y; { |a &o=x| put $a $o $y }",
+
+		WantSymbols: []*Symbol{
+			{Name: "y", Kind: VariableSymbol, Decl: rg(4, 5), Refs: []diag.Ranging{rg(29, 30)}},
+			{Name: "a", Kind: VariableSymbol, Decl: rg(10, 11), Refs: []diag.Ranging{rg(23, 24)}},
+			{Name: "o", Kind: VariableSymbol, Decl: rg(13, 14), Refs: []diag.Ranging{rg(26, 27)}},
+		},
+	},
+	{
+		Name: "module",
+		//     0123456789012345678901
+		Code: "use a/str; str:join",
+
+		WantSymbols: []*Symbol{
+			{Name: "str", Kind: NamespaceSymbol, Decl: rg(6, 9), Refs: []diag.Ranging{rg(11, 14)}},
+		},
+	},
+	{
+		Name: "qualified references to namespace variable",
+		//     0123456789012345678901234567890123456789
+		Code: "var ns: = (ns [&]); put $ns:x; set ns:x = 2",
+
+		WantSymbols: []*Symbol{
+			{Name: "ns", Kind: NamespaceSymbol, Decl: rg(4, 6), Refs: []diag.Ranging{rg(25, 27), rg(35, 37)}},
+		},
+	},
+	{
+		Name: "local: is not a special namespace",
+		//     0123456789012345678
+		Code: "var x; put $local:x",
+
+		WantSymbols: []*Symbol{
+			{Name: "x", Kind: VariableSymbol, Decl: rg(4, 5)},
+		},
+	},
+	{
+		Name: "quoted name",
+		//     0123456789012345678
+		Code: "var 'x' = 1; put $x",
+
+		WantSymbols: []*Symbol{
+			{Name: "x", Kind: VariableSymbol, Decl: rg(5, 6), Refs: []diag.Ranging{rg(18, 19)}},
+		},
+	},
+	{
+		Name: "name with escape sequences",
+		//     0123456789012345678901
+		Code: `var "\x78" = 1; put $x`,
+
+		WantSymbols: []*Symbol{
+			{Name: "x", Kind: VariableSymbol, Decl: rg(4, 10), Refs: []diag.Ranging{rg(21, 22)}, Inexact: true},
+		},
+	},
+	{
+		Name: "builtins are not symbols",
+		Code: "echo $pid",
+
+		WantSymbols: nil,
+	},
+}
+
+func TestCheckSymbols(t *testing.T) {
+	for _, tc := range symbolsTests {
+		t.Run(tc.Name, func(t *testing.T) {
+			tree, _ := parse.Parse(parse.Source{Name: "[test]", Code: tc.Code}, parse.Config{})
+			symbols := NewEvaler().CheckSymbols(tree)
+			if diff := cmp.Diff(tc.WantSymbols, symbols); diff != "" {
+				t.Errorf("symbols (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -17,9 +17,18 @@ const (
 	CmpUncomparable
 )
 
+// Comparer wraps the Compare method.
+type Comparer interface {
+	// Compare compares the receiver to another value. It should return
+	// CmpEqual iff Equal(other) is true, and CmpUncomparable if other has a
+	// different type.
+	Compare(other any) Ordering
+}
+
 // Cmp compares two Elvish values and returns the ordering relationship between
 // them. Cmp(a, b) returns CmpEqual iff Equal(a, b) is true or both a and b are
-// NaNs.
+// NaNs. It is implemented for the builtin types nil, bool, string and numbers,
+// the List type, and types satisfying the Comparer interface.
 func Cmp(a, b any) Ordering {
 	return cmpInner(a, b, Cmp)
 }
@@ -85,6 +94,8 @@ func cmpInner(a, b any, recurse func(a, b any) Ordering) Ordering {
 				return CmpMore
 			}
 		}
+	case Comparer:
+		return a.Compare(b)
 	default:
 		if Equal(a, b) {
 			return CmpEqual
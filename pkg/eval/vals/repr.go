@@ -72,15 +72,15 @@ func Repr(v any, indent int) string {
 	case PseudoMap:
 		f := v.Fields()
 		fValue := reflect.ValueOf(v.Fields())
-		s := reprFieldOrMethodMap(FieldMapKeys(getMethodMapKeys(f)),
-			func(i int) any { return fValue.Method(i).Call(nil)[0].Interface() }, indent)
-		// Add a tag immediately after [.
-		return "[^" + Kind(v) + " " + s[1:]
+		keys, values := sortedFieldOrMethodMapPairs(FieldMapKeys(getMethodMapKeys(f)),
+			func(i int) any { return fValue.Method(i).Call(nil)[0].Interface() })
+		return ReprTaggedMap(Kind(v), keys, values, indent)
 	default:
 		if keys := GetFieldMapKeys(v); keys != nil {
 			value := reflect.ValueOf(v)
-			return reprFieldOrMethodMap(keys,
-				func(i int) any { return value.Field(i).Interface() }, indent)
+			keys, values := sortedFieldOrMethodMapPairs(keys,
+				func(i int) any { return value.Field(i).Interface() })
+			return reprStringKeyMap(keys, values, indent)
 		}
 		return fmt.Sprintf("<unknown %v>", v)
 	}
@@ -111,8 +111,8 @@ type fieldMapPair struct {
 	value any
 }
 
-func reprFieldOrMethodMap(keys FieldMapKeys, value func(i int) any, indent int) string {
-	builder := NewMapReprBuilder(indent)
+// Returns the keys and values of a field map or method map, sorted by keys.
+func sortedFieldOrMethodMapPairs(keys FieldMapKeys, value func(i int) any) ([]string, []any) {
 	// Collect all the key-value pairs.
 	pairs := make([]fieldMapPair, len(keys))
 	for i, key := range keys {
@@ -122,9 +122,18 @@ func reprFieldOrMethodMap(keys FieldMapKeys, value func(i int) any, indent int)
 	sort.Slice(pairs, func(i, j int) bool {
 		return pairs[i].key < pairs[j].key
 	})
-	// Print the pairs.
-	for _, pair := range pairs {
-		builder.WritePair(Repr(pair.key, indent+1), indent+2, Repr(pair.value, indent+2))
+	sortedKeys := make([]string, len(pairs))
+	values := make([]any, len(pairs))
+	for i, pair := range pairs {
+		sortedKeys[i], values[i] = pair.key, pair.value
+	}
+	return sortedKeys, values
+}
+
+func reprStringKeyMap(keys []string, values []any, indent int) string {
+	builder := NewMapReprBuilder(indent)
+	for i, key := range keys {
+		builder.WritePair(Repr(key, indent+1), indent+2, Repr(values[i], indent+2))
 	}
 	return builder.String()
 }
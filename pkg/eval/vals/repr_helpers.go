@@ -73,3 +73,11 @@ func (b *MapReprBuilder) String() string {
 	}
 	return s
 }
+
+// ReprTaggedMap returns the Repr of a map-like value with a tag, like
+// "[^tag &k=v]", writing the pairs in the given order. This is how pseudo maps
+// are shown, with their kinds as the tags.
+func ReprTaggedMap(tag string, keys []string, values []any, indent int) string {
+	// Add the tag immediately after [.
+	return "[^" + tag + " " + reprStringKeyMap(keys, values, indent)[1:]
+}
@@ -2,6 +2,7 @@ package vals
 
 import (
 	"fmt"
+	"math"
 	"math/big"
 	"os"
 	"testing"
@@ -63,3 +64,11 @@ func TestReprPlain_MapWithKeysOfMixedTypes(t *testing.T) {
 		t.Errorf("got %q, want %q or %q", got, want1, want2)
 	}
 }
+
+func TestReprTaggedMap(t *testing.T) {
+	tt.Test(t, ReprTaggedMap,
+		Args("point", []string{"x", "y"}, []any{1, "foo"}, math.MinInt).
+			Rets("[^point &x=(num 1) &y=foo]"),
+		Args("empty", []string(nil), []any(nil), math.MinInt).Rets("[^empty &]"),
+	)
+}
@@ -3,7 +3,7 @@ package filter_test
 import (
 	"testing"
 
-	"src.elv.sh/pkg/edit/filter"
+	"src.elv.sh/pkg/filter"
 	"src.elv.sh/pkg/parse"
 )
 
@@ -4,7 +4,7 @@ import (
 	"reflect"
 	"testing"
 
-	"src.elv.sh/pkg/edit/filter"
+	"src.elv.sh/pkg/filter"
 	"src.elv.sh/pkg/ui"
 )
 
@@ -0,0 +1,344 @@
+package lsp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	lsp "pkg.nimblebun.works/go-lsp"
+	"src.elv.sh/pkg/diag"
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/parse/cmpd"
+)
+
+// A problem found in a document, published as a diagnostic, along with the
+// quick fixes offered for it.
+type problem struct {
+	rg    diag.Ranging
+	diag  lsp.Diagnostic
+	fixes []fix
+}
+
+type fix struct {
+	title string
+	edits []lsp.TextEdit
+}
+
+// Modules that are only available in the interactive shell, and can't be
+// found by the language server.
+var shellModules = []string{"daemon", "store"}
+
+// Namespaces that are only available in the interactive shell. Variables in
+// them can't be resolved by the language server, so compilation errors about
+// them are not reported.
+var shellNamespaces = []string{"edit:"}
+
+// Returns the problems found in a document: parse errors, and if there are
+// none, compilation errors, uses of deprecated features, unknown modules and
+// qualified names that need a use form. The analysis is the result of
+// compiling the document.
+func (s *server) findProblems(uri lsp.DocumentURI, document document, analysis eval.Analysis) []problem {
+	code := document.code
+	newProblem := func(r diag.Ranger, severity lsp.DiagnosticSeverity, source, msg string) problem {
+		return problem{rg: r.Range(), diag: lsp.Diagnostic{
+			Range: lspRangeFromRange(code, r), Severity: severity,
+			Source: source, Message: msg,
+		}}
+	}
+
+	if document.parseErr != nil {
+		var problems []problem
+		for _, err := range parse.UnpackErrors(document.parseErr) {
+			problems = append(problems, newProblem(err, lsp.DSError, "parse", err.Message))
+		}
+		return problems
+	}
+
+	root := document.parseTree.Root
+	var problems []problem
+	for _, err := range eval.UnpackCompilationErrors(analysis.CompileErr) {
+		if name, ok := variableNotFound(err.Message); ok && inShellNamespace(name) {
+			continue
+		}
+		p := newProblem(err, lsp.DSError, "compile", err.Message)
+		if name, ok := undeclaredVariable(err.Message); ok {
+			p.fixes = append(p.fixes, declareVariableFix(code, root, err.Range(), name))
+		}
+		problems = append(problems, p)
+	}
+
+	for _, dep := range analysis.Deprecations {
+		p := newProblem(dep.Range, lsp.DSWarning, "deprecation", dep.Message)
+		if dep.Replacement != "" {
+			p.fixes = append(p.fixes, fix{
+				fmt.Sprintf("Replace with %s", dep.Replacement),
+				[]lsp.TextEdit{{Range: p.diag.Range, NewText: dep.Replacement}}})
+		}
+		problems = append(problems, p)
+	}
+
+	predefined := append(s.evaler.PredefinedModules(), shellModules...)
+	for _, spec := range useSpecs(root) {
+		name, _ := cmpd.StringLiteral(spec)
+		if s.moduleExists(uri, predefined, name) {
+			continue
+		}
+		p := newProblem(spec, lsp.DSWarning, "compile",
+			fmt.Sprintf("module %s not found", parse.Quote(name)))
+		// Offer the closest predefined modules, if they are close enough.
+		best := 3
+		for _, candidate := range predefined {
+			d := editDistance(name, candidate)
+			if d < best {
+				best, p.fixes = d, nil
+			}
+			if d == best {
+				p.fixes = append(p.fixes, fix{
+					fmt.Sprintf("Change to %s", parse.Quote(candidate)),
+					[]lsp.TextEdit{{Range: p.diag.Range, NewText: parse.Quote(candidate)}}})
+			}
+		}
+		problems = append(problems, p)
+	}
+
+	// Autofixes are "use mod" forms for qualified names like mod:foo where
+	// mod is a predefined module. They are offered on all such names, which
+	// don't cause compilation errors when used as commands.
+	for _, autofix := range analysis.Autofixes {
+		mod := strings.TrimPrefix(autofix, "use ")
+		useFix := fix{"Add " + autofix, []lsp.TextEdit{insertUse(code, root, autofix)}}
+		walkPrimaries(root, func(n *parse.Primary) {
+			if (n.Type != parse.Bareword && n.Type != parse.Variable) ||
+				!strings.HasPrefix(strings.TrimPrefix(n.Value, "@"), mod+":") {
+				return
+			}
+			for i := range problems {
+				if problems[i].rg == n.Range() {
+					problems[i].fixes = append(problems[i].fixes, useFix)
+					return
+				}
+			}
+			p := newProblem(n, lsp.DSWarning, "compile",
+				fmt.Sprintf("module %s is not imported", parse.Quote(mod)))
+			p.fixes = []fix{useFix}
+			problems = append(problems, p)
+		})
+	}
+	return problems
+}
+
+// Returns the name of the variable in the compilation error message if it is
+// about an unqualified variable that is not declared.
+func undeclaredVariable(msg string) (string, bool) {
+	name, ok := variableNotFound(msg)
+	if !ok || parse.Quote(name) != name || strings.Contains(name, ":") {
+		return "", false
+	}
+	return name, true
+}
+
+// Returns the name of the variable in a compilation error message about a
+// variable that can't be found.
+func variableNotFound(msg string) (string, bool) {
+	for _, prefix := range []string{"variable $", "cannot find variable $"} {
+		if !strings.HasPrefix(msg, prefix) {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(msg, prefix), " not found")
+		return name, name != ""
+	}
+	return "", false
+}
+
+func inShellNamespace(name string) bool {
+	for _, ns := range shellNamespaces {
+		if strings.HasPrefix(name, ns) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns a fix that declares an undeclared variable referenced in rg. If the
+// reference is the target of a set form, the set form is turned into a var
+// form; otherwise a var form is inserted before the enclosing pipeline.
+func declareVariableFix(code string, root *parse.Chunk, rg diag.Ranging, name string) fix {
+	title := "Declare $" + name
+	var n parse.Node = findNode(root, rg)
+	for ; n != nil; n = parse.Parent(n) {
+		if form, ok := n.(*parse.Form); ok && form.Head != nil {
+			if head, _ := cmpd.StringLiteral(form.Head); head == "set" {
+				return fix{title, []lsp.TextEdit{
+					{Range: lspRangeFromRange(code, form.Head), NewText: "var"}}}
+			}
+			break
+		}
+	}
+	// Find the enclosing pipeline that is not in an output capture, so that
+	// the variable is declared in the scope of the reference.
+	var pipeline *parse.Pipeline
+	for n = findNode(root, rg); n != nil; n = parse.Parent(n) {
+		if p, ok := n.(*parse.Pipeline); ok {
+			pipeline = p
+			if chunkScoped(parse.Parent(p)) {
+				break
+			}
+		}
+	}
+	if pipeline == nil {
+		return fix{title, nil}
+	}
+	from := pipeline.Range().From
+	lineStart := strings.LastIndexByte(code[:from], '\n') + 1
+	indent := code[lineStart:from]
+	newText := "var " + name + "; "
+	if strings.TrimLeft(indent, " \t") == "" {
+		newText = "var " + name + "\n" + indent
+	}
+	return fix{title, []lsp.TextEdit{{
+		Range: lspRangeFromRange(code, diag.PointRanging(from)), NewText: newText}}}
+}
+
+// Returns whether n is a chunk that is the top level of the document or the
+// body of a lambda.
+func chunkScoped(n parse.Node) bool {
+	if _, ok := n.(*parse.Chunk); !ok {
+		return false
+	}
+	p, ok := parse.Parent(n).(*parse.Primary)
+	return parse.Parent(n) == nil || (ok && p.Type == parse.Lambda)
+}
+
+// Returns the innermost node that contains rg.
+func findNode(root parse.Node, rg diag.Ranging) parse.Node {
+	for _, ch := range parse.Children(root) {
+		if r := ch.Range(); r.From <= rg.From && rg.To <= r.To {
+			return findNode(ch, rg)
+		}
+	}
+	return root
+}
+
+// Returns an edit that adds a use form after the last top-level use form, or
+// before the first top-level pipeline if there is none.
+func insertUse(code string, root *parse.Chunk, use string) lsp.TextEdit {
+	at, newText := 0, use+"\n"
+	if len(root.Pipelines) > 0 {
+		at = root.Pipelines[0].Range().From
+	}
+	for _, pipeline := range root.Pipelines {
+		if len(pipeline.Forms) == 0 {
+			continue
+		}
+		if head, _ := cmpd.StringLiteral(pipeline.Forms[0].Head); head == "use" {
+			end := pipeline.Range().To
+			if i := strings.IndexByte(code[end:], '\n'); i != -1 {
+				at = end + i + 1
+			} else {
+				at, newText = len(code), "\n"+use
+			}
+		}
+	}
+	return lsp.TextEdit{Range: lspRangeFromRange(code, diag.PointRanging(at)), NewText: newText}
+}
+
+// Returns the module specs of all use forms with a literal spec.
+func useSpecs(n parse.Node) []*parse.Compound {
+	var specs []*parse.Compound
+	if form, ok := n.(*parse.Form); ok && len(form.Args) > 0 {
+		if head, _ := cmpd.StringLiteral(form.Head); head == "use" {
+			if _, ok := cmpd.StringLiteral(form.Args[0]); ok {
+				specs = append(specs, form.Args[0])
+			}
+		}
+	}
+	for _, ch := range parse.Children(n) {
+		specs = append(specs, useSpecs(ch)...)
+	}
+	return specs
+}
+
+func walkPrimaries(n parse.Node, f func(*parse.Primary)) {
+	if p, ok := n.(*parse.Primary); ok {
+		f(p)
+	}
+	for _, ch := range parse.Children(n) {
+		walkPrimaries(ch, f)
+	}
+}
+
+// Returns whether the module with the given spec can be found. Relative specs
+// are resolved against the directory of the document, and other specs are
+// looked up in the predefined modules and the library directories.
+func (s *server) moduleExists(uri lsp.DocumentURI, predefined []string, spec string) bool {
+	if strings.HasPrefix(spec, "./") || strings.HasPrefix(spec, "../") {
+		path, ok := pathFromURI(uri)
+		if !ok {
+			// Can't resolve without a path; assume the module exists.
+			return true
+		}
+		return fileExists(filepath.Join(filepath.Dir(path), spec+".elv"))
+	}
+	for _, name := range predefined {
+		if name == spec {
+			return true
+		}
+	}
+	for _, dir := range s.libDirs {
+		if fileExists(filepath.Join(dir, spec+".elv")) || fileExists(filepath.Join(dir, spec+".so")) {
+			return true
+		}
+	}
+	return false
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// Returns the Levenshtein distance between two strings, counted in bytes.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// Returns the code actions that fix the problems overlapping with rg.
+func codeActions(uri lsp.DocumentURI, problems []problem, rg diag.Ranging) []codeAction {
+	actions := []codeAction{}
+	for _, p := range problems {
+		if p.rg.To < rg.From || rg.To < p.rg.From {
+			continue
+		}
+		for _, fix := range p.fixes {
+			if fix.edits == nil {
+				continue
+			}
+			actions = append(actions, codeAction{
+				Title:       fix.title,
+				Kind:        "quickfix",
+				Diagnostics: []lsp.Diagnostic{p.diag},
+				Edit:        &workspaceEdit{map[lsp.DocumentURI][]lsp.TextEdit{uri: fix.edits}},
+				IsPreferred: len(p.fixes) == 1,
+			})
+		}
+	}
+	return actions
+}
@@ -0,0 +1,213 @@
+package lsp
+
+import (
+	"context"
+	"strings"
+	"unicode/utf16"
+
+	lsp "pkg.nimblebun.works/go-lsp"
+	"src.elv.sh/pkg/elvdoc"
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/mods/doc"
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/parse/cmpd"
+	"src.elv.sh/pkg/parse/np"
+)
+
+// Rewrites the first segment of qname if it is the alias of a module imported
+// in the document, like "s:join" for "use str s", so that it can be used to
+// look up docs of builtin modules.
+func resolveModuleAlias(document document, qname string) string {
+	sigil := ""
+	if strings.HasPrefix(qname, "$") {
+		sigil, qname = "$", qname[1:]
+	}
+	first, rest := eval.SplitQName(qname)
+	if rest == "" {
+		return sigil + qname
+	}
+	alias := strings.TrimSuffix(first, ":")
+	for _, sym := range documentSymbols(document.code, document.parseTree.Root, true) {
+		if sym.Kind == skModule && sym.Name == alias {
+			return sigil + sym.Detail + ":" + rest
+		}
+	}
+	return sigil + qname
+}
+
+// Returns the Markdown doc of a function or variable declared in the document,
+// which is its elvdoc if there is one. For functions without an elvdoc, the
+// doc is just the usage.
+func userDoc(document document, sym *eval.Symbol) (string, bool) {
+	if entry, ok := userElvdoc(document, sym); ok {
+		return entry.FullContent(), true
+	}
+	if sym.Kind == eval.FunctionSymbol {
+		if lambda := fnLambda(document, sym); lambda != nil {
+			sig := fnSignature(sym.Name, lambda)
+			return "```elvish\n" + sig.Label + "\n```", true
+		}
+	}
+	return "", false
+}
+
+// Finds the elvdoc of a symbol declared in the document.
+func userElvdoc(document document, sym *eval.Symbol) (elvdoc.Entry, bool) {
+	docs, err := elvdoc.Extract(strings.NewReader(document.code), "")
+	if err != nil {
+		return elvdoc.Entry{}, false
+	}
+	name, entries := sym.Name, docs.Vars
+	switch sym.Kind {
+	case eval.VariableSymbol:
+		name = "$" + name
+	case eval.FunctionSymbol:
+		entries = docs.Fns
+	default:
+		return elvdoc.Entry{}, false
+	}
+	// Entries are identified by the line of the declaration, which is the line
+	// after the doc comment.
+	declLine := strings.Count(document.code[:sym.Decl.From], "\n") + 1
+	for _, entry := range entries {
+		if entry.Name == name && entry.Content != "" &&
+			entry.LineNo+strings.Count(entry.Content, "\n") == declLine {
+			return entry, true
+		}
+	}
+	return elvdoc.Entry{}, false
+}
+
+// Finds the lambda in the fn form that declares sym.
+func fnLambda(document document, sym *eval.Symbol) *parse.Primary {
+	var form *parse.Form
+	if !np.Find(document.parseTree.Root, sym.Decl.From).Match(
+		np.SimpleExpr(&np.SimpleExprData{}, nil), np.Store(&form)) {
+		return nil
+	}
+	if head, _ := cmpd.StringLiteral(form.Head); head != "fn" || len(form.Args) < 2 {
+		return nil
+	}
+	lambda, _ := cmpd.Lambda(form.Args[1])
+	return lambda
+}
+
+func (s *server) signatureHelp(_ context.Context, params lsp.TextDocumentPositionParams) (any, error) {
+	document, ok := s.documents[params.TextDocument.URI]
+	if !ok {
+		return nil, unknownDocument(params.TextDocument.URI)
+	}
+	pos := lspPositionToIdx(document.code, params.Position)
+
+	// Find the innermost form whose arguments the cursor is in.
+	var form *parse.Form
+	for _, n := range np.FindLeft(document.parseTree.Root, pos) {
+		if f, ok := n.(*parse.Form); ok && f.Head != nil && f.Head.To < pos {
+			form = f
+			break
+		}
+	}
+	if form == nil {
+		return nil, nil
+	}
+
+	var sig signatureInformation
+	var lambda *parse.Primary
+	if sym := symbolAt(document, form.Head.From); sym != nil && sym.Kind != eval.NamespaceSymbol {
+		// A function declared in the document.
+		if sym.Kind != eval.FunctionSymbol {
+			return nil, nil
+		}
+		if lambda = fnLambda(document, sym); lambda == nil {
+			return nil, nil
+		}
+		sig = fnSignature(sym.Name, lambda)
+		if entry, ok := userElvdoc(document, sym); ok {
+			sig.Documentation = &lsp.MarkupContent{Kind: lsp.MKMarkdown, Value: entry.Content}
+		}
+	} else {
+		// A builtin function.
+		name, ok := cmpd.StringLiteral(form.Head)
+		if !ok {
+			return nil, nil
+		}
+		entry, err := doc.Lookup(resolveModuleAlias(document, name))
+		if err != nil || entry.Fn == nil {
+			return nil, nil
+		}
+		lambda = &parse.Primary{}
+		err = parse.ParseAs(parse.Source{Code: "{|" + entry.Fn.Signature + "|}"}, lambda, parse.Config{})
+		if err != nil {
+			return nil, nil
+		}
+		sig = fnSignature(name, lambda)
+		sig.Documentation = &lsp.MarkupContent{Kind: lsp.MKMarkdown, Value: entry.Content}
+	}
+
+	return signatureHelp{
+		Signatures:      []signatureInformation{sig},
+		ActiveParameter: activeParameter(form, pos, lambda),
+	}, nil
+}
+
+// Builds the signature of a function from its lambda. The label is in the
+// same format as the usage in elvdocs, like "f $a $b... &k=v".
+func fnSignature(name string, lambda *parse.Primary) signatureInformation {
+	var sb strings.Builder
+	sb.WriteString(parse.QuoteCommandName(name))
+	var params []parameterInformation
+	add := func(s string) {
+		sb.WriteByte(' ')
+		start := utf16Len(sb.String())
+		sb.WriteString(s)
+		params = append(params, parameterInformation{[2]int{start, utf16Len(sb.String())}})
+	}
+	for _, arg := range lambda.Elements {
+		text := parse.SourceText(arg)
+		if rest, ok := strings.CutPrefix(text, "@"); ok {
+			add("$" + rest + "...")
+		} else {
+			add("$" + text)
+		}
+	}
+	for _, opt := range lambda.MapPairs {
+		add(strings.TrimSpace(parse.SourceText(opt)))
+	}
+	return signatureInformation{Label: sb.String(), Parameters: params}
+}
+
+// Returns the index of the parameter in the signature built by fnSignature
+// that corresponds to the argument or option at pos. Returns the number of
+// parameters if there is no such parameter.
+func activeParameter(form *parse.Form, pos int, lambda *parse.Primary) int {
+	nParams := len(lambda.Elements) + len(lambda.MapPairs)
+	for _, opt := range form.Opts {
+		if opt.From <= pos && pos <= opt.To {
+			key := parse.SourceText(opt.Key)
+			for i, p := range lambda.MapPairs {
+				if parse.SourceText(p.Key) == key {
+					return len(lambda.Elements) + i
+				}
+			}
+			return nParams
+		}
+	}
+	// The argument at pos, or the next one if pos is between two arguments.
+	arg := 0
+	for _, a := range form.Args {
+		if a.To < pos {
+			arg++
+		}
+	}
+	for i, p := range lambda.Elements {
+		if strings.HasPrefix(parse.SourceText(p), "@") && arg >= i {
+			return i
+		}
+	}
+	if arg < len(lambda.Elements) {
+		return arg
+	}
+	return nParams
+}
+
+func utf16Len(s string) int { return len(utf16.Encode([]rune(s))) }
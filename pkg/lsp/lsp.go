@@ -11,6 +11,11 @@ import (
 
 // Program is the LSP subprogram.
 type Program struct {
+	// Returns the directories of library modules, which are indexed for
+	// workspace symbols. The caller should supply pkg/shell.LibPaths. If it is
+	// nil, only the workspace roots are indexed.
+	LibDirs func() ([]string, error)
+
 	run bool
 }
 
@@ -24,7 +29,13 @@ func (p *Program) Run(fds [3]*os.File, _ []string) error {
 	}
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	s := newServer()
+	var libDirs []string
+	if p.LibDirs != nil {
+		// An error only means that some directories can't be determined;
+		// indexing the rest is still useful.
+		libDirs, _ = p.LibDirs()
+	}
+	s := newServer(libDirs)
 	conn := jsonrpc2.NewConn(ctx,
 		jsonrpc2.NewBufferedStream(transport{fds[0], fds[1]}, jsonrpc2.VSCodeObjectCodec{}),
 		handler(s))
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -12,8 +13,11 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/sourcegraph/jsonrpc2"
 	lsp "pkg.nimblebun.works/go-lsp"
+	"src.elv.sh/pkg/diag"
+	"src.elv.sh/pkg/eval"
 	"src.elv.sh/pkg/mods/doc"
 	"src.elv.sh/pkg/must"
+	"src.elv.sh/pkg/parse"
 	"src.elv.sh/pkg/prog"
 	"src.elv.sh/pkg/testutil"
 )
@@ -67,6 +71,27 @@ var diagTests = []struct {
 			Severity: lsp.DSError, Source: "parse", Message: "should be variable name",
 		},
 	}},
+	{"compilation error", "echo $x", []lsp.Diagnostic{
+		{
+			Range:    lspRange(0, 5, 0, 7),
+			Severity: lsp.DSError, Source: "compile", Message: "variable $x not found",
+		},
+	}},
+	{"variables in the edit namespace",
+		"set edit:prompt = { put '> ' }; echo $edit:completion:arg-completer",
+		[]lsp.Diagnostic{}},
+	{"unknown module", "use strr", []lsp.Diagnostic{
+		{
+			Range:    lspRange(0, 4, 0, 8),
+			Severity: lsp.DSWarning, Source: "compile", Message: "module strr not found",
+		},
+	}},
+	{"module not imported", "str:join , []", []lsp.Diagnostic{
+		{
+			Range:    lspRange(0, 0, 0, 8),
+			Severity: lsp.DSWarning, Source: "compile", Message: "module str is not imported",
+		},
+	}},
 }
 
 func TestDidOpenDiagnostics(t *testing.T) {
@@ -92,6 +117,31 @@ func TestDidChangeDiagnostics(t *testing.T) {
 	}
 }
 
+func TestDidChangeIncremental(t *testing.T) {
+	f := setup(t)
+	f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams("echo\n$!"))
+	checkDiag(t, f, diagParam([]lsp.Diagnostic{
+		{Range: lspRange(1, 1, 1, 2), Severity: lsp.DSError,
+			Source: "parse", Message: "should be variable name"},
+	}))
+
+	// Fix the error.
+	r := lspRange(1, 1, 1, 2)
+	f.conn.Notify(bgCtx, "textDocument/didChange",
+		incrementalChangeParams(textDocumentContentChangeEvent{&r, "pid"}))
+	checkDiag(t, f, diagParam([]lsp.Diagnostic{}))
+
+	// Multiple changes are applied in order: "echo\n$pid" -> "$pid" -> "$!pid".
+	r1, r2 := lspRange(0, 0, 1, 0), lspRange(0, 1, 0, 1)
+	f.conn.Notify(bgCtx, "textDocument/didChange", incrementalChangeParams(
+		textDocumentContentChangeEvent{&r1, ""},
+		textDocumentContentChangeEvent{&r2, "!"}))
+	checkDiag(t, f, diagParam([]lsp.Diagnostic{
+		{Range: lspRange(0, 1, 0, 2), Severity: lsp.DSError,
+			Source: "parse", Message: "should be variable name"},
+	}))
+}
+
 var hoverTests = []struct {
 	name string
 	text string
@@ -129,6 +179,62 @@ var hoverTests = []struct {
 
 		wantHover: lsp.Hover{},
 	},
+	{
+		name: "module command",
+		text: "str:join , [a b]",
+		pos:  lsp.Position{Line: 0, Character: 0},
+
+		wantHover: hoverWith(must.OK1(doc.Source("str:join"))),
+	},
+	{
+		name: "module command with alias",
+		text: "use str s\ns:join , [a b]",
+		pos:  lsp.Position{Line: 1, Character: 0},
+
+		wantHover: hoverWith(must.OK1(doc.Source("str:join"))),
+	},
+	{
+		name: "editor variable",
+		text: "echo $edit:prompt",
+		pos:  lsp.Position{Line: 0, Character: 5},
+
+		wantHover: hoverWith(must.OK1(doc.Source("$edit:prompt"))),
+	},
+	{
+		name: "function variable",
+		text: "echo $put~",
+		pos:  lsp.Position{Line: 0, Character: 5},
+
+		wantHover: hoverWith(must.OK1(doc.Source("put"))),
+	},
+	{
+		name: "user function with elvdoc",
+		text: "# Does f.\nfn f {|a| }\nf x",
+		pos:  lsp.Position{Line: 2, Character: 0},
+
+		wantHover: hoverWith("```elvish\nf $a\n```\n\nDoes f.\n"),
+	},
+	{
+		name: "user function without elvdoc",
+		text: "fn f {|a @b &k=v| }\nf x",
+		pos:  lsp.Position{Line: 1, Character: 0},
+
+		wantHover: hoverWith("```elvish\nf $a $b... &k=v\n```"),
+	},
+	{
+		name: "user variable with elvdoc",
+		text: "# The x.\nvar x\necho $x",
+		pos:  lsp.Position{Line: 2, Character: 6},
+
+		wantHover: hoverWith("The x.\n"),
+	},
+	{
+		name: "user function shadowing builtin",
+		text: "fn echo { }\necho",
+		pos:  lsp.Position{Line: 1, Character: 0},
+
+		wantHover: hoverWith("```elvish\necho\n```"),
+	},
 }
 
 func hoverWith(markdown string) lsp.Hover {
@@ -168,6 +274,101 @@ var completionTests = []struct {
 	{"bad", "put [", completionParams(0, 5), 0},
 }
 
+var signatureHelpTests = []struct {
+	name string
+	text string
+	pos  lsp.Position
+
+	wantSignatureHelp *signatureHelp
+}{
+	{
+		name: "builtin function",
+		//     0123456789
+		text: "str:join , ",
+		pos:  lsp.Position{Line: 0, Character: 11},
+
+		wantSignatureHelp: &signatureHelp{
+			Signatures: []signatureInformation{{
+				Label:         "str:join $sep $input-list?",
+				Documentation: docMarkup("str:join"),
+				Parameters:    []parameterInformation{{[2]int{9, 13}}, {[2]int{14, 26}}},
+			}},
+			ActiveParameter: 1,
+		},
+	},
+	{
+		name: "user function with rest argument and option",
+		//                      0123456789
+		text: "fn f {|a @b &k=v| }\nf x y z &k=",
+		pos:  lsp.Position{Line: 1, Character: 6},
+
+		wantSignatureHelp: &signatureHelp{
+			Signatures: []signatureInformation{{
+				Label:      "f $a $b... &k=v",
+				Parameters: []parameterInformation{{[2]int{2, 4}}, {[2]int{5, 10}}, {[2]int{11, 15}}},
+			}},
+			ActiveParameter: 1,
+		},
+	},
+	{
+		name: "option of user function",
+		text: "fn f {|a @b &k=v| }\nf x y z &k=",
+		pos:  lsp.Position{Line: 1, Character: 11},
+
+		wantSignatureHelp: &signatureHelp{
+			Signatures: []signatureInformation{{
+				Label:      "f $a $b... &k=v",
+				Parameters: []parameterInformation{{[2]int{2, 4}}, {[2]int{5, 10}}, {[2]int{11, 15}}},
+			}},
+			ActiveParameter: 2,
+		},
+	},
+	{
+		name: "innermost form",
+		//     0123456789012
+		text: "echo (put a ",
+		pos:  lsp.Position{Line: 0, Character: 12},
+
+		wantSignatureHelp: &signatureHelp{
+			Signatures: []signatureInformation{{
+				Label:         "put $value...",
+				Documentation: docMarkup("put"),
+				Parameters:    []parameterInformation{{[2]int{4, 13}}},
+			}},
+			ActiveParameter: 0,
+		},
+	},
+	{
+		name: "unknown command",
+		text: "some-external ",
+		pos:  lsp.Position{Line: 0, Character: 14},
+
+		wantSignatureHelp: nil,
+	},
+}
+
+func docMarkup(qname string) *lsp.MarkupContent {
+	return &lsp.MarkupContent{Kind: lsp.MKMarkdown, Value: must.OK1(doc.Lookup(qname)).Content}
+}
+
+func TestSignatureHelp(t *testing.T) {
+	f := setup(t)
+
+	for _, test := range signatureHelpTests {
+		t.Run(test.name, func(t *testing.T) {
+			f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams(test.text))
+			var response *signatureHelp
+			err := f.conn.Call(bgCtx, "textDocument/signatureHelp", positionParams(test.pos), &response)
+			if err != nil {
+				t.Errorf("got error %v", err)
+			}
+			if diff := cmp.Diff(test.wantSignatureHelp, response); diff != "" {
+				t.Errorf("response (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestCompletion(t *testing.T) {
 	f := setup(t)
 	testutil.Setenv(t, "PATH", "")
@@ -196,6 +397,461 @@ func TestCompletion(t *testing.T) {
 	}
 }
 
+// Shared by the tests for definition, references, documentHighlight and
+// rename.
+const symbolText = "var x = 1; fn f { put $x }\nf; echo $x"
+
+func TestDefinition(t *testing.T) {
+	f := setup(t)
+	f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams(symbolText))
+
+	tests := []struct {
+		name string
+		pos  lsp.Position
+		want *location
+	}{
+		{"from variable use", lsp.Position{Line: 0, Character: 23},
+			&location{testURI, lspRange(0, 4, 0, 5)}},
+		{"from function call", lsp.Position{Line: 1, Character: 0},
+			&location{testURI, lspRange(0, 14, 0, 15)}},
+		{"from declaration", lsp.Position{Line: 0, Character: 4},
+			&location{testURI, lspRange(0, 4, 0, 5)}},
+		{"no symbol", lsp.Position{Line: 1, Character: 4}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var response *location
+			err := f.conn.Call(bgCtx, "textDocument/definition", positionParams(test.pos), &response)
+			if err != nil {
+				t.Errorf("got error %v", err)
+			}
+			if diff := cmp.Diff(test.want, response); diff != "" {
+				t.Errorf("response (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReferences(t *testing.T) {
+	f := setup(t)
+	f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams(symbolText))
+
+	for _, includeDecl := range []bool{false, true} {
+		t.Run(fmt.Sprint("includeDeclaration=", includeDecl), func(t *testing.T) {
+			request := referenceParams{
+				TextDocumentPositionParams: positionParams(lsp.Position{Line: 1, Character: 9}),
+				Context:                    referenceContext{IncludeDeclaration: includeDecl},
+			}
+			var response []location
+			err := f.conn.Call(bgCtx, "textDocument/references", request, &response)
+			if err != nil {
+				t.Errorf("got error %v", err)
+			}
+			want := []location{
+				{testURI, lspRange(0, 23, 0, 24)},
+				{testURI, lspRange(1, 9, 1, 10)},
+			}
+			if includeDecl {
+				want = append([]location{{testURI, lspRange(0, 4, 0, 5)}}, want...)
+			}
+			if diff := cmp.Diff(want, response); diff != "" {
+				t.Errorf("response (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReferences_QualifiedNames(t *testing.T) {
+	f := setup(t)
+	f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams(
+		"var ns: = (ns [&x=1])\nput $ns:x\nset ns:x = 2"))
+
+	request := referenceParams{
+		TextDocumentPositionParams: positionParams(lsp.Position{Line: 1, Character: 5}),
+		Context:                    referenceContext{IncludeDeclaration: true},
+	}
+	var response []location
+	err := f.conn.Call(bgCtx, "textDocument/references", request, &response)
+	if err != nil {
+		t.Errorf("got error %v", err)
+	}
+	want := []location{
+		{testURI, lspRange(0, 4, 0, 6)},
+		{testURI, lspRange(1, 5, 1, 7)},
+		{testURI, lspRange(2, 4, 2, 6)},
+	}
+	if diff := cmp.Diff(want, response); diff != "" {
+		t.Errorf("response (-want +got):\n%s", diff)
+	}
+}
+
+func TestDocumentHighlight(t *testing.T) {
+	f := setup(t)
+	f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams(symbolText))
+
+	var response []documentHighlight
+	err := f.conn.Call(bgCtx, "textDocument/documentHighlight",
+		positionParams(lsp.Position{Line: 0, Character: 14}), &response)
+	if err != nil {
+		t.Errorf("got error %v", err)
+	}
+	want := []documentHighlight{
+		{lspRange(0, 14, 0, 15), dhkWrite},
+		{lspRange(1, 0, 1, 1), dhkRead},
+	}
+	if diff := cmp.Diff(want, response); diff != "" {
+		t.Errorf("response (-want +got):\n%s", diff)
+	}
+}
+
+func TestRename(t *testing.T) {
+	f := setup(t)
+	f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams(symbolText))
+
+	request := renameParams{positionParams(lsp.Position{Line: 0, Character: 4}), "y"}
+	var response workspaceEdit
+	err := f.conn.Call(bgCtx, "textDocument/rename", request, &response)
+	if err != nil {
+		t.Errorf("got error %v", err)
+	}
+	want := workspaceEdit{map[lsp.DocumentURI][]lsp.TextEdit{testURI: {
+		{Range: lspRange(0, 4, 0, 5), NewText: "y"},
+		{Range: lspRange(0, 23, 0, 24), NewText: "y"},
+		{Range: lspRange(1, 9, 1, 10), NewText: "y"},
+	}}}
+	if diff := cmp.Diff(want, response); diff != "" {
+		t.Errorf("response (-want +got):\n%s", diff)
+	}
+
+	for _, bad := range []string{"", "a b", "a:b"} {
+		request := renameParams{positionParams(lsp.Position{Line: 0, Character: 4}), bad}
+		err := f.conn.Call(bgCtx, "textDocument/rename", request, &response)
+		if err == nil {
+			t.Errorf("got nil error for new name %q", bad)
+		}
+	}
+}
+
+func TestRename_NameWithEscapeSequences(t *testing.T) {
+	f := setup(t)
+	f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams(`var "\x78" = 1; put $x`))
+
+	request := renameParams{positionParams(lsp.Position{Line: 0, Character: 21}), "y"}
+	var response workspaceEdit
+	err := f.conn.Call(bgCtx, "textDocument/rename", request, &response)
+	if err == nil {
+		t.Errorf("got nil error")
+	}
+}
+
+func TestDocumentSymbol(t *testing.T) {
+	f := setup(t)
+	f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams(
+		"use a/b c\nvar x @y = 1 2\nfn f {|a| var z; set x = 2 }\nset x = 3"))
+
+	var response []documentSymbol
+	err := f.conn.Call(bgCtx, "textDocument/documentSymbol",
+		documentSymbolParams{lsp.TextDocumentIdentifier{URI: testURI}}, &response)
+	if err != nil {
+		t.Errorf("got error %v", err)
+	}
+	want := []documentSymbol{
+		{Name: "c", Detail: "a/b", Kind: skModule,
+			Range: lspRange(0, 0, 0, 9), SelectionRange: lspRange(0, 8, 0, 9)},
+		{Name: "x", Detail: "var", Kind: skVariable,
+			Range: lspRange(1, 0, 1, 14), SelectionRange: lspRange(1, 4, 1, 5)},
+		{Name: "y", Detail: "var", Kind: skVariable,
+			Range: lspRange(1, 0, 1, 14), SelectionRange: lspRange(1, 6, 1, 8)},
+		{Name: "f", Detail: "|a|", Kind: skFunction,
+			Range: lspRange(2, 0, 2, 28), SelectionRange: lspRange(2, 3, 2, 4),
+			Children: []documentSymbol{
+				{Name: "z", Detail: "var", Kind: skVariable,
+					Range: lspRange(2, 10, 2, 15), SelectionRange: lspRange(2, 14, 2, 15)},
+			}},
+		{Name: "x", Detail: "set", Kind: skVariable,
+			Range: lspRange(3, 0, 3, 9), SelectionRange: lspRange(3, 4, 3, 5)},
+	}
+	if diff := cmp.Diff(want, response); diff != "" {
+		t.Errorf("response (-want +got):\n%s", diff)
+	}
+}
+
+func TestWorkspaceSymbol(t *testing.T) {
+	root := testutil.TempDir(t)
+	libDir := testutil.TempDir(t)
+	testutil.ApplyDirIn(testutil.Dir{
+		"foo.elv": "fn foo-root { }",
+		"not-elv": "fn foo-not-elv { }",
+		".git":    testutil.Dir{"foo.elv": "fn foo-hidden { }"},
+
+		"node_modules": testutil.Dir{"foo.elv": "fn foo-node-modules { }"},
+	}, root)
+	testutil.ApplyDirIn(testutil.Dir{
+		"m": testutil.Dir{"foo.elv": "fn foo-lib { }\nfn bar { }"},
+	}, libDir)
+
+	p := &Program{LibDirs: func() ([]string, error) { return []string{libDir}, nil }}
+	f := setupWith(t, p, initializeParams{RootURI: uriFromPath(root)})
+	f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams("var foo-open"))
+
+	var response []symbolInformation
+	err := f.conn.Call(bgCtx, "workspace/symbol", workspaceSymbolParams{"FOO"}, &response)
+	if err != nil {
+		t.Errorf("got error %v", err)
+	}
+	want := []symbolInformation{
+		{Name: "foo-open", Kind: skVariable,
+			Location: location{testURI, lspRange(0, 4, 0, 12)}},
+		{Name: "foo-root", Kind: skFunction,
+			Location: location{uriFromPath(filepath.Join(root, "foo.elv")), lspRange(0, 3, 0, 11)}},
+		{Name: "foo-lib", Kind: skFunction,
+			Location: location{uriFromPath(filepath.Join(libDir, "m", "foo.elv")), lspRange(0, 3, 0, 10)}},
+	}
+	if diff := cmp.Diff(want, response); diff != "" {
+		t.Errorf("response (-want +got):\n%s", diff)
+	}
+}
+
+func TestWorkspaceSymbol_Index(t *testing.T) {
+	root := testutil.TempDir(t)
+	path := filepath.Join(root, "foo.elv")
+	mtime := time.Now().Add(-time.Hour)
+	writeFile := func(content string) {
+		testutil.ApplyDirIn(testutil.Dir{"foo.elv": content}, root)
+		must.OK(os.Chtimes(path, mtime, mtime))
+	}
+	writeFile("fn foo { }")
+
+	f := setupWith(t, &Program{}, initializeParams{RootURI: uriFromPath(root)})
+	query := func() []string {
+		var response []symbolInformation
+		err := f.conn.Call(bgCtx, "workspace/symbol", workspaceSymbolParams{""}, &response)
+		if err != nil {
+			t.Errorf("got error %v", err)
+		}
+		var names []string
+		for _, info := range response {
+			names = append(names, info.Name)
+		}
+		return names
+	}
+	wantNames := func(want ...string) {
+		t.Helper()
+		if names := query(); !reflect.DeepEqual(names, want) {
+			t.Errorf("got names %v, want %v", names, want)
+		}
+	}
+
+	wantNames("foo")
+	// Files whose modification time hasn't changed are not parsed again.
+	writeFile("fn bar { }")
+	wantNames("foo")
+	// Changes reported by the client invalidate the index.
+	f.conn.Notify(bgCtx, "workspace/didChangeWatchedFiles",
+		didChangeWatchedFilesParams{[]fileEvent{{uriFromPath(path)}}})
+	wantNames("bar")
+	// So do changes to the modification time.
+	mtime = mtime.Add(time.Minute)
+	writeFile("fn lorem { }")
+	wantNames("lorem")
+	// Removed files are removed from the index.
+	must.OK(os.Remove(path))
+	wantNames()
+}
+
+func TestFormatting(t *testing.T) {
+	f := setup(t)
+	f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams("echo  a|b\necho  c\n"))
+
+	var response []lsp.TextEdit
+	err := f.conn.Call(bgCtx, "textDocument/formatting",
+		documentFormattingParams{TextDocument: lsp.TextDocumentIdentifier{URI: testURI}}, &response)
+	if err != nil {
+		t.Errorf("got error %v", err)
+	}
+	want := []lsp.TextEdit{{Range: lspRange(0, 5, 1, 5), NewText: "a | b\necho"}}
+	if diff := cmp.Diff(want, response); diff != "" {
+		t.Errorf("response (-want +got):\n%s", diff)
+	}
+
+	err = f.conn.Call(bgCtx, "textDocument/rangeFormatting",
+		documentRangeFormattingParams{
+			TextDocument: lsp.TextDocumentIdentifier{URI: testURI},
+			Range:        lspRange(1, 0, 1, 1)}, &response)
+	if err != nil {
+		t.Errorf("got error %v", err)
+	}
+	want = []lsp.TextEdit{{Range: lspRange(1, 5, 1, 6), NewText: ""}}
+	if diff := cmp.Diff(want, response); diff != "" {
+		t.Errorf("response (-want +got):\n%s", diff)
+	}
+
+	// Documents with parse errors are not formatted.
+	f.conn.Notify(bgCtx, "textDocument/didChange", didChangeParams("echo  ["))
+	err = f.conn.Call(bgCtx, "textDocument/formatting",
+		documentFormattingParams{TextDocument: lsp.TextDocumentIdentifier{URI: testURI}}, &response)
+	if err != nil {
+		t.Errorf("got error %v", err)
+	}
+	if len(response) != 0 {
+		t.Errorf("got edits %v for document with parse errors", response)
+	}
+}
+
+var codeActionTests = []struct {
+	name string
+	text string
+	rg   lsp.Range
+	want []codeAction
+}{
+	{
+		name: "declare variable",
+		text: "echo a\necho $x",
+		rg:   lspRange(1, 5, 1, 7),
+		want: []codeAction{quickFix("Declare $x", true, lspRange(1, 0, 1, 0), "var x\n")},
+	},
+	{
+		name: "declare variable in the middle of a line",
+		text: "echo a; echo (put $x)",
+		rg:   lspRange(0, 18, 0, 18),
+		want: []codeAction{quickFix("Declare $x", true, lspRange(0, 8, 0, 8), "var x; ")},
+	},
+	{
+		name: "declare variable in lambda",
+		text: "f {\n  echo $x\n}",
+		rg:   lspRange(1, 7, 1, 9),
+		want: []codeAction{quickFix("Declare $x", true, lspRange(1, 2, 1, 2), "var x\n  ")},
+	},
+	{
+		name: "turn set into var",
+		text: "set x = foo",
+		rg:   lspRange(0, 4, 0, 5),
+		want: []codeAction{quickFix("Declare $x", true, lspRange(0, 0, 0, 3), "var")},
+	},
+	{
+		name: "add use form",
+		text: "# comment\nstr:join , []",
+		rg:   lspRange(1, 0, 1, 0),
+		want: []codeAction{quickFix("Add use str", true, lspRange(1, 0, 1, 0), "use str\n")},
+	},
+	{
+		name: "add use form after existing ones",
+		text: "use re\necho $str:x",
+		rg:   lspRange(1, 5, 1, 11),
+		want: []codeAction{quickFix("Add use str", true, lspRange(1, 0, 1, 0), "use str\n")},
+	},
+	{
+		name: "fix module spec",
+		text: "use strr",
+		rg:   lspRange(0, 0, 0, 8),
+		want: []codeAction{quickFix(`Change to str`, true, lspRange(0, 4, 0, 8), "str")},
+	},
+	{
+		name: "no problems in range",
+		text: "echo $x\necho",
+		rg:   lspRange(1, 0, 1, 4),
+		want: []codeAction{},
+	},
+}
+
+func TestCodeAction(t *testing.T) {
+	f := setup(t)
+	for _, test := range codeActionTests {
+		t.Run(test.name, func(t *testing.T) {
+			f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams(test.text))
+			<-f.diags
+			var response []codeAction
+			err := f.conn.Call(bgCtx, "textDocument/codeAction", codeActionParams{
+				TextDocument: lsp.TextDocumentIdentifier{URI: testURI},
+				Range:        test.rg}, &response)
+			if err != nil {
+				t.Errorf("got error %v", err)
+			}
+			// Only compare the edits, since the diagnostics are tested
+			// separately.
+			for i := range response {
+				response[i].Diagnostics = nil
+			}
+			if diff := cmp.Diff(test.want, response); diff != "" {
+				t.Errorf("response (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCodeAction_Deprecation(t *testing.T) {
+	testutil.Set(t, &prog.DeprecationLevel, 22)
+	s := newServer(nil)
+	// "foo" is a fake deprecated builtin; see eval.compiler.checkDeprecatedBuiltin.
+	s.evaler.ExtendBuiltin(eval.BuildNs().AddGoFn("foo", func() {}))
+	code := "foo"
+	tree, err := parse.Parse(parse.Source{Name: testURI, Code: code}, parse.Config{})
+	problems := s.findProblems(testURI,
+		document{code: code, parseTree: tree, parseErr: err}, s.evaler.CheckAll(tree))
+
+	want := []codeAction{quickFix("Replace with bar", true, lspRange(0, 0, 0, 3), "bar")}
+	want[0].Diagnostics = []lsp.Diagnostic{{
+		Range: lspRange(0, 0, 0, 3), Severity: lsp.DSWarning, Source: "deprecation",
+		Message: `the "foo" command is deprecated; use "bar" instead`}}
+	got := codeActions(testURI, problems, diag.Ranging{From: 0, To: 0})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("code actions (-want +got):\n%s", diff)
+	}
+}
+
+func quickFix(title string, preferred bool, r lsp.Range, newText string) codeAction {
+	return codeAction{
+		Title: title, Kind: "quickfix", IsPreferred: preferred,
+		Edit: &workspaceEdit{map[lsp.DocumentURI][]lsp.TextEdit{
+			testURI: {{Range: r, NewText: newText}}}},
+	}
+}
+
+func TestSemanticTokens(t *testing.T) {
+	f := setup(t)
+	f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams(
+		"var x = 'a\nb' # c\nif $x { ls | echo $pid }"))
+
+	var response semanticTokens
+	err := f.conn.Call(bgCtx, "textDocument/semanticTokens/full",
+		semanticTokensParams{lsp.TextDocumentIdentifier{URI: testURI}}, &response)
+	if err != nil {
+		t.Errorf("got error %v", err)
+	}
+	want := []uint32{
+		0, 0, 3, 4, 0, // var (a special form)
+		0, 4, 1, 2, 0, // x
+		0, 2, 1, 4, 0, // =
+		0, 2, 2, 1, 0, // 'a (first line of the string)
+		1, 0, 2, 1, 0, // b' (second line of the string)
+		0, 3, 3, 0, 0, // # c
+		1, 0, 2, 4, 0, // if
+		0, 3, 2, 2, 0, // $x
+		0, 5, 2, 3, 0, // ls
+		0, 3, 1, 5, 0, // |
+		0, 2, 4, 3, 1, // echo (a builtin)
+		0, 5, 4, 2, 1, // $pid (a builtin)
+	}
+	if diff := cmp.Diff(want, response.Data); diff != "" {
+		t.Errorf("response (-want +got):\n%s", diff)
+	}
+
+	err = f.conn.Call(bgCtx, "textDocument/semanticTokens/range",
+		semanticTokensRangeParams{
+			TextDocument: lsp.TextDocumentIdentifier{URI: testURI},
+			Range:        lspRange(2, 0, 2, 5)}, &response)
+	if err != nil {
+		t.Errorf("got error %v", err)
+	}
+	want = []uint32{
+		2, 0, 2, 4, 0, // if
+		0, 3, 2, 2, 0, // $x
+	}
+	if diff := cmp.Diff(want, response.Data); diff != "" {
+		t.Errorf("response (-want +got):\n%s", diff)
+	}
+}
+
 var jsonrpcErrorTests = []struct {
 	name    string
 	method  string
@@ -213,6 +869,17 @@ var jsonrpcErrorTests = []struct {
 			TextDocumentPositionParams: lsp.TextDocumentPositionParams{
 				TextDocument: lsp.TextDocumentIdentifier{URI: "file://unknown"}}},
 		unknownDocument("file://unknown")},
+	{"unknown document to definition", "textDocument/definition",
+		lsp.TextDocumentPositionParams{
+			TextDocument: lsp.TextDocumentIdentifier{URI: "file://unknown"}},
+		unknownDocument("file://unknown")},
+	{"unknown document to semantic tokens", "textDocument/semanticTokens/full",
+		semanticTokensParams{lsp.TextDocumentIdentifier{URI: "file://unknown"}},
+		unknownDocument("file://unknown")},
+	{"unknown document to formatting", "textDocument/formatting",
+		documentFormattingParams{
+			TextDocument: lsp.TextDocumentIdentifier{URI: "file://unknown"}},
+		unknownDocument("file://unknown")},
 }
 
 func TestJSONRPCErrors(t *testing.T) {
@@ -234,16 +901,25 @@ func didOpenParams(text string) lsp.DidOpenTextDocumentParams {
 		TextDocument: lsp.TextDocumentItem{URI: testURI, Text: text}}
 }
 
-func didChangeParams(text string) lsp.DidChangeTextDocumentParams {
-	return lsp.DidChangeTextDocumentParams{
+func didChangeParams(text string) didChangeTextDocumentParams {
+	return didChangeTextDocumentParams{
 		TextDocument: lsp.VersionedTextDocumentIdentifier{
 			TextDocumentIdentifier: lsp.TextDocumentIdentifier{URI: testURI},
 		},
-		ContentChanges: []lsp.TextDocumentContentChangeEvent{
+		ContentChanges: []textDocumentContentChangeEvent{
 			{Text: text},
 		}}
 }
 
+func incrementalChangeParams(changes ...textDocumentContentChangeEvent) didChangeTextDocumentParams {
+	return didChangeTextDocumentParams{
+		TextDocument: lsp.VersionedTextDocumentIdentifier{
+			TextDocumentIdentifier: lsp.TextDocumentIdentifier{URI: testURI},
+		},
+		ContentChanges: changes,
+	}
+}
+
 func diagParam(diags []lsp.Diagnostic) lsp.PublishDiagnosticsParams {
 	return lsp.PublishDiagnosticsParams{URI: testURI, Diagnostics: diags}
 }
@@ -269,19 +945,37 @@ func completionParams(line, char int) lsp.CompletionParams {
 	}
 }
 
+func positionParams(pos lsp.Position) lsp.TextDocumentPositionParams {
+	return lsp.TextDocumentPositionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: testURI},
+		Position:     pos,
+	}
+}
+
+func lspRange(line1, char1, line2, char2 int) lsp.Range {
+	return lsp.Range{
+		Start: lsp.Position{Line: line1, Character: char1},
+		End:   lsp.Position{Line: line2, Character: char2},
+	}
+}
+
 type clientFixture struct {
 	conn  *jsonrpc2.Conn
 	diags <-chan lsp.PublishDiagnosticsParams
 }
 
 func setup(t *testing.T) *clientFixture {
+	return setupWith(t, &Program{}, initializeParams{})
+}
+
+func setupWith(t *testing.T, p *Program, params initializeParams) *clientFixture {
 	r0, w0 := must.Pipe()
 	r1, w1 := must.Pipe()
 
 	// Run server
 	done := make(chan struct{})
 	go func() {
-		prog.Run([3]*os.File{r0, w1, nil}, []string{"elvish", "-lsp"}, &Program{})
+		prog.Run([3]*os.File{r0, w1, nil}, []string{"elvish", "-lsp"}, p)
 		close(done)
 	}()
 	t.Cleanup(func() { <-done })
@@ -296,7 +990,7 @@ func setup(t *testing.T) *clientFixture {
 
 	// LSP handshake
 	err := conn.Call(context.Background(),
-		"initialize", lsp.InitializeParams{}, &lsp.InitializeResult{})
+		"initialize", params, &lsp.InitializeResult{})
 	if err != nil {
 		t.Errorf("got error %v, want nil", err)
 	}
@@ -0,0 +1,213 @@
+package lsp
+
+import (
+	lsp "pkg.nimblebun.works/go-lsp"
+)
+
+// LSP protocol types used by the server in addition to the ones from
+// pkg.nimblebun.works/go-lsp. Field names and JSON tags follow the LSP
+// specification.
+
+type initializeParams struct {
+	RootURI          lsp.DocumentURI   `json:"rootUri"`
+	WorkspaceFolders []workspaceFolder `json:"workspaceFolders"`
+}
+
+type workspaceFolder struct {
+	URI  lsp.DocumentURI `json:"uri"`
+	Name string          `json:"name"`
+}
+
+type initializeResult struct {
+	Capabilities serverCapabilities `json:"capabilities"`
+}
+
+// Fields declared here take precedence over those of the same JSON name in the
+// embedded lsp.ServerCapabilities.
+type serverCapabilities struct {
+	lsp.ServerCapabilities
+	DefinitionProvider        bool `json:"definitionProvider,omitempty"`
+	ReferencesProvider        bool `json:"referencesProvider,omitempty"`
+	DocumentHighlightProvider bool `json:"documentHighlightProvider,omitempty"`
+	RenameProvider            bool `json:"renameProvider,omitempty"`
+	DocumentSymbolProvider    bool `json:"documentSymbolProvider,omitempty"`
+	WorkspaceSymbolProvider   bool `json:"workspaceSymbolProvider,omitempty"`
+
+	DocumentFormattingProvider      bool `json:"documentFormattingProvider,omitempty"`
+	DocumentRangeFormattingProvider bool `json:"documentRangeFormattingProvider,omitempty"`
+
+	CodeActionProvider     bool                   `json:"codeActionProvider,omitempty"`
+	SignatureHelpProvider  *signatureHelpOptions  `json:"signatureHelpProvider,omitempty"`
+	SemanticTokensProvider *semanticTokensOptions `json:"semanticTokensProvider,omitempty"`
+}
+
+type location struct {
+	URI   lsp.DocumentURI `json:"uri"`
+	Range lsp.Range       `json:"range"`
+}
+
+type referenceParams struct {
+	lsp.TextDocumentPositionParams
+	Context referenceContext `json:"context"`
+}
+
+type referenceContext struct {
+	IncludeDeclaration bool `json:"includeDeclaration"`
+}
+
+type documentHighlightKind int
+
+const (
+	dhkRead  documentHighlightKind = 2
+	dhkWrite documentHighlightKind = 3
+)
+
+type documentHighlight struct {
+	Range lsp.Range             `json:"range"`
+	Kind  documentHighlightKind `json:"kind,omitempty"`
+}
+
+type renameParams struct {
+	lsp.TextDocumentPositionParams
+	NewName string `json:"newName"`
+}
+
+type workspaceEdit struct {
+	Changes map[lsp.DocumentURI][]lsp.TextEdit `json:"changes"`
+}
+
+type symbolKind int
+
+const (
+	skModule   symbolKind = 2
+	skFunction symbolKind = 12
+	skVariable symbolKind = 13
+)
+
+type documentSymbolParams struct {
+	TextDocument lsp.TextDocumentIdentifier `json:"textDocument"`
+}
+
+type documentSymbol struct {
+	Name           string           `json:"name"`
+	Detail         string           `json:"detail,omitempty"`
+	Kind           symbolKind       `json:"kind"`
+	Range          lsp.Range        `json:"range"`
+	SelectionRange lsp.Range        `json:"selectionRange"`
+	Children       []documentSymbol `json:"children,omitempty"`
+}
+
+type workspaceSymbolParams struct {
+	Query string `json:"query"`
+}
+
+type didChangeWatchedFilesParams struct {
+	Changes []fileEvent `json:"changes"`
+}
+
+// The type of the change is not needed, since any change invalidates the
+// cached symbols of the file.
+type fileEvent struct {
+	URI lsp.DocumentURI `json:"uri"`
+}
+
+type symbolInformation struct {
+	Name          string     `json:"name"`
+	Kind          symbolKind `json:"kind"`
+	Location      location   `json:"location"`
+	ContainerName string     `json:"containerName,omitempty"`
+}
+
+// The formatter has a fixed style, so the options are ignored.
+type formattingOptions struct {
+	TabSize      int  `json:"tabSize"`
+	InsertSpaces bool `json:"insertSpaces"`
+}
+
+type documentFormattingParams struct {
+	TextDocument lsp.TextDocumentIdentifier `json:"textDocument"`
+	Options      formattingOptions          `json:"options"`
+}
+
+type documentRangeFormattingParams struct {
+	TextDocument lsp.TextDocumentIdentifier `json:"textDocument"`
+	Range        lsp.Range                  `json:"range"`
+	Options      formattingOptions          `json:"options"`
+}
+
+type didChangeTextDocumentParams struct {
+	TextDocument   lsp.VersionedTextDocumentIdentifier `json:"textDocument"`
+	ContentChanges []textDocumentContentChangeEvent    `json:"contentChanges"`
+}
+
+type textDocumentContentChangeEvent struct {
+	// If nil, Text is the full content of the document.
+	Range *lsp.Range `json:"range,omitempty"`
+	Text  string     `json:"text"`
+}
+
+type semanticTokensLegend struct {
+	TokenTypes     []string `json:"tokenTypes"`
+	TokenModifiers []string `json:"tokenModifiers"`
+}
+
+type semanticTokensOptions struct {
+	Legend semanticTokensLegend `json:"legend"`
+	Range  bool                 `json:"range"`
+	Full   bool                 `json:"full"`
+}
+
+type semanticTokensParams struct {
+	TextDocument lsp.TextDocumentIdentifier `json:"textDocument"`
+}
+
+type semanticTokensRangeParams struct {
+	TextDocument lsp.TextDocumentIdentifier `json:"textDocument"`
+	Range        lsp.Range                  `json:"range"`
+}
+
+type semanticTokens struct {
+	Data []uint32 `json:"data"`
+}
+
+type signatureHelpOptions struct {
+	TriggerCharacters []string `json:"triggerCharacters,omitempty"`
+}
+
+type signatureHelp struct {
+	Signatures      []signatureInformation `json:"signatures"`
+	ActiveSignature int                    `json:"activeSignature"`
+	ActiveParameter int                    `json:"activeParameter"`
+}
+
+type signatureInformation struct {
+	Label         string                 `json:"label"`
+	Documentation *lsp.MarkupContent     `json:"documentation,omitempty"`
+	Parameters    []parameterInformation `json:"parameters"`
+}
+
+type parameterInformation struct {
+	// Start and end offsets within the label of the signature, in UTF-16 code
+	// units.
+	Label [2]int `json:"label"`
+}
+
+type codeActionParams struct {
+	TextDocument lsp.TextDocumentIdentifier `json:"textDocument"`
+	Range        lsp.Range                  `json:"range"`
+	Context      codeActionContext          `json:"context"`
+}
+
+// The diagnostics in the context are ignored, since the server finds the
+// problems in the range itself.
+type codeActionContext struct {
+	Diagnostics []lsp.Diagnostic `json:"diagnostics"`
+}
+
+type codeAction struct {
+	Title       string           `json:"title"`
+	Kind        string           `json:"kind,omitempty"`
+	Diagnostics []lsp.Diagnostic `json:"diagnostics,omitempty"`
+	Edit        *workspaceEdit   `json:"edit,omitempty"`
+	IsPreferred bool             `json:"isPreferred,omitempty"`
+}
@@ -0,0 +1,109 @@
+package lsp
+
+import (
+	"context"
+	"strings"
+
+	lsp "pkg.nimblebun.works/go-lsp"
+	"src.elv.sh/pkg/diag"
+	"src.elv.sh/pkg/edit/highlight"
+	"src.elv.sh/pkg/eval"
+)
+
+// The legend of semantic tokens. The indices of token types must match the
+// values of highlight.TokenType.
+var (
+	semanticTokenTypes = []string{
+		highlight.CommentToken:  "comment",
+		highlight.StringToken:   "string",
+		highlight.VariableToken: "variable",
+		highlight.CommandToken:  "function",
+		highlight.KeywordToken:  "keyword",
+		highlight.OperatorToken: "operator",
+	}
+	semanticTokenModifiers = []string{"defaultLibrary"}
+)
+
+const defaultLibraryModifier = 1 << 0
+
+func (s *server) semanticTokensFull(_ context.Context, params semanticTokensParams) (any, error) {
+	document, ok := s.documents[params.TextDocument.URI]
+	if !ok {
+		return nil, unknownDocument(params.TextDocument.URI)
+	}
+	return s.semanticTokens(document, diag.Ranging{From: 0, To: len(document.code)}), nil
+}
+
+func (s *server) semanticTokensRange(_ context.Context, params semanticTokensRangeParams) (any, error) {
+	document, ok := s.documents[params.TextDocument.URI]
+	if !ok {
+		return nil, unknownDocument(params.TextDocument.URI)
+	}
+	return s.semanticTokens(document, diag.Ranging{
+		From: lspPositionToIdx(document.code, params.Range.Start),
+		To:   lspPositionToIdx(document.code, params.Range.End),
+	}), nil
+}
+
+// Returns the semantic tokens that overlap with rg, classified in the same way
+// as the highlighting in the editor. Commands that are special forms are
+// keywords, and builtin commands and variables have the defaultLibrary
+// modifier.
+func (s *server) semanticTokens(document document, rg diag.Ranging) semanticTokens {
+	code := document.code
+	// The LSP position of each byte index of code that starts a codepoint.
+	positions := make([]lsp.Position, len(code)+1)
+	walkString(code, func(i int, p lsp.Position) bool {
+		positions[i] = p
+		return true
+	})
+
+	builtin := s.evaler.Builtin()
+	data := []uint32{}
+	var last lsp.Position
+	emit := func(from, to int, typ highlight.TokenType, modifiers uint32) {
+		start, end := positions[from], positions[to]
+		deltaChar := start.Character
+		if start.Line == last.Line {
+			deltaChar -= last.Character
+		}
+		data = append(data, uint32(start.Line-last.Line), uint32(deltaChar),
+			uint32(end.Character-start.Character), uint32(typ), modifiers)
+		last = start
+	}
+
+	for _, token := range highlight.Tokens(document.parseTree) {
+		if token.To <= rg.From || token.From >= rg.To {
+			continue
+		}
+		text := code[token.From:token.To]
+		typ, modifiers := token.Type, uint32(0)
+		switch typ {
+		case highlight.CommandToken:
+			if eval.IsBuiltinSpecial[text] {
+				typ = highlight.KeywordToken
+			} else if builtin.HasKeyString(text + eval.FnSuffix) {
+				modifiers = defaultLibraryModifier
+			}
+		case highlight.VariableToken:
+			_, name := eval.SplitSigil(strings.TrimPrefix(text, "$"))
+			if builtin.HasKeyString(name) {
+				modifiers = defaultLibraryModifier
+			}
+		}
+		// Tokens may not span multiple lines, so split them at line breaks.
+		from := token.From
+		for i := token.From; i < token.To; i++ {
+			if code[i] == '\r' || code[i] == '\n' {
+				if i > from {
+					emit(from, i, typ, modifiers)
+				}
+				from = i + 1
+			}
+		}
+		if token.To > from {
+			emit(from, token.To, typ, modifiers)
+		}
+	}
+	return semanticTokens{data}
+}
@@ -4,12 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/sourcegraph/jsonrpc2"
 	lsp "pkg.nimblebun.works/go-lsp"
 	"src.elv.sh/pkg/diag"
 	"src.elv.sh/pkg/edit/complete"
 	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/mods"
 	"src.elv.sh/pkg/mods/doc"
 	"src.elv.sh/pkg/parse"
 	"src.elv.sh/pkg/parse/np"
@@ -25,16 +28,30 @@ var (
 type server struct {
 	evaler    *eval.Evaler
 	documents map[lsp.DocumentURI]document
+	// Directories of library modules, indexed for workspace symbols along with
+	// the workspace roots.
+	libDirs []string
+	// Workspace roots, as given by the client in the initialize request.
+	roots []string
+	// Symbols of files under roots and libDirs, keyed by path.
+	symbolIndex map[string]indexedFile
 }
 
 type document struct {
 	code      string
 	parseTree parse.Tree
 	parseErr  error
+	symbols   []*eval.Symbol
+	problems  []problem
+	// Symbols for workspace/symbol requests.
+	workspaceSymbols []symbolInformation
 }
 
-func newServer() *server {
-	return &server{eval.NewEvaler(), make(map[lsp.DocumentURI]document)}
+func newServer(libDirs []string) *server {
+	ev := eval.NewEvaler()
+	mods.AddTo(ev)
+	return &server{ev, make(map[lsp.DocumentURI]document), libDirs, nil,
+		make(map[string]indexedFile)}
 }
 
 func handler(s *server) jsonrpc2.Handler {
@@ -45,12 +62,28 @@ func handler(s *server) jsonrpc2.Handler {
 		"textDocument/hover":      convertMethod(s.hover),
 		"textDocument/completion": convertMethod(s.completion),
 
+		"textDocument/signatureHelp": convertMethod(s.signatureHelp),
+
+		"textDocument/definition":        convertMethod(s.definition),
+		"textDocument/references":        convertMethod(s.references),
+		"textDocument/documentHighlight": convertMethod(s.documentHighlight),
+		"textDocument/rename":            convertMethod(s.rename),
+		"textDocument/documentSymbol":    convertMethod(s.documentSymbol),
+		"workspace/symbol":               convertMethod(s.workspaceSymbol),
+		"textDocument/formatting":        convertMethod(s.formatting),
+		"textDocument/rangeFormatting":   convertMethod(s.rangeFormatting),
+		"textDocument/codeAction":        convertMethod(s.codeAction),
+
+		"textDocument/semanticTokens/full":  convertMethod(s.semanticTokensFull),
+		"textDocument/semanticTokens/range": convertMethod(s.semanticTokensRange),
+
 		"textDocument/didClose": noop,
 		// Required by spec.
 		"initialized": noop,
-		// Called by clients even when server doesn't advertise support:
+		// Used to invalidate the workspace symbol index. Called by clients
+		// even when server doesn't advertise support:
 		// https://microsoft.github.io/language-server-protocol/specification#workspace_didChangeWatchedFiles
-		"workspace/didChangeWatchedFiles": noop,
+		"workspace/didChangeWatchedFiles": convertMethod(s.didChangeWatchedFiles),
 	})
 }
 
@@ -86,15 +119,54 @@ func conn(ctx context.Context) *jsonrpc2.Conn { return ctx.Value(connKey{}).(*js
 
 // Handler implementations. These are all called synchronously.
 
-func (s *server) initialize(_ context.Context, _ json.RawMessage) (any, error) {
-	return &lsp.InitializeResult{
-		Capabilities: lsp.ServerCapabilities{
-			TextDocumentSync: &lsp.TextDocumentSyncOptions{
-				OpenClose: true,
-				Change:    lsp.TDSyncKindFull,
+func (s *server) initialize(_ context.Context, rawParams json.RawMessage) (any, error) {
+	var params initializeParams
+	if json.Unmarshal(rawParams, &params) != nil {
+		return nil, errInvalidParams
+	}
+	rootURIs := []lsp.DocumentURI{params.RootURI}
+	if len(params.WorkspaceFolders) > 0 {
+		rootURIs = rootURIs[:0]
+		for _, folder := range params.WorkspaceFolders {
+			rootURIs = append(rootURIs, folder.URI)
+		}
+	}
+	for _, uri := range rootURIs {
+		if path, ok := pathFromURI(uri); ok {
+			s.roots = append(s.roots, path)
+		}
+	}
+
+	return &initializeResult{
+		Capabilities: serverCapabilities{
+			ServerCapabilities: lsp.ServerCapabilities{
+				TextDocumentSync: &lsp.TextDocumentSyncOptions{
+					OpenClose: true,
+					Change:    lsp.TDSyncKindIncremental,
+				},
+				CompletionProvider: &lsp.CompletionOptions{},
+				HoverProvider:      &lsp.HoverOptions{},
+			},
+			DefinitionProvider:              true,
+			ReferencesProvider:              true,
+			DocumentHighlightProvider:       true,
+			RenameProvider:                  true,
+			DocumentSymbolProvider:          true,
+			WorkspaceSymbolProvider:         true,
+			DocumentFormattingProvider:      true,
+			DocumentRangeFormattingProvider: true,
+			CodeActionProvider:              true,
+			SignatureHelpProvider: &signatureHelpOptions{
+				TriggerCharacters: []string{" "},
+			},
+			SemanticTokensProvider: &semanticTokensOptions{
+				Legend: semanticTokensLegend{
+					TokenTypes:     semanticTokenTypes,
+					TokenModifiers: semanticTokenModifiers,
+				},
+				Range: true,
+				Full:  true,
 			},
-			CompletionProvider: &lsp.CompletionOptions{},
-			HoverProvider:      &lsp.HoverOptions{},
 		},
 	}, nil
 }
@@ -105,10 +177,28 @@ func (s *server) didOpen(ctx context.Context, params lsp.DidOpenTextDocumentPara
 	return nil, nil
 }
 
-func (s *server) didChange(ctx context.Context, params lsp.DidChangeTextDocumentParams) (any, error) {
-	// ContentChanges includes full text since the server is only advertised to
-	// support that; see the initialize method.
-	uri, content := params.TextDocument.URI, params.ContentChanges[0].Text
+func (s *server) didChange(ctx context.Context, params didChangeTextDocumentParams) (any, error) {
+	uri := params.TextDocument.URI
+	document, ok := s.documents[uri]
+	content := document.code
+	for _, change := range params.ContentChanges {
+		if change.Range == nil {
+			content = change.Text
+			ok = true
+			continue
+		}
+		if !ok {
+			return nil, unknownDocument(uri)
+		}
+		// Changes are applied in order, each to the result of the previous
+		// one.
+		from := lspPositionToIdx(content, change.Range.Start)
+		to := lspPositionToIdx(content, change.Range.End)
+		if to < from {
+			return nil, errInvalidParams
+		}
+		content = content[:from] + change.Text + content[to:]
+	}
 	s.updateDocument(conn(ctx), uri, content)
 	return nil, nil
 }
@@ -120,12 +210,27 @@ func (s *server) hover(_ context.Context, params lsp.TextDocumentPositionParams)
 	}
 	pos := lspPositionToIdx(document.code, params.Position)
 
+	// Functions and variables declared in the document shadow builtins.
+	// Modules are handled below, since their names may be aliases of builtin
+	// modules.
+	if sym := symbolAt(document, pos); sym != nil && sym.Kind != eval.NamespaceSymbol {
+		if markdown, ok := userDoc(document, sym); ok {
+			return lsp.Hover{Contents: lsp.MarkupContent{Kind: lsp.MKMarkdown, Value: markdown}}, nil
+		}
+		return nil, nil
+	}
+
 	p := np.Find(document.parseTree.Root, pos)
 	// Try variable doc
 	var primary *parse.Primary
 	if p.Match(np.Store(&primary)) && primary.Type == parse.Variable {
-		// TODO: Take shadowing into consideration.
-		markdown, err := doc.Source("$" + primary.Value)
+		_, name := eval.SplitSigil(primary.Value)
+		qname := "$" + name
+		if strings.HasSuffix(name, eval.FnSuffix) {
+			// Show the doc of the function for $f~.
+			qname = strings.TrimSuffix(name, eval.FnSuffix)
+		}
+		markdown, err := doc.Source(resolveModuleAlias(document, qname))
 		if err == nil {
 			return lsp.Hover{Contents: lsp.MarkupContent{Kind: lsp.MKMarkdown, Value: markdown}}, nil
 		}
@@ -134,8 +239,7 @@ func (s *server) hover(_ context.Context, params lsp.TextDocumentPositionParams)
 	var expr np.SimpleExprData
 	var form *parse.Form
 	if p.Match(np.SimpleExpr(&expr, nil), np.Store(&form)) && form.Head == expr.Compound {
-		// TODO: Take shadowing into consideration.
-		markdown, err := doc.Source(expr.Value)
+		markdown, err := doc.Source(resolveModuleAlias(document, expr.Value))
 		if err == nil {
 			return lsp.Hover{Contents: lsp.MarkupContent{Kind: lsp.MKMarkdown, Value: markdown}}, nil
 		}
@@ -185,20 +289,213 @@ func (s *server) completion(_ context.Context, params lsp.CompletionParams) (any
 	return lspItems, nil
 }
 
+func (s *server) definition(_ context.Context, params lsp.TextDocumentPositionParams) (any, error) {
+	document, sym, err := s.findSymbol(params)
+	if sym == nil {
+		return nil, err
+	}
+	return location{params.TextDocument.URI, lspRangeFromRange(document.code, sym.Decl)}, nil
+}
+
+func (s *server) references(_ context.Context, params referenceParams) (any, error) {
+	document, sym, err := s.findSymbol(params.TextDocumentPositionParams)
+	if sym == nil {
+		return []location{}, err
+	}
+	var ranges []diag.Ranging
+	if params.Context.IncludeDeclaration {
+		ranges = append(ranges, sym.Decl)
+	}
+	ranges = append(ranges, sym.Refs...)
+	locations := make([]location, len(ranges))
+	for i, r := range ranges {
+		locations[i] = location{params.TextDocument.URI, lspRangeFromRange(document.code, r)}
+	}
+	return locations, nil
+}
+
+func (s *server) documentHighlight(_ context.Context, params lsp.TextDocumentPositionParams) (any, error) {
+	document, sym, err := s.findSymbol(params)
+	if sym == nil {
+		return []documentHighlight{}, err
+	}
+	highlights := []documentHighlight{
+		{lspRangeFromRange(document.code, sym.Decl), dhkWrite}}
+	for _, r := range sym.Refs {
+		highlights = append(highlights,
+			documentHighlight{lspRangeFromRange(document.code, r), dhkRead})
+	}
+	return highlights, nil
+}
+
+func (s *server) rename(_ context.Context, params renameParams) (any, error) {
+	document, sym, err := s.findSymbol(params.TextDocumentPositionParams)
+	if sym == nil {
+		return nil, err
+	}
+	if sym.Kind == eval.NamespaceSymbol {
+		return nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.CodeInvalidParams,
+			Message: "cannot rename modules",
+		}
+	}
+	if sym.Inexact {
+		return nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.CodeInvalidParams,
+			Message: "cannot rename names written with escape sequences",
+		}
+	}
+	// Only allow names that can be written as barewords and are unqualified.
+	newName := params.NewName
+	if newName == "" || parse.Quote(newName) != newName || strings.ContainsAny(newName, ":~") {
+		return nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.CodeInvalidParams,
+			Message: fmt.Sprintf("invalid name: %s", parse.Quote(newName)),
+		}
+	}
+	edits := []lsp.TextEdit{
+		{Range: lspRangeFromRange(document.code, sym.Decl), NewText: newName}}
+	for _, r := range sym.Refs {
+		edits = append(edits,
+			lsp.TextEdit{Range: lspRangeFromRange(document.code, r), NewText: newName})
+	}
+	return workspaceEdit{map[lsp.DocumentURI][]lsp.TextEdit{params.TextDocument.URI: edits}}, nil
+}
+
+func (s *server) documentSymbol(_ context.Context, params documentSymbolParams) (any, error) {
+	document, ok := s.documents[params.TextDocument.URI]
+	if !ok {
+		return nil, unknownDocument(params.TextDocument.URI)
+	}
+	symbols := documentSymbols(document.code, document.parseTree.Root, true)
+	if symbols == nil {
+		symbols = []documentSymbol{}
+	}
+	return symbols, nil
+}
+
+func (s *server) workspaceSymbol(_ context.Context, params workspaceSymbolParams) (any, error) {
+	dirs := append(append([]string(nil), s.roots...), s.libDirs...)
+	return s.findWorkspaceSymbols(dirs, params.Query), nil
+}
+
+func (s *server) didChangeWatchedFiles(_ context.Context, params didChangeWatchedFilesParams) (any, error) {
+	for _, change := range params.Changes {
+		if path, ok := pathFromURI(change.URI); ok {
+			delete(s.symbolIndex, path)
+		}
+	}
+	return nil, nil
+}
+
+func (s *server) formatting(_ context.Context, params documentFormattingParams) (any, error) {
+	document, ok := s.documents[params.TextDocument.URI]
+	if !ok {
+		return nil, unknownDocument(params.TextDocument.URI)
+	}
+	return formattingEdits(document, parse.FormatConfig{}), nil
+}
+
+func (s *server) rangeFormatting(_ context.Context, params documentRangeFormattingParams) (any, error) {
+	document, ok := s.documents[params.TextDocument.URI]
+	if !ok {
+		return nil, unknownDocument(params.TextDocument.URI)
+	}
+	rg := diag.Ranging{
+		From: lspPositionToIdx(document.code, params.Range.Start),
+		To:   lspPositionToIdx(document.code, params.Range.End),
+	}
+	return formattingEdits(document, parse.FormatConfig{Range: &rg}), nil
+}
+
+func (s *server) codeAction(_ context.Context, params codeActionParams) (any, error) {
+	document, ok := s.documents[params.TextDocument.URI]
+	if !ok {
+		return nil, unknownDocument(params.TextDocument.URI)
+	}
+	rg := diag.Ranging{
+		From: lspPositionToIdx(document.code, params.Range.Start),
+		To:   lspPositionToIdx(document.code, params.Range.End),
+	}
+	return codeActions(params.TextDocument.URI, document.problems, rg), nil
+}
+
+// Returns the edits that format the document, which is a single edit that
+// replaces the part that differs from the formatted code. No edits are
+// returned if the document has parse errors, since the formatter only works on
+// complete parse trees.
+func formattingEdits(document document, cfg parse.FormatConfig) []lsp.TextEdit {
+	if document.parseErr != nil {
+		return []lsp.TextEdit{}
+	}
+	formatted, err := parse.Format(document.parseTree, cfg)
+	if err != nil || formatted == document.code {
+		return []lsp.TextEdit{}
+	}
+	code := document.code
+	prefix := 0
+	for prefix < len(code) && prefix < len(formatted) && code[prefix] == formatted[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(code)-prefix && suffix < len(formatted)-prefix &&
+		code[len(code)-1-suffix] == formatted[len(formatted)-1-suffix] {
+		suffix++
+	}
+	// Don't split multi-byte codepoints.
+	for prefix > 0 && prefix < len(code) && !utf8.RuneStart(code[prefix]) {
+		prefix--
+	}
+	for suffix > 0 && !utf8.RuneStart(code[len(code)-suffix]) {
+		suffix--
+	}
+	return []lsp.TextEdit{{
+		Range:   lspRangeFromRange(code, diag.Ranging{From: prefix, To: len(code) - suffix}),
+		NewText: formatted[prefix : len(formatted)-suffix],
+	}}
+}
+
+// Finds the symbol that is declared or referenced at the given position. The
+// returned symbol is nil if the document is unknown, in which case the error
+// is non-nil, or if there is no symbol at the position.
+func (s *server) findSymbol(params lsp.TextDocumentPositionParams) (document, *eval.Symbol, error) {
+	document, ok := s.documents[params.TextDocument.URI]
+	if !ok {
+		return document, nil, unknownDocument(params.TextDocument.URI)
+	}
+	return document, symbolAt(document, lspPositionToIdx(document.code, params.Position)), nil
+}
+
+// Returns the symbol declared or referenced at idx, or nil if there is none.
+func symbolAt(document document, idx int) *eval.Symbol {
+	// The end of a range is included, so that a cursor placed right after a
+	// name also finds the name.
+	contains := func(r diag.Ranging) bool { return r.From <= idx && idx <= r.To }
+	for _, sym := range document.symbols {
+		if contains(sym.Decl) {
+			return sym
+		}
+		for _, r := range sym.Refs {
+			if contains(r) {
+				return sym
+			}
+		}
+	}
+	return nil
+}
+
 func (s *server) updateDocument(conn *jsonrpc2.Conn, uri lsp.DocumentURI, code string) {
 	tree, err := parse.Parse(parse.Source{Name: string(uri), Code: code}, parse.Config{})
-	s.documents[uri] = document{code, tree, err}
+	// Compile the document only once for all the information needed.
+	analysis := s.evaler.CheckAll(tree)
+	document := document{code, tree, err, analysis.Symbols, nil,
+		workspaceSymbols(uri, code, tree.Root)}
+	document.problems = s.findProblems(uri, document, analysis)
+	s.documents[uri] = document
 	go func() {
-		// Convert the parse error to lsp.Diagnostic objects and publish them.
-		entries := parse.UnpackErrors(err)
-		diags := make([]lsp.Diagnostic, len(entries))
-		for i, err := range entries {
-			diags[i] = lsp.Diagnostic{
-				Range:    lspRangeFromRange(code, err),
-				Severity: lsp.DSError,
-				Source:   "parse",
-				Message:  err.Message,
-			}
+		diags := make([]lsp.Diagnostic, len(document.problems))
+		for i, p := range document.problems {
+			diags[i] = p.diag
 		}
 		conn.Notify(context.Background(), "textDocument/publishDiagnostics",
 			lsp.PublishDiagnosticsParams{URI: uri, Diagnostics: diags})
@@ -0,0 +1,213 @@
+package lsp
+
+import (
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	lsp "pkg.nimblebun.works/go-lsp"
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/parse/cmpd"
+)
+
+// Returns the symbols declared in chunk: fn, var and use declarations, and if
+// top is true, targets of set. Symbols declared in the body of a fn are its
+// children.
+func documentSymbols(code string, chunk *parse.Chunk, top bool) []documentSymbol {
+	var symbols []documentSymbol
+	for _, pipeline := range chunk.Pipelines {
+		for _, form := range pipeline.Forms {
+			symbols = append(symbols, formSymbols(code, form, top)...)
+		}
+	}
+	return symbols
+}
+
+func formSymbols(code string, form *parse.Form, top bool) []documentSymbol {
+	head, ok := cmpd.StringLiteral(form.Head)
+	if !ok {
+		return nil
+	}
+	formRange := lspRangeFromRange(code, form)
+	switch head {
+	case "fn":
+		if len(form.Args) < 2 {
+			return nil
+		}
+		name, ok := cmpd.StringLiteral(form.Args[0])
+		if !ok {
+			return nil
+		}
+		sym := documentSymbol{
+			Name: name, Kind: skFunction,
+			Range: formRange, SelectionRange: lspRangeFromRange(code, form.Args[0]),
+		}
+		if body, ok := cmpd.Lambda(form.Args[1]); ok && body.Chunk != nil {
+			sym.Detail = strings.TrimSpace(strings.TrimPrefix(
+				code[body.Range().From:body.Chunk.Range().From], "{"))
+			sym.Children = documentSymbols(code, body.Chunk, false)
+		}
+		return []documentSymbol{sym}
+	case "use":
+		if len(form.Args) == 0 {
+			return nil
+		}
+		spec, ok := cmpd.StringLiteral(form.Args[0])
+		if !ok {
+			return nil
+		}
+		nameNode := form.Args[len(form.Args)-1]
+		name := spec[strings.LastIndexByte(spec, '/')+1:]
+		if len(form.Args) > 1 {
+			name, _ = cmpd.StringLiteral(nameNode)
+		}
+		return []documentSymbol{{
+			Name: name, Detail: spec, Kind: skModule,
+			Range: formRange, SelectionRange: lspRangeFromRange(code, nameNode),
+		}}
+	case "var", "set":
+		if head == "set" && !top {
+			return nil
+		}
+		var symbols []documentSymbol
+		for _, arg := range form.Args {
+			if s, _ := cmpd.StringLiteral(arg); s == "=" {
+				break
+			}
+			if len(arg.Indexings) != 1 {
+				continue
+			}
+			_, name := eval.SplitSigil(arg.Indexings[0].Head.Value)
+			symbols = append(symbols, documentSymbol{
+				Name: name, Detail: head, Kind: skVariable,
+				Range: formRange, SelectionRange: lspRangeFromRange(code, arg),
+			})
+		}
+		return symbols
+	}
+	return nil
+}
+
+// Calls f with each symbol in symbols and their descendants, along with the
+// name of the parent symbol.
+func walkSymbols(symbols []documentSymbol, container string, f func(documentSymbol, string)) {
+	for _, sym := range symbols {
+		f(sym, container)
+		walkSymbols(sym.Children, sym.Name, f)
+	}
+}
+
+// Directories that are skipped when indexing workspace symbols, in addition to
+// hidden directories. They usually contain third-party code.
+var skippedDirs = map[string]bool{"node_modules": true, "vendor": true}
+
+// A file in the workspace symbol index.
+type indexedFile struct {
+	modTime time.Time
+	symbols []symbolInformation
+}
+
+// Returns all the symbols in a document, with their containers.
+func workspaceSymbols(uri lsp.DocumentURI, code string, chunk *parse.Chunk) []symbolInformation {
+	var infos []symbolInformation
+	walkSymbols(documentSymbols(code, chunk, true), "", func(sym documentSymbol, container string) {
+		infos = append(infos, symbolInformation{
+			Name: sym.Name, Kind: sym.Kind,
+			Location:      location{uri, sym.SelectionRange},
+			ContainerName: container,
+		})
+	})
+	return infos
+}
+
+// Returns the symbols in all open documents and all .elv files under dirs
+// whose names contain query, ignoring case. Open documents take precedence
+// over the files on disk, since they may have unsaved changes.
+//
+// Files on disk are only parsed again when their modification time changes
+// or the client reports a change to them; the symbols are otherwise kept in
+// s.symbolIndex.
+func (s *server) findWorkspaceSymbols(dirs []string, query string) []symbolInformation {
+	query = strings.ToLower(query)
+	infos := []symbolInformation{}
+	addSymbols := func(symbols []symbolInformation) {
+		for _, sym := range symbols {
+			if strings.Contains(strings.ToLower(sym.Name), query) {
+				infos = append(infos, sym)
+			}
+		}
+	}
+
+	uris := make([]lsp.DocumentURI, 0, len(s.documents))
+	for uri := range s.documents {
+		uris = append(uris, uri)
+	}
+	sort.Slice(uris, func(i, j int) bool { return uris[i] < uris[j] })
+	for _, uri := range uris {
+		addSymbols(s.documents[uri].workspaceSymbols)
+	}
+
+	visited := make(map[string]bool)
+	for _, dir := range dirs {
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				// Skip files and directories that can't be read.
+				return nil
+			}
+			if d.IsDir() {
+				if path != dir && (strings.HasPrefix(d.Name(), ".") || skippedDirs[d.Name()]) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if visited[path] || filepath.Ext(path) != ".elv" {
+				return nil
+			}
+			visited[path] = true
+			uri := uriFromPath(path)
+			if _, open := s.documents[uri]; open {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			file, ok := s.symbolIndex[path]
+			if !ok || !file.modTime.Equal(info.ModTime()) {
+				code, err := os.ReadFile(path)
+				if err != nil {
+					return nil
+				}
+				tree, _ := parse.Parse(parse.Source{Name: string(uri), Code: string(code)}, parse.Config{})
+				file = indexedFile{info.ModTime(), workspaceSymbols(uri, string(code), tree.Root)}
+				s.symbolIndex[path] = file
+			}
+			addSymbols(file.symbols)
+			return nil
+		})
+	}
+	// Drop files that no longer exist or are no longer in the workspace.
+	for path := range s.symbolIndex {
+		if !visited[path] {
+			delete(s.symbolIndex, path)
+		}
+	}
+	return infos
+}
+
+func uriFromPath(path string) lsp.DocumentURI {
+	return lsp.DocumentURI((&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String())
+}
+
+func pathFromURI(uri lsp.DocumentURI) (string, bool) {
+	u, err := url.Parse(string(uri))
+	if err != nil || u.Scheme != "file" {
+		return "", false
+	}
+	return filepath.FromSlash(u.Path), true
+}
@@ -86,6 +86,16 @@ func find(fm *eval.Frame, qs ...string) {
 
 // Source returns the doc source for a symbol.
 func Source(qname string) (string, error) {
+	entry, err := Lookup(qname)
+	if err != nil {
+		return "", err
+	}
+	return entry.FullContent(), nil
+}
+
+// Lookup returns the elvdoc entry for a symbol. Unlike [Source], this also
+// gives access to the signature of functions.
+func Lookup(qname string) (elvdoc.Entry, error) {
 	isVar := strings.HasPrefix(qname, "$")
 	var ns string
 	if strings.ContainsRune(qname, ':') {
@@ -110,7 +120,7 @@ func Source(qname string) (string, error) {
 
 	docs, ok := docsMap()[ns]
 	if !ok {
-		return "", fmt.Errorf("no doc for %s", parse.Quote(qname))
+		return elvdoc.Entry{}, fmt.Errorf("no doc for %s", parse.Quote(qname))
 	}
 	var entries []elvdoc.Entry
 	if isVar {
@@ -120,11 +130,11 @@ func Source(qname string) (string, error) {
 	}
 	for _, entry := range entries {
 		if entry.Name == qname {
-			return entry.FullContent(), nil
+			return entry, nil
 		}
 	}
 
-	return "", fmt.Errorf("no doc for %s", parse.Quote(qname))
+	return elvdoc.Entry{}, fmt.Errorf("no doc for %s", parse.Quote(qname))
 }
 
 func symbols(fm *eval.Frame) error {
@@ -16,6 +16,7 @@ import (
 	readline_binding "src.elv.sh/pkg/mods/readline-binding"
 	"src.elv.sh/pkg/mods/runtime"
 	"src.elv.sh/pkg/mods/str"
+	"src.elv.sh/pkg/mods/time"
 	"src.elv.sh/pkg/mods/unix"
 )
 
@@ -35,6 +36,7 @@ func AddTo(ev *eval.Evaler) {
 	ev.AddModule("doc", doc.Ns)
 	ev.AddModule("os", os.Ns)
 	ev.AddModule("md", md.Ns)
+	ev.AddModule("time", time.Ns)
 	if unix.ExposeUnixNs {
 		ev.AddModule("unix", unix.Ns)
 	}
@@ -5,6 +5,7 @@ import (
 	"io/fs"
 
 	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/mods/time"
 )
 
 var typeNames = map[fs.FileMode]string{
@@ -34,6 +35,6 @@ func statMap(fi fs.FileInfo) vals.Map {
 		"type", typeName,
 		"perm", int(mode&fs.ModePerm),
 		"special-modes", specialModesToList(mode),
+		"mod-time", time.NewTime(fi.ModTime()),
 		"sys", statSysMap(fi.Sys()))
-	// TODO: ModTime
 }
@@ -0,0 +1,355 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/errs"
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/store/storedefs"
+)
+
+type historyOpts struct{ Format string }
+
+func (o *historyOpts) SetDefaultOptions() { o.Format = "jsonl" }
+
+// A codec for a history file format. The read function calls add with each
+// entry, in oldest to newest order; only the Text and Meta fields of the
+// entries are used.
+type historyFormat struct {
+	read  func(r io.Reader, add func(storedefs.Cmd) error) error
+	write func(w io.Writer, cmds []storedefs.Cmd) error
+}
+
+var historyFormats = map[string]historyFormat{
+	"bash":  {readBashHistory, writeBashHistory},
+	"zsh":   {readZshHistory, writeZshHistory},
+	"fish":  {readFishHistory, writeFishHistory},
+	"jsonl": {readJSONLHistory, writeJSONLHistory},
+}
+
+func getHistoryFormat(name string) (historyFormat, error) {
+	format, ok := historyFormats[name]
+	if !ok {
+		return historyFormat{}, errs.BadValue{What: "format",
+			Valid: "bash, zsh, fish or jsonl", Actual: parse.Quote(name)}
+	}
+	return format, nil
+}
+
+func exportHistory(fm *eval.Frame, opts historyOpts, s storedefs.Store) error {
+	format, err := getHistoryFormat(opts.Format)
+	if err != nil {
+		return err
+	}
+	cmds, err := s.CmdsWithSeq(0, -1)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(fm.ByteOutput())
+	err = format.write(w, cmds)
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func importHistory(fm *eval.Frame, opts historyOpts, s storedefs.Store) error {
+	format, err := getHistoryFormat(opts.Format)
+	if err != nil {
+		return err
+	}
+	// Read all the entries first, so that a malformed input imports nothing
+	// and the store is written to in one transaction.
+	var cmds []storedefs.Cmd
+	err = format.read(fm.InputFile(), func(cmd storedefs.Cmd) error {
+		if cmd.Text != "" {
+			cmds = append(cmds, cmd)
+		}
+		return nil
+	})
+	if err != nil || len(cmds) == 0 {
+		return err
+	}
+	return s.AddCmds(cmds)
+}
+
+// Calls f with each line from r, without the line ending.
+func eachLine(r io.Reader, f func(string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<24)
+	for scanner.Scan() {
+		err := f(strings.TrimSuffix(scanner.Text(), "\r"))
+		if err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// Converts a start time in seconds since the Unix epoch to an integer suitable
+// for history files that only support integer timestamps.
+func unixSeconds(t float64) int64 { return int64(math.Floor(t)) }
+
+// Bash history, as written with HISTTIMEFORMAT set. Timestamps are written as
+// comment lines of the form "#<epoch>" before each command. When a file has
+// timestamps, a command extends until the next timestamp line, which is how
+// multi-line commands are stored with the lithist option; otherwise each line
+// is a command.
+
+var bashTimestamp = regexp.MustCompile(`^#([0-9]+)$`)
+
+func readBashHistory(r io.Reader, add func(storedefs.Cmd) error) error {
+	var (
+		lines   []string
+		start   float64
+		hasTime bool
+	)
+	flush := func() error {
+		if len(lines) == 0 {
+			return nil
+		}
+		cmd := storedefs.Cmd{Text: strings.Join(lines, "\n"),
+			Meta: storedefs.CmdMeta{Start: start}}
+		lines = lines[:0]
+		return add(cmd)
+	}
+	err := eachLine(r, func(line string) error {
+		if m := bashTimestamp.FindStringSubmatch(line); m != nil {
+			err := flush()
+			t, _ := strconv.ParseInt(m[1], 10, 64)
+			start, hasTime = float64(t), true
+			return err
+		}
+		lines = append(lines, line)
+		if !hasTime {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return flush()
+}
+
+func writeBashHistory(w io.Writer, cmds []storedefs.Cmd) error {
+	// When a file has any timestamp, every command must be preceded by one,
+	// since a command extends until the next timestamp line. Commands without
+	// a start time get a timestamp of 0, which is read back as no start time.
+	hasTime := false
+	for _, cmd := range cmds {
+		if cmd.Meta.Start != 0 {
+			hasTime = true
+			break
+		}
+	}
+	for _, cmd := range cmds {
+		if hasTime {
+			_, err := fmt.Fprintf(w, "#%d\n", unixSeconds(cmd.Meta.Start))
+			if err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(w, "%s\n", cmd.Text)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Zsh history, with or without the EXTENDED_HISTORY option. Extended entries
+// look like ": <start>:<elapsed>;<command>". Newlines within a command are
+// stored as a backslash at the end of a line. Zsh also "metafies" certain
+// bytes, which is undone when reading and redone when writing.
+
+var zshExtended = regexp.MustCompile(`^: *([0-9]+):([0-9]+);`)
+
+func readZshHistory(r io.Reader, add func(storedefs.Cmd) error) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	var buf strings.Builder
+	return eachLine(bytes.NewReader(unmetafyZsh(data)), func(line string) error {
+		if strings.HasSuffix(line, `\`) {
+			buf.WriteString(line[:len(line)-1])
+			buf.WriteByte('\n')
+			return nil
+		}
+		buf.WriteString(line)
+		entry := buf.String()
+		buf.Reset()
+
+		var meta storedefs.CmdMeta
+		if m := zshExtended.FindStringSubmatchIndex(entry); m != nil {
+			start, _ := strconv.ParseInt(entry[m[2]:m[3]], 10, 64)
+			elapsed, _ := strconv.ParseInt(entry[m[4]:m[5]], 10, 64)
+			meta = storedefs.CmdMeta{Start: float64(start), Duration: float64(elapsed)}
+			entry = entry[m[1]:]
+		}
+		return add(storedefs.Cmd{Text: entry, Meta: meta})
+	})
+}
+
+func writeZshHistory(w io.Writer, cmds []storedefs.Cmd) error {
+	for _, cmd := range cmds {
+		text := strings.ReplaceAll(cmd.Text, "\n", "\\\n")
+		_, err := fmt.Fprintf(w, ": %d:%d;%s\n", unixSeconds(cmd.Meta.Start),
+			int64(cmd.Meta.Duration), metafyZsh(text))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const zshMeta = 0x83
+
+func isZshMeta(b byte) bool { return b == 0 || (zshMeta <= b && b <= 0xa2) }
+
+func unmetafyZsh(data []byte) []byte {
+	if bytes.IndexByte(data, zshMeta) == -1 {
+		return data
+	}
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		if data[i] == zshMeta && i+1 < len(data) {
+			i++
+			out = append(out, data[i]^32)
+		} else {
+			out = append(out, data[i])
+		}
+	}
+	return out
+}
+
+func metafyZsh(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if isZshMeta(s[i]) {
+			sb.WriteByte(zshMeta)
+			sb.WriteByte(s[i] ^ 32)
+		} else {
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String()
+}
+
+// Fish history, which is a subset of YAML:
+//
+//	- cmd: echo foo
+//	  when: 1700000000
+//	  paths:
+//	    - foo
+//
+// In the cmd field, newlines are escaped as \n and backslashes as \\.
+
+func readFishHistory(r io.Reader, add func(storedefs.Cmd) error) error {
+	var (
+		cmd     storedefs.Cmd
+		pending bool
+	)
+	flush := func() error {
+		if !pending {
+			return nil
+		}
+		pending = false
+		return add(cmd)
+	}
+	err := eachLine(r, func(line string) error {
+		if text, ok := strings.CutPrefix(line, "- cmd: "); ok {
+			err := flush()
+			cmd, pending = storedefs.Cmd{Text: unescapeFish(text)}, true
+			return err
+		}
+		if when, ok := strings.CutPrefix(line, "  when: "); ok && pending {
+			t, _ := strconv.ParseInt(strings.TrimSpace(when), 10, 64)
+			cmd.Meta.Start = float64(t)
+		}
+		// Other fields, like paths, are ignored.
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return flush()
+}
+
+func writeFishHistory(w io.Writer, cmds []storedefs.Cmd) error {
+	for _, cmd := range cmds {
+		_, err := fmt.Fprintf(w, "- cmd: %s\n", escapeFish(cmd.Text))
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "  when: %d\n", unixSeconds(cmd.Meta.Start))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	fishEscaper   = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	fishUnescaper = strings.NewReplacer(`\\`, `\`, `\n`, "\n")
+)
+
+func escapeFish(s string) string   { return fishEscaper.Replace(s) }
+func unescapeFish(s string) string { return fishUnescaper.Replace(s) }
+
+// JSON lines, with each line being an object with the field "text" and
+// optionally the metadata fields. This is the only format that preserves all
+// the metadata.
+
+type jsonlEntry struct {
+	Text     string  `json:"text"`
+	Start    float64 `json:"start,omitempty"`
+	Duration float64 `json:"duration,omitempty"`
+	Dir      string  `json:"dir,omitempty"`
+	Status   string  `json:"status,omitempty"`
+	Session  string  `json:"session,omitempty"`
+}
+
+func readJSONLHistory(r io.Reader, add func(storedefs.Cmd) error) error {
+	dec := json.NewDecoder(r)
+	for {
+		var e jsonlEntry
+		err := dec.Decode(&e)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		err = add(storedefs.Cmd{Text: e.Text, Meta: storedefs.CmdMeta{
+			Start: e.Start, Duration: e.Duration,
+			Dir: e.Dir, Status: e.Status, Session: e.Session}})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func writeJSONLHistory(w io.Writer, cmds []storedefs.Cmd) error {
+	enc := json.NewEncoder(w)
+	for _, cmd := range cmds {
+		m := cmd.Meta
+		err := enc.Encode(jsonlEntry{Text: cmd.Text,
+			Start: m.Start, Duration: m.Duration,
+			Dir: m.Dir, Status: m.Status, Session: m.Session})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
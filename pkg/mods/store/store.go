@@ -5,6 +5,14 @@ import (
 	"src.elv.sh/pkg/store/storedefs"
 )
 
+type searchCmdsOpts struct {
+	Mode        string
+	Limit       int
+	NewestFirst bool
+}
+
+func (o *searchCmdsOpts) SetDefaultOptions() { o.Mode = storedefs.SearchSubstr }
+
 func Ns(s storedefs.Store) *eval.Ns {
 	return eval.BuildNsNamed("store").
 		AddGoFns(map[string]any{
@@ -15,6 +23,17 @@ func Ns(s storedefs.Store) *eval.Ns {
 			"cmds":         s.CmdsWithSeq,
 			"next-cmd":     s.NextCmd,
 			"prev-cmd":     s.PrevCmd,
+			"search-cmds": func(opts searchCmdsOpts, pattern string) ([]storedefs.Cmd, error) {
+				return s.SearchCmds(storedefs.CmdQuery{Pattern: pattern,
+					Mode: opts.Mode, Limit: opts.Limit, NewestFirst: opts.NewestFirst})
+			},
+
+			"export-history": func(fm *eval.Frame, opts historyOpts) error {
+				return exportHistory(fm, opts, s)
+			},
+			"import-history": func(fm *eval.Frame, opts historyOpts) error {
+				return importHistory(fm, opts, s)
+			},
 
 			"add-dir": func(dir string) error { return s.AddDir(dir, 1) },
 			"del-dir": s.DelDir,
@@ -23,3 +23,16 @@ func TestTranscripts(t *testing.T) {
 		},
 	)
 }
+
+func TestZshMetafication(t *testing.T) {
+	// The em dash is encoded as E2 80 94, of which 0x94 is metafied by zsh, as
+	// is the NUL byte.
+	s := "echo —\x00"
+	metafied := metafyZsh(s)
+	if want := "echo \xe2\x80\x83\xb4\x83\x20"; metafied != want {
+		t.Errorf("metafyZsh(%q) -> %q, want %q", s, metafied, want)
+	}
+	if unmetafied := string(unmetafyZsh([]byte(metafied))); unmetafied != s {
+		t.Errorf("unmetafyZsh(%q) -> %q, want %q", metafied, unmetafied, s)
+	}
+}
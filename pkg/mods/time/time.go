@@ -0,0 +1,180 @@
+// Package time exposes functionality from Go's time package as an Elvish
+// module.
+package time
+
+import (
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"time"
+
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/errs"
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/eval/vars"
+	"src.elv.sh/pkg/parse"
+)
+
+// Ns is the namespace for the time: module.
+var Ns = eval.BuildNsNamed("time").
+	AddVars(map[string]vars.Var{
+		"rfc3339":      vars.NewReadOnly(time.RFC3339),
+		"rfc3339-nano": vars.NewReadOnly(time.RFC3339Nano),
+		"rfc1123":      vars.NewReadOnly(time.RFC1123),
+		"rfc1123z":     vars.NewReadOnly(time.RFC1123Z),
+		"date-time":    vars.NewReadOnly(time.DateTime),
+		"date-only":    vars.NewReadOnly(time.DateOnly),
+		"time-only":    vars.NewReadOnly(time.TimeOnly),
+		"kitchen":      vars.NewReadOnly(time.Kitchen),
+	}).
+	AddGoFns(map[string]any{
+		"now":       now,
+		"since":     since,
+		"from-unix": fromUnix,
+		"parse":     parseTime,
+		"format":    format,
+		"in-zone":   inZone,
+		"duration":  duration,
+		"add":       add,
+		"sub":       sub,
+	}).Ns()
+
+func now() Time { return NewTime(time.Now()) }
+
+func since(t Time) Duration { return Duration{time.Since(t.t)} }
+
+func fromUnix(sec vals.Num) (Time, error) {
+	switch sec := sec.(type) {
+	case int:
+		return NewTime(time.Unix(int64(sec), 0)), nil
+	case *big.Int:
+		if sec.IsInt64() {
+			return NewTime(time.Unix(sec.Int64(), 0)), nil
+		}
+	case *big.Rat, float64:
+		var f float64
+		if vals.ScanToGo(sec, &f) == nil && math.Abs(f) < math.MaxInt64 {
+			whole := math.Floor(f)
+			return NewTime(time.Unix(int64(whole), int64((f-whole)*1e9))), nil
+		}
+	}
+	return Time{}, errs.OutOfRange{What: "seconds",
+		ValidLow: "-2^63", ValidHigh: "2^63-1", Actual: vals.ToString(sec)}
+}
+
+type parseOpts struct {
+	Layout string
+	Zone   string
+}
+
+func (o *parseOpts) SetDefaultOptions() {
+	o.Layout = time.RFC3339
+	o.Zone = "Local"
+}
+
+func parseTime(opts parseOpts, s string) (Time, error) {
+	loc, err := loadZone(opts.Zone)
+	if err != nil {
+		return Time{}, err
+	}
+	t, err := time.ParseInLocation(opts.Layout, s, loc)
+	if err != nil {
+		return Time{}, err
+	}
+	return NewTime(t), nil
+}
+
+type formatOpts struct{ Layout string }
+
+func (o *formatOpts) SetDefaultOptions() { o.Layout = time.RFC3339 }
+
+func format(opts formatOpts, t Time) string { return t.t.Format(opts.Layout) }
+
+func inZone(zone string, t Time) (Time, error) {
+	loc, err := loadZone(zone)
+	if err != nil {
+		return Time{}, err
+	}
+	return Time{t.t.In(loc)}, nil
+}
+
+var offsetPattern = regexp.MustCompile(`^([+-])(\d\d):(\d\d)$`)
+
+// Loads a time zone from an IANA name like "Europe/London", "UTC", "Local", or
+// a UTC offset like "+08:00".
+func loadZone(name string) (*time.Location, error) {
+	if m := offsetPattern.FindStringSubmatch(name); m != nil {
+		hours, _ := strconv.Atoi(m[2])
+		minutes, _ := strconv.Atoi(m[3])
+		offset := hours*3600 + minutes*60
+		if m[1] == "-" {
+			offset = -offset
+		}
+		return time.FixedZone(name, offset), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "" {
+		return nil, errs.BadValue{What: "zone",
+			Valid: "a time zone name or UTC offset", Actual: parse.Quote(name)}
+	}
+	return loc, nil
+}
+
+func duration(v any) (Duration, error) {
+	if d, ok := v.(Duration); ok {
+		return d, nil
+	}
+	if s, ok := v.(string); ok {
+		if d, err := time.ParseDuration(s); err == nil {
+			return Duration{d}, nil
+		}
+	}
+	// Also handles strings that are numbers.
+	var f float64
+	if vals.ScanToGo(v, &f) == nil &&
+		!math.IsNaN(f) && math.Abs(f*float64(time.Second)) <= math.MaxInt64 {
+		return Duration{time.Duration(f * float64(time.Second))}, nil
+	}
+	return Duration{}, errs.BadValue{What: "duration",
+		Valid: "a number of seconds or a duration string", Actual: vals.ReprPlain(v)}
+}
+
+func add(a, b any) (any, error) {
+	switch a := a.(type) {
+	case Time:
+		if b, ok := b.(Duration); ok {
+			return Time{a.t.Add(b.d)}, nil
+		}
+	case Duration:
+		switch b := b.(type) {
+		case Time:
+			return Time{b.t.Add(a.d)}, nil
+		case Duration:
+			return Duration{a.d + b.d}, nil
+		}
+	}
+	return nil, badOperands(a, b, "a time and a duration, or two durations")
+}
+
+func sub(a, b any) (any, error) {
+	switch a := a.(type) {
+	case Time:
+		switch b := b.(type) {
+		case Time:
+			return Duration{a.t.Sub(b.t)}, nil
+		case Duration:
+			return Time{a.t.Add(-b.d)}, nil
+		}
+	case Duration:
+		if b, ok := b.(Duration); ok {
+			return Duration{a.d - b.d}, nil
+		}
+	}
+	return nil, badOperands(a, b, "two times, a time and a duration, or two durations")
+}
+
+func badOperands(a, b any, valid string) error {
+	return errs.BadValue{What: "operands", Valid: valid,
+		Actual: vals.Kind(a) + " and " + vals.Kind(b)}
+}
@@ -0,0 +1,158 @@
+package time
+
+import (
+	"cmp"
+	"encoding/json"
+	"time"
+
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/persistent/hash"
+)
+
+// Time is an Elvish value representing an instant, along with the time zone
+// used to show it.
+type Time struct{ t time.Time }
+
+var (
+	_ vals.Comparer  = Time{}
+	_ vals.PseudoMap = Time{}
+)
+
+// NewTime creates a Time from a time.Time. The monotonic clock reading is
+// stripped, so that equal Time values always have the same representation.
+func NewTime(t time.Time) Time { return Time{t.Round(0)} }
+
+// Go returns the underlying time.Time.
+func (t Time) Go() time.Time { return t.t }
+
+// Kind returns "time".
+func (Time) Kind() string { return "time" }
+
+// Repr returns an expression that evaluates to a Time of the same instant and
+// UTC offset.
+func (t Time) Repr(int) string {
+	return "(time:parse " + parse.Quote(t.String()) + ")"
+}
+
+// String formats the time using the RFC 3339 layout, with fractional seconds
+// if they are not zero.
+func (t Time) String() string { return t.t.Format(time.RFC3339Nano) }
+
+// Equal returns whether other is a Time of the same instant, regardless of
+// the time zone.
+func (t Time) Equal(other any) bool {
+	o, ok := other.(Time)
+	return ok && t.t.Equal(o.t)
+}
+
+// Hash returns the hash of the instant.
+func (t Time) Hash() uint32 { return hash.UInt64(uint64(t.t.UnixNano())) }
+
+// Compare orders Time values chronologically.
+func (t Time) Compare(other any) vals.Ordering {
+	if o, ok := other.(Time); ok {
+		return ordering(t.t.Compare(o.t))
+	}
+	return vals.CmpUncomparable
+}
+
+// MarshalJSON encodes the time as a JSON string in the same format as String.
+func (t Time) MarshalJSON() ([]byte, error) { return json.Marshal(t.String()) }
+
+// MarshalText encodes the time in the same format as String. It is used by
+// to-yaml and to-toml.
+func (t Time) MarshalText() ([]byte, error) { return []byte(t.String()), nil }
+
+func (t Time) Fields() vals.MethodMap { return timeFields{t.t} }
+
+type timeFields struct{ t time.Time }
+
+func (f timeFields) Year() int          { return f.t.Year() }
+func (f timeFields) Month() int         { return int(f.t.Month()) }
+func (f timeFields) Day() int           { return f.t.Day() }
+func (f timeFields) Hour() int          { return f.t.Hour() }
+func (f timeFields) Minute() int        { return f.t.Minute() }
+func (f timeFields) Second() int        { return f.t.Second() }
+func (f timeFields) Nanosecond() int    { return f.t.Nanosecond() }
+func (f timeFields) Weekday() string    { return f.t.Weekday().String() }
+func (f timeFields) YearDay() int       { return f.t.YearDay() }
+func (f timeFields) Unix() vals.Num     { return vals.Int64ToNum(f.t.Unix()) }
+func (f timeFields) UnixNano() vals.Num { return vals.Int64ToNum(f.t.UnixNano()) }
+
+func (f timeFields) Zone() string {
+	name, _ := f.t.Zone()
+	return name
+}
+
+func (f timeFields) Offset() int {
+	_, offset := f.t.Zone()
+	return offset
+}
+
+// Duration is an Elvish value representing the elapsed time between two
+// instants.
+type Duration struct{ d time.Duration }
+
+var (
+	_ vals.Comparer  = Duration{}
+	_ vals.PseudoMap = Duration{}
+)
+
+// NewDuration creates a Duration from a time.Duration.
+func NewDuration(d time.Duration) Duration { return Duration{d} }
+
+// Go returns the underlying time.Duration.
+func (d Duration) Go() time.Duration { return d.d }
+
+// Kind returns "duration".
+func (Duration) Kind() string { return "duration" }
+
+// Repr returns an expression that evaluates to the same Duration.
+func (d Duration) Repr(int) string {
+	return "(time:duration " + parse.Quote(d.String()) + ")"
+}
+
+// String formats the duration like "1h2m3.5s".
+func (d Duration) String() string { return d.d.String() }
+
+// Equal returns whether other is a Duration of the same length.
+func (d Duration) Equal(other any) bool { return other == d }
+
+// Hash returns the hash of the length of the duration.
+func (d Duration) Hash() uint32 { return hash.UInt64(uint64(d.d)) }
+
+// Compare orders Duration values by their lengths.
+func (d Duration) Compare(other any) vals.Ordering {
+	if o, ok := other.(Duration); ok {
+		return ordering(cmp.Compare(d.d, o.d))
+	}
+	return vals.CmpUncomparable
+}
+
+// MarshalJSON encodes the duration as a JSON number of seconds.
+func (d Duration) MarshalJSON() ([]byte, error) { return json.Marshal(d.d.Seconds()) }
+
+// MarshalText encodes the duration in the same format as String, which is
+// understood by time:duration. It is used by to-yaml and to-toml.
+func (d Duration) MarshalText() ([]byte, error) { return []byte(d.String()), nil }
+
+func (d Duration) Fields() vals.MethodMap { return durationFields{d.d} }
+
+type durationFields struct{ d time.Duration }
+
+func (f durationFields) Hours() float64        { return f.d.Hours() }
+func (f durationFields) Minutes() float64      { return f.d.Minutes() }
+func (f durationFields) Seconds() float64      { return f.d.Seconds() }
+func (f durationFields) Nanoseconds() vals.Num { return vals.Int64ToNum(f.d.Nanoseconds()) }
+
+func ordering(c int) vals.Ordering {
+	switch {
+	case c < 0:
+		return vals.CmpLess
+	case c > 0:
+		return vals.CmpMore
+	default:
+		return vals.CmpEqual
+	}
+}
@@ -0,0 +1,697 @@
+package parse
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+
+	"src.elv.sh/pkg/diag"
+)
+
+// This file implements a formatter of Elvish code. The formatter works on the
+// parse tree and decides the whitespace between nodes, keeping comments and
+// the line breaks that are meaningful to the author:
+//
+//   - Indentation is two spaces per level of lambdas, output captures, lists
+//     and maps that span multiple lines.
+//
+//   - Within a line, nodes are separated by exactly one space. This includes
+//     pipes and redirections, which are written like "a | b" and "a > file".
+//
+//   - Line breaks between pipelines and between elements of lists and maps
+//     are kept, with at most one blank line. Line breaks elsewhere are
+//     removed, except for line continuations within forms.
+//
+//   - A pipeline is broken after every "|" if it was broken after any "|" in
+//     the source or would be longer than formatWidth.
+//
+//   - Map pairs that are each on their own line and on consecutive lines have
+//     their values aligned.
+
+// FormatConfig keeps configuration options when formatting.
+type FormatConfig struct {
+	// If not nil, only format top-level pipelines that overlap with this
+	// range, keeping the rest of the code as is.
+	Range *diag.Ranging
+}
+
+const (
+	formatIndent = "  "
+	formatWidth  = 80
+)
+
+var errFormatChangesAST = errors.New("formatting would change more than whitespace; this is a bug")
+
+// Format returns the source code of the tree in canonical format. The tree
+// must have been parsed without errors.
+func Format(tree Tree, cfg FormatConfig) (string, error) {
+	f := &formatter{src: tree.Source.Code, rng: cfg.Range, lineStart: true}
+	f.chunk(tree.Root, tree.Root.From, topChunk)
+	if len(f.buf) > 0 && (f.rng == nil || !f.lastVerbatim) {
+		f.breakLine(false)
+	}
+	formatted := string(f.buf)
+
+	// Guard against bugs in the formatter.
+	newTree, err := Parse(Source{Name: tree.Source.Name, Code: formatted}, Config{})
+	if err != nil || !sameAST(tree.Root, newTree.Root) ||
+		!slices.Equal(comments(tree.Root), comments(newTree.Root)) {
+		return "", errFormatChangesAST
+	}
+	return formatted, nil
+}
+
+type formatter struct {
+	src string
+	rng *diag.Ranging
+	buf []byte
+	// Level of indentation.
+	indent int
+	// Whether nothing has been written on the current line.
+	lineStart bool
+	// Whether the current line ends with a comment, so the next write must
+	// start a new line.
+	needNewline bool
+	// Whether the last top-level pipeline was written as is.
+	lastVerbatim bool
+}
+
+// Writes s, indenting it if it starts a line.
+func (f *formatter) write(s string) {
+	if s == "" {
+		return
+	}
+	if f.needNewline {
+		f.newline()
+	}
+	if f.lineStart {
+		f.buf = append(f.buf, strings.Repeat(formatIndent, f.indent)...)
+		f.lineStart = false
+	}
+	f.buf = append(f.buf, s...)
+}
+
+// Writes s as is.
+func (f *formatter) raw(s string) {
+	if s == "" {
+		return
+	}
+	f.buf = append(f.buf, s...)
+	f.lineStart = strings.HasSuffix(s, "\n")
+	f.needNewline = false
+}
+
+func (f *formatter) space() {
+	if !f.lineStart && !f.needNewline {
+		f.write(" ")
+	}
+}
+
+func (f *formatter) newline() {
+	f.buf = append(trimRightSpaces(f.buf), '\n')
+	f.lineStart = true
+	f.needNewline = false
+}
+
+// Starts a new line unless already at the start of one, and also writes a
+// blank line if blank is true.
+func (f *formatter) breakLine(blank bool) {
+	if !f.lineStart {
+		f.newline()
+	}
+	if blank && len(f.buf) > 0 && !strings.HasSuffix(string(f.buf), "\n\n") {
+		f.newline()
+	}
+}
+
+func (f *formatter) comment(text string) {
+	f.space()
+	f.write(text)
+	f.needNewline = true
+}
+
+func trimRightSpaces(b []byte) []byte {
+	for len(b) > 0 && (b[len(b)-1] == ' ' || b[len(b)-1] == '\t') {
+		b = b[:len(b)-1]
+	}
+	return b
+}
+
+// Gaps between nodes. All the text in a gap is whitespace, punctuation or
+// comments; only the comments and line breaks are relevant to the formatter.
+
+type gapItemType int
+
+const (
+	gapNewline gapItemType = iota
+	gapComment
+	// A "^" followed by a newline.
+	gapContinuation
+)
+
+type gapItem struct {
+	typ  gapItemType
+	text string
+}
+
+func (f *formatter) scanGap(from, to int) []gapItem {
+	var items []gapItem
+	s := f.src[from:to]
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '#':
+			j := strings.IndexAny(s[i:], "\r\n")
+			if j == -1 {
+				j = len(s) - i
+			}
+			items = append(items, gapItem{gapComment, strings.TrimRight(s[i:i+j], " \t")})
+			i += j - 1
+		case '\r':
+			if i+1 < len(s) && s[i+1] == '\n' {
+				i++
+			}
+			items = append(items, gapItem{gapNewline, ""})
+		case '\n':
+			items = append(items, gapItem{gapNewline, ""})
+		case '^':
+			if i+1 < len(s) && s[i+1] == '\r' {
+				i++
+			}
+			if i+1 < len(s) && s[i+1] == '\n' {
+				i++
+			}
+			items = append(items, gapItem{gapContinuation, ""})
+		}
+	}
+	return items
+}
+
+func hasGapItem(items []gapItem, typ gapItemType) bool {
+	for _, item := range items {
+		if item.typ == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func countNewlines(items []gapItem) int {
+	n := 0
+	for _, item := range items {
+		if item.typ == gapNewline {
+			n++
+		}
+	}
+	return n
+}
+
+// Writes the comments and line breaks in a gap. The before and after
+// arguments tell whether there is a node before and after the gap within the
+// same block; blank lines are only kept between nodes and comments, with at
+// most one blank line in a row. Returns whether the next write will start a
+// new line.
+func (f *formatter) gap(items []gapItem, before, after bool) bool {
+	newlines := 0
+	for _, item := range items {
+		switch item.typ {
+		case gapNewline:
+			newlines++
+		case gapComment:
+			if newlines > 0 {
+				f.breakLine(before && newlines > 1)
+			}
+			f.comment(item.text)
+			newlines = 0
+			before = true
+		}
+	}
+	if newlines > 0 {
+		f.breakLine(before && after && newlines > 1)
+	}
+	return f.lineStart || f.needNewline
+}
+
+// Returns whether the chunk starting at from should be written on multiple
+// lines, which is the case when it has any line break or comment between its
+// pipelines.
+func (f *formatter) chunkIsMultiline(n *Chunk, from int) bool {
+	prevEnd := from
+	check := func(to int) bool {
+		items := f.scanGap(prevEnd, to)
+		return hasGapItem(items, gapNewline) || hasGapItem(items, gapComment)
+	}
+	for _, p := range n.Pipelines {
+		if check(p.From) {
+			return true
+		}
+		prevEnd = p.To
+	}
+	if len(n.Pipelines) == 0 {
+		return hasGapItem(f.scanGap(prevEnd, n.To), gapComment)
+	}
+	return check(n.To)
+}
+
+func (f *formatter) verbatim(p *Pipeline) bool {
+	return f.rng != nil && (p.To < f.rng.From || f.rng.To < p.From)
+}
+
+type chunkMode int
+
+const (
+	// The root of the tree.
+	topChunk chunkMode = iota
+	// A chunk that continues the current line.
+	inlineChunk
+	// A chunk that starts on a new line.
+	blockChunk
+)
+
+// Writes a chunk, including the gap between from and its first pipeline.
+func (f *formatter) chunk(n *Chunk, from int, mode chunkMode) {
+	top := mode == topChunk
+	prevEnd := from
+	for i, p := range n.Pipelines {
+		verbatim := top && f.verbatim(p)
+		switch {
+		case verbatim && (i == 0 || f.lastVerbatim):
+			f.raw(f.src[prevEnd:p.From])
+		case i == 0:
+			f.gap(f.scanGap(prevEnd, p.From), false, true)
+			if mode == blockChunk {
+				f.breakLine(false)
+			}
+		default:
+			if !f.gap(f.scanGap(prevEnd, p.From), true, true) {
+				f.write(";")
+				f.space()
+			}
+		}
+		if verbatim {
+			if f.needNewline {
+				f.newline()
+			}
+			f.raw(f.src[p.From:p.To])
+		} else {
+			f.pipeline(p)
+		}
+		if top {
+			f.lastVerbatim = verbatim
+		}
+		prevEnd = p.To
+	}
+	if top && f.lastVerbatim {
+		f.raw(f.src[prevEnd:n.To])
+	} else {
+		f.gap(f.scanGap(prevEnd, n.To), len(n.Pipelines) > 0, false)
+	}
+}
+
+func (f *formatter) pipeline(p *Pipeline) {
+	breakAll := false
+	if len(p.Forms) > 1 {
+		width := len(formatIndent) * f.indent
+		multiline := false
+		for i, form := range p.Forms {
+			if i > 0 {
+				width += len(" | ")
+				if hasGapItem(f.scanGap(p.Forms[i-1].To, form.From), gapNewline) {
+					breakAll = true
+				}
+			}
+			text := SourceText(form)
+			multiline = multiline || strings.ContainsAny(text, "\r\n")
+			width += len(strings.Join(strings.Fields(text), " "))
+		}
+		breakAll = breakAll || (!multiline && width > formatWidth)
+	}
+
+	indented := false
+	for i, form := range p.Forms {
+		if i > 0 {
+			f.space()
+			f.write("|")
+			if f.gap(f.scanGap(p.Forms[i-1].To, form.From), false, false) || breakAll {
+				f.breakLine(false)
+				if !indented {
+					f.indent++
+					indented = true
+				}
+			} else {
+				f.space()
+			}
+		}
+		f.form(form)
+	}
+	if p.Background {
+		f.space()
+		f.write("&")
+	}
+	f.gap(f.scanGap(p.Forms[len(p.Forms)-1].To, p.To), false, false)
+	if indented {
+		f.indent--
+	}
+}
+
+func (f *formatter) form(n *Form) {
+	f.compound(n.Head)
+	indented := false
+	prevEnd := n.Head.To
+	for _, child := range Children(n) {
+		if _, isSep := child.(*Sep); isSep || child == Node(n.Head) {
+			continue
+		}
+		if hasGapItem(f.scanGap(prevEnd, child.Range().From), gapContinuation) {
+			f.space()
+			f.write("^")
+			f.breakLine(false)
+			if !indented {
+				f.indent++
+				indented = true
+			}
+		} else {
+			f.space()
+		}
+		switch child := child.(type) {
+		case *Compound:
+			f.compound(child)
+		case *MapPair:
+			f.mapPair(child, 0)
+		case *Redir:
+			f.redir(child)
+		}
+		prevEnd = child.Range().To
+	}
+	f.gap(f.scanGap(prevEnd, n.To), false, false)
+	if indented {
+		f.indent--
+	}
+}
+
+var redirSigns = map[RedirMode]string{
+	Read: "<", Write: ">", ReadWrite: "<>", Append: ">>",
+}
+
+func (f *formatter) redir(n *Redir) {
+	if n.Left != nil {
+		f.compound(n.Left)
+	}
+	f.write(redirSigns[n.Mode])
+	if n.RightIsFd {
+		f.write("&")
+	} else {
+		f.write(" ")
+	}
+	f.compound(n.Right)
+}
+
+func (f *formatter) compound(n *Compound) {
+	for _, in := range n.Indexings {
+		f.primary(in.Head)
+		for _, index := range in.Indices {
+			f.write("[")
+			f.array(index)
+			f.write("]")
+		}
+	}
+}
+
+func (f *formatter) array(n *Array) {
+	text := SourceText(n)
+	if len(n.Semicolons) > 0 || strings.ContainsAny(text, "#\r\n^") {
+		f.write(text)
+		return
+	}
+	for i, c := range n.Compounds {
+		if i > 0 {
+			f.write(" ")
+		}
+		f.compound(c)
+	}
+}
+
+func (f *formatter) primary(n *Primary) {
+	switch n.Type {
+	case OutputCapture:
+		f.capture(n, "(")
+	case ExceptionCapture:
+		f.capture(n, "?(")
+	case List:
+		f.seq(n, "[", n.From+1, n.To-1, "]")
+	case Map:
+		if len(n.MapPairs) == 0 {
+			f.write("[&]")
+		} else {
+			f.seq(n, "[", n.From+1, n.To-1, "]")
+		}
+	case Lambda:
+		f.lambda(n)
+	case Braced:
+		// Elements may be separated by either commas or whitespace; keep the
+		// choice of the author.
+		f.write("{")
+		for i, c := range n.Braced {
+			if i > 0 {
+				if strings.Contains(f.src[n.Braced[i-1].To:c.From], ",") {
+					f.write(",")
+				} else {
+					f.write(" ")
+				}
+			}
+			f.compound(c)
+		}
+		f.write("}")
+	default:
+		f.write(SourceText(n))
+	}
+}
+
+func (f *formatter) capture(n *Primary, open string) {
+	f.write(open)
+	if f.chunkIsMultiline(n.Chunk, n.Chunk.From) {
+		// Keep the closing parenthesis on the last line of the chunk unless
+		// it was on its own line.
+		trailingFrom := n.Chunk.From
+		if len(n.Chunk.Pipelines) > 0 {
+			trailingFrom = n.Chunk.Pipelines[len(n.Chunk.Pipelines)-1].To
+		}
+		trailing := f.scanGap(trailingFrom, n.Chunk.To)
+		f.block(n.Chunk, n.Chunk.From, hasGapItem(trailing, gapNewline))
+	} else {
+		f.chunk(n.Chunk, n.Chunk.From, inlineChunk)
+	}
+	f.write(")")
+}
+
+func (f *formatter) lambda(n *Primary) {
+	f.write("{")
+	// Find the two "|" around the signature, if there is one.
+	var pipes []Node
+	for _, child := range Children(n) {
+		if _, isSep := child.(*Sep); isSep && SourceText(child) == "|" {
+			pipes = append(pipes, child)
+		}
+	}
+	// Spaces and comments before the body are parsed as part of the lambda
+	// rather than the chunk when there is no signature.
+	bodyFrom := n.From + 1
+	if len(pipes) == 2 {
+		f.seq(n, "|", pipes[0].Range().To, pipes[1].Range().From, "|")
+		bodyFrom = pipes[1].Range().To
+	}
+	if f.chunkIsMultiline(n.Chunk, bodyFrom) {
+		f.block(n.Chunk, bodyFrom, true)
+	} else {
+		f.space()
+		if len(n.Chunk.Pipelines) > 0 {
+			f.chunk(n.Chunk, bodyFrom, inlineChunk)
+			f.space()
+		}
+	}
+	f.write("}")
+}
+
+// Writes an indented chunk starting on a new line. If closeOnOwnLine is true,
+// also starts a new line after the chunk.
+func (f *formatter) block(n *Chunk, from int, closeOnOwnLine bool) {
+	f.indent++
+	f.chunk(n, from, blockChunk)
+	f.indent--
+	if closeOnOwnLine {
+		f.breakLine(false)
+	}
+}
+
+// Writes the elements and map pairs of a list, map or lambda signature.
+func (f *formatter) seq(n *Primary, open string, from, to int, close string) {
+	var items []Node
+	for _, child := range Children(n) {
+		switch child.(type) {
+		case *Compound, *MapPair:
+			if child.Range().From >= from && child.Range().To <= to {
+				items = append(items, child)
+			}
+		}
+	}
+	// Gaps before each item and after the last one.
+	gaps := make([][]gapItem, len(items)+1)
+	prevEnd := from
+	for i, item := range items {
+		gaps[i] = f.scanGap(prevEnd, item.Range().From)
+		prevEnd = item.Range().To
+	}
+	gaps[len(items)] = f.scanGap(prevEnd, to)
+	keyWidths := alignedKeyWidths(items, gaps)
+	// Only indent the items when they are on their own lines, so that a
+	// multi-line lambda in a single-line list is not indented twice.
+	multiline := false
+	for _, gap := range gaps {
+		multiline = multiline || hasGapItem(gap, gapNewline)
+	}
+
+	f.write(open)
+	if multiline {
+		f.indent++
+	}
+	for i, item := range items {
+		if !f.gap(gaps[i], i > 0, true) && i > 0 {
+			f.space()
+		}
+		switch item := item.(type) {
+		case *Compound:
+			f.compound(item)
+		case *MapPair:
+			f.mapPair(item, keyWidths[i])
+		}
+	}
+	f.gap(gaps[len(items)], len(items) > 0, false)
+	if multiline {
+		f.indent--
+	}
+	f.write(close)
+}
+
+// Returns the width to pad the key part of each map pair in items to, given
+// the gaps before each item and after the last one. Map pairs on their own
+// lines are padded, so that there is at least one space after the "=" and the
+// values of such map pairs on consecutive lines are aligned. The width is 0 for
+// map pairs that are not padded.
+func alignedKeyWidths(items []Node, gaps [][]gapItem) []int {
+	widths := make([]int, len(items))
+	// Whether each item can be aligned with its neighbors.
+	alignable := make([]bool, len(items))
+	for i, item := range items {
+		pair, ok := item.(*MapPair)
+		if !ok || pair.Value == nil ||
+			countNewlines(gaps[i]) == 0 || countNewlines(gaps[i+1]) == 0 {
+			continue
+		}
+		key := SourceText(pair.Key)
+		if strings.ContainsAny(key, "\r\n") {
+			continue
+		}
+		widths[i] = len(key)
+		alignable[i] = !strings.ContainsAny(SourceText(pair.Value), "\r\n")
+	}
+	for i := 0; i < len(items); {
+		j := i + 1
+		if alignable[i] {
+			// Extend the group to alignable map pairs on the following lines.
+			for j < len(items) && alignable[j] &&
+				countNewlines(gaps[j]) == 1 && !hasGapItem(gaps[j], gapComment) {
+				j++
+			}
+		}
+		max := 0
+		for k := i; k < j; k++ {
+			if widths[k] > max {
+				max = widths[k]
+			}
+		}
+		for k := i; k < j; k++ {
+			widths[k] = max
+		}
+		i = j
+	}
+	return widths
+}
+
+// Writes a map pair, padding the key to keyWidth if it is not 0.
+func (f *formatter) mapPair(n *MapPair, keyWidth int) {
+	f.write("&")
+	f.compound(n.Key)
+	if n.Value == nil {
+		return
+	}
+	f.write("=")
+	if keyWidth > 0 {
+		f.write(strings.Repeat(" ", keyWidth-len(SourceText(n.Key))+1))
+	}
+	f.compound(n.Value)
+}
+
+// Reports whether two trees have the same AST, disregarding whitespace and
+// comments.
+func sameAST(a, b Node) bool {
+	switch a := a.(type) {
+	case *Pipeline:
+		if a.Background != b.(*Pipeline).Background {
+			return false
+		}
+	case *Redir:
+		b := b.(*Redir)
+		if a.Mode != b.Mode || a.RightIsFd != b.RightIsFd {
+			return false
+		}
+	case *Primary:
+		b, ok := b.(*Primary)
+		if !ok || a.Type != b.Type || a.Value != b.Value {
+			return false
+		}
+	}
+	ca, cb := nonSepChildren(a), nonSepChildren(b)
+	if len(ca) != len(cb) {
+		return false
+	}
+	for i := range ca {
+		if !sameNodeType(ca[i], cb[i]) || !sameAST(ca[i], cb[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func nonSepChildren(n Node) []Node {
+	var children []Node
+	for _, child := range Children(n) {
+		if _, isSep := child.(*Sep); !isSep {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+func sameNodeType(a, b Node) bool {
+	return reflect.TypeOf(a) == reflect.TypeOf(b)
+}
+
+// Returns the text of all the comments in a tree, which are always within Sep
+// nodes.
+func comments(n Node) []string {
+	if _, isSep := n.(*Sep); isSep {
+		var texts []string
+		f := &formatter{src: SourceText(n)}
+		for _, item := range f.scanGap(0, len(f.src)) {
+			if item.typ == gapComment {
+				texts = append(texts, item.text)
+			}
+		}
+		return texts
+	}
+	var texts []string
+	for _, child := range Children(n) {
+		texts = append(texts, comments(child)...)
+	}
+	return texts
+}
@@ -0,0 +1,117 @@
+package parse
+
+import (
+	"strings"
+	"testing"
+
+	"src.elv.sh/pkg/diag"
+	"src.elv.sh/pkg/tt"
+)
+
+func TestFormat(t *testing.T) {
+	tt.Test(t, tt.Fn(format).ArgsFmt("(%q)"),
+		// Spacing within forms, around pipes and around redirections.
+		Args("echo  a   b").Rets("echo a b\n"),
+		Args("a|b  |c").Rets("a | b | c\n"),
+		Args("echo a >out 2>&1 <in").Rets("echo a > out 2>&1 < in\n"),
+		Args("echo a >>out").Rets("echo a >> out\n"),
+		Args("sleep 1&").Rets("sleep 1 &\n"),
+
+		// Compound expressions and indexing are kept as is.
+		Args("echo a$x'b'[0] {a,b} {a b}").Rets("echo a$x'b'[0] {a,b} {a b}\n"),
+
+		// Lists, maps and captures.
+		Args("put [ a  b ] [&k=v  &k2= v2] [ & ]").Rets("put [a b] [&k=v &k2=v2] [&]\n"),
+		Args("put ( echo  a ) ?( fail x )").Rets("put (echo a) ?(fail x)\n"),
+
+		// Lambdas.
+		Args("each {|x|put $x}").Rets("each {|x| put $x }\n"),
+		Args("f { }").Rets("f { }\n"),
+		Args("fn f { |a b &o=x|}").Rets("fn f {|a b &o=x| }\n"),
+		Args("if a {\necho 1\n    echo 2\n} else {\n echo 3\n}").
+			Rets("if a {\n  echo 1\n  echo 2\n} else {\n  echo 3\n}\n"),
+		Args("f {\n  g {\n  echo\n  }\n}").Rets("f {\n  g {\n    echo\n  }\n}\n"),
+
+		// Multi-line lists and maps, with values of map pairs on consecutive
+		// lines aligned.
+		Args("var m = [\n&a= 1\n&long-key=2\n\n&c=(echo\n)\n]").
+			Rets("var m = [\n  &a=        1\n  &long-key= 2\n\n  &c= (\n    echo\n  )\n]\n"),
+		Args("var l = [\na\n  b\n]").Rets("var l = [\n  a\n  b\n]\n"),
+		// A multi-line lambda in a single-line list is only indented once.
+		Args("var l = [$@l {\necho\n}]").Rets("var l = [$@l {\n  echo\n}]\n"),
+
+		// Multi-line captures keep the closing parenthesis in place.
+		Args("var x = (\necho a\necho b)").Rets("var x = (\n  echo a\n  echo b)\n"),
+		Args("var x = (\necho a\n)").Rets("var x = (\n  echo a\n)\n"),
+
+		// Comments and blank lines.
+		Args("# c1\n\n\n\necho a # c2\n\n# c3\necho b").
+			Rets("# c1\n\necho a # c2\n\n# c3\necho b\n"),
+		Args("f { # c1\n# c2\necho\n}").Rets("f { # c1\n  # c2\n  echo\n}\n"),
+		Args("f {\n\n  # c1\n  echo\n\n}").Rets("f {\n  # c1\n  echo\n}\n"),
+		Args("var m = [\n# c1\n&a=1 # c2\n]").Rets("var m = [\n  # c1\n  &a= 1 # c2\n]\n"),
+
+		// Line continuations are kept and indented.
+		Args("echo a ^\nb ^\n        c").Rets("echo a ^\n  b ^\n  c\n"),
+
+		// Pipelines are broken after every "|" if they are broken after any.
+		Args("a | b |\nc").Rets("a |\n  b |\n  c\n"),
+		Args("f {\na |\nb\n}").Rets("f {\n  a |\n    b\n}\n"),
+		// Pipelines that are too long are also broken.
+		Args("echo "+strings.Repeat("a", 40)+" | "+"echo "+strings.Repeat("b", 40)).
+			Rets("echo "+strings.Repeat("a", 40)+" |\n  echo "+strings.Repeat("b", 40)+"\n"),
+		// Blank lines after "|" are removed.
+		Args("a |\n\nb").Rets("a |\n  b\n"),
+	)
+}
+
+func format(code string) string {
+	tree, err := Parse(Source{Name: "[test]", Code: code}, Config{})
+	if err != nil {
+		return "parse error: " + err.Error()
+	}
+	formatted, err := Format(tree, FormatConfig{})
+	if err != nil {
+		return "format error: " + err.Error()
+	}
+	return formatted
+}
+
+func TestFormat_Range(t *testing.T) {
+	tt.Test(t, tt.Fn(formatRange).ArgsFmt("(%q, %v, %v)"),
+		// Only the pipelines overlapping with the range are formatted.
+		//   01234567890123456789
+		Args("echo  a\necho  b\necho  c\n", 8, 9).
+			Rets("echo  a\necho b\necho  c\n"),
+		Args("echo  a\necho  b\necho  c\n", 0, 9).
+			Rets("echo a\necho b\necho  c\n"),
+		// A range within a lambda formats the whole top-level pipeline.
+		Args("f {\necho  a\n}\necho  b", 5, 6).
+			Rets("f {\n  echo a\n}\necho  b"),
+	)
+}
+
+func formatRange(code string, from, to int) string {
+	tree, err := Parse(Source{Name: "[test]", Code: code}, Config{})
+	if err != nil {
+		return "parse error: " + err.Error()
+	}
+	formatted, err := Format(tree, FormatConfig{Range: &diag.Ranging{From: from, To: to}})
+	if err != nil {
+		return "format error: " + err.Error()
+	}
+	return formatted
+}
+
+func TestFormat_Idempotent(t *testing.T) {
+	for _, code := range []string{
+		"if a {\n  echo\n} elif b {\n  # c1\n  # c2\n  if c {\n    echo\n  }\n}\n",
+		"var m = [\n  &a=  [\n    &b= 1\n  ]\n  &cc= 2\n]\n",
+		"a |\n  b {\n    c |\n      d\n  } |\n  e\n",
+	} {
+		once := format(code)
+		if twice := format(once); twice != once {
+			t.Errorf("formatting %q is not idempotent:\nonce:  %q\ntwice: %q", code, once, twice)
+		}
+	}
+}
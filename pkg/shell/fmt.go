@@ -0,0 +1,77 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"src.elv.sh/pkg/diag"
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/prog"
+)
+
+// Formats the given files, or stdin if there are none. The result is written
+// to stdout, or back to the files if write is true.
+func formatFiles(fds [3]*os.File, files []string, write bool) error {
+	if len(files) == 0 {
+		if write {
+			return prog.BadUsage("-w can't be used when formatting stdin")
+		}
+		code, err := io.ReadAll(fds[0])
+		if err != nil {
+			fmt.Fprintln(fds[2], "cannot read stdin:", err)
+			return prog.Exit(2)
+		}
+		formatted, ok := formatCode(fds[2], "[stdin]", string(code))
+		if !ok {
+			return prog.Exit(2)
+		}
+		fmt.Fprint(fds[1], formatted)
+		return nil
+	}
+
+	failed := false
+	for _, file := range files {
+		code, err := readFileUTF8(file)
+		if err != nil {
+			fmt.Fprintf(fds[2], "cannot read %q: %v\n", file, err)
+			failed = true
+			continue
+		}
+		formatted, ok := formatCode(fds[2], file, code)
+		if !ok {
+			failed = true
+			continue
+		}
+		if !write {
+			fmt.Fprint(fds[1], formatted)
+		} else if formatted != code {
+			// Keep the permission bits of the file by truncating it rather
+			// than creating a new one.
+			err := os.WriteFile(file, []byte(formatted), 0o644)
+			if err != nil {
+				fmt.Fprintf(fds[2], "cannot write %q: %v\n", file, err)
+				failed = true
+			}
+		}
+	}
+	if failed {
+		return prog.Exit(2)
+	}
+	return nil
+}
+
+// Formats code, showing any error on stderr.
+func formatCode(stderr io.Writer, name, code string) (string, bool) {
+	tree, err := parse.Parse(parse.Source{Name: name, Code: code, IsFile: true}, parse.Config{})
+	if err != nil {
+		diag.ShowError(stderr, err)
+		return "", false
+	}
+	formatted, err := parse.Format(tree, parse.FormatConfig{})
+	if err != nil {
+		fmt.Fprintf(stderr, "cannot format %s: %v\n", name, err)
+		return "", false
+	}
+	return formatted, true
+}
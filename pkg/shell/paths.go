@@ -20,7 +20,9 @@ func rcPath() (string, error) {
 	}
 }
 
-func libPaths() ([]string, error) {
+// LibPaths returns the directories searched for library modules, which become
+// the value of $runtime:lib-dirs.
+func LibPaths() ([]string, error) {
 	var paths []string
 
 	if configHome := os.Getenv(env.XDG_CONFIG_HOME); configHome != "" {
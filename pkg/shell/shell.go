@@ -46,6 +46,8 @@ type Program struct {
 
 	codeInArg   bool
 	compileOnly bool
+	format      bool
+	formatWrite bool
 	noRC        bool
 	rc          string
 	json        *bool
@@ -63,6 +65,10 @@ func (p *Program) RegisterFlags(fs *prog.FlagSet) {
 		"Treat the first argument as code to execute")
 	fs.BoolVar(&p.compileOnly, "compileonly", false,
 		"Parse and compile Elvish code without executing it")
+	fs.BoolVar(&p.format, "fmt", false,
+		"Format Elvish source files, or stdin if none is given")
+	fs.BoolVar(&p.formatWrite, "w", false,
+		"With -fmt, write the result to the source files instead of stdout")
 	fs.BoolVar(&p.noRC, "norc", false,
 		"Don't read the RC file when running interactively")
 	fs.StringVar(&p.rc, "rc", "",
@@ -75,6 +81,12 @@ func (p *Program) RegisterFlags(fs *prog.FlagSet) {
 }
 
 func (p *Program) Run(fds [3]*os.File, args []string) error {
+	if p.format {
+		return formatFiles(fds, args, p.formatWrite)
+	} else if p.formatWrite {
+		return prog.BadUsage("-w can only be used with -fmt")
+	}
+
 	cleanup1 := incSHLVL()
 	defer cleanup1()
 	cleanup2 := initSignal(fds)
@@ -138,7 +150,7 @@ func (p *Program) makeEvaler(stderr io.Writer, interactive bool) *eval.Evaler {
 		}
 	}
 
-	libs, err := libPaths()
+	libs, err := LibPaths()
 	if err != nil {
 		fmt.Fprintln(stderr, "Warning: resolving lib paths:", err)
 	} else {
@@ -189,7 +201,8 @@ func evalInTTY(fds [3]*os.File, ev *eval.Evaler, ed editor, src parse.Source) er
 	defer restore()
 	ctx, done := eval.ListenInterrupts()
 	err := ev.Eval(src, eval.EvalCfg{
-		Ports: ports, Interrupts: ctx, PutInFg: true})
+		Ports: ports, Interrupts: ctx, PutInFg: true,
+		JobControl: ed != nil && sys.IsATTY(fds[0].Fd())})
 	done()
 	if ed != nil {
 		ed.RunAfterCommandHooks(src, time.Since(start).Seconds(), err)
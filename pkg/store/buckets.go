@@ -1,8 +1,9 @@
 package store
 
 const (
-	bucketCmd = "cmd"
-	bucketDir = "dir"
+	bucketCmd     = "cmd"
+	bucketCmdMeta = "cmdmeta"
+	bucketDir     = "dir"
 )
 
 // The following buckets were used before and are thus reserved:
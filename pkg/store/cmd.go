@@ -3,8 +3,12 @@ package store
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"regexp"
 
 	bolt "go.etcd.io/bbolt"
+	"src.elv.sh/pkg/filter"
 	. "src.elv.sh/pkg/store/storedefs"
 )
 
@@ -13,6 +17,10 @@ func init() {
 		_, err := tx.CreateBucketIfNotExists([]byte(bucketCmd))
 		return err
 	}
+	initDB["initialize command metadata table"] = func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucketCmdMeta))
+		return err
+	}
 }
 
 // NextCmdSeq returns the next sequence number of the command history.
@@ -47,7 +55,58 @@ func (s *dbStore) AddCmd(cmd string) (int, error) {
 func (s *dbStore) DelCmd(seq int) error {
 	return s.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(bucketCmd))
-		return b.Delete(marshalSeq(uint64(seq)))
+		err := b.Delete(marshalSeq(uint64(seq)))
+		if err != nil {
+			return err
+		}
+		return tx.Bucket([]byte(bucketCmdMeta)).Delete(marshalSeq(uint64(seq)))
+	})
+}
+
+// AddCmds adds multiple commands to the command history in one transaction,
+// along with their metadata. The Seq field of each command is ignored; new
+// sequence numbers are allocated in order.
+func (s *dbStore) AddCmds(cmds []Cmd) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketCmd))
+		bMeta := tx.Bucket([]byte(bucketCmdMeta))
+		for _, cmd := range cmds {
+			seq, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+			k := marshalSeq(seq)
+			if err := b.Put(k, []byte(cmd.Text)); err != nil {
+				return err
+			}
+			if cmd.Meta == (CmdMeta{}) {
+				continue
+			}
+			data, err := json.Marshal(cmd.Meta)
+			if err != nil {
+				return err
+			}
+			if err := bMeta.Put(k, data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SetCmdMeta sets the metadata of the command history item with the given
+// sequence number, replacing any existing metadata.
+func (s *dbStore) SetCmdMeta(seq int, meta CmdMeta) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		k := marshalSeq(uint64(seq))
+		if tx.Bucket([]byte(bucketCmd)).Get(k) == nil {
+			return ErrNoMatchingCmd
+		}
+		return tx.Bucket([]byte(bucketCmdMeta)).Put(k, data)
 	})
 }
 
@@ -71,9 +130,10 @@ func (s *dbStore) Cmd(seq int) (string, error) {
 func (s *dbStore) IterateCmds(from, upto int, f func(Cmd)) error {
 	return s.db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(bucketCmd))
+		mb := tx.Bucket([]byte(bucketCmdMeta))
 		c := b.Cursor()
 		for k, v := c.Seek(marshalSeq(uint64(from))); k != nil && unmarshalSeq(k) < uint64(upto); k, v = c.Next() {
-			f(Cmd{Text: string(v), Seq: int(unmarshalSeq(k))})
+			f(makeCmd(k, v, mb))
 		}
 		return nil
 	})
@@ -98,7 +158,7 @@ func (s *dbStore) NextCmd(from int, prefix string) (Cmd, error) {
 		p := []byte(prefix)
 		for k, v := c.Seek(marshalSeq(uint64(from))); k != nil; k, v = c.Next() {
 			if bytes.HasPrefix(v, p) {
-				cmd = Cmd{Text: string(v), Seq: int(unmarshalSeq(k))}
+				cmd = makeCmd(k, v, tx.Bucket([]byte(bucketCmdMeta)))
 				return nil
 			}
 		}
@@ -129,7 +189,7 @@ func (s *dbStore) PrevCmd(upto int, prefix string) (Cmd, error) {
 
 		for ; k != nil; k, v = c.Prev() {
 			if bytes.HasPrefix(v, p) {
-				cmd = Cmd{Text: string(v), Seq: int(unmarshalSeq(k))}
+				cmd = makeCmd(k, v, tx.Bucket([]byte(bucketCmdMeta)))
 				return nil
 			}
 		}
@@ -138,6 +198,66 @@ func (s *dbStore) PrevCmd(upto int, prefix string) (Cmd, error) {
 	return cmd, err
 }
 
+// SearchCmds finds commands matching the query.
+func (s *dbStore) SearchCmds(q CmdQuery) ([]Cmd, error) {
+	match, err := compileCmdQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	var cmds []Cmd
+	err = s.db.View(func(tx *bolt.Tx) error {
+		mb := tx.Bucket([]byte(bucketCmdMeta))
+		c := tx.Bucket([]byte(bucketCmd)).Cursor()
+		first, next := c.First, c.Next
+		if q.NewestFirst {
+			first, next = c.Last, c.Prev
+		}
+		for k, v := first(); k != nil; k, v = next() {
+			if !match(v) {
+				continue
+			}
+			cmds = append(cmds, makeCmd(k, v, mb))
+			if q.Limit > 0 && len(cmds) >= q.Limit {
+				break
+			}
+		}
+		return nil
+	})
+	return cmds, err
+}
+
+func compileCmdQuery(q CmdQuery) (func([]byte) bool, error) {
+	switch q.Mode {
+	case "", SearchSubstr:
+		p := []byte(q.Pattern)
+		return func(v []byte) bool { return bytes.Contains(v, p) }, nil
+	case SearchRegexp:
+		re, err := regexp.Compile(q.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		return re.Match, nil
+	case SearchFilter:
+		f, err := filter.Compile(q.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		return func(v []byte) bool { return f.Match(string(v)) }, nil
+	default:
+		return nil, fmt.Errorf("unknown search mode %q", q.Mode)
+	}
+}
+
+// Builds a Cmd from a key-value pair in the command bucket, looking up its
+// metadata in the metadata bucket. Malformed metadata is ignored.
+func makeCmd(k, v []byte, mb *bolt.Bucket) Cmd {
+	cmd := Cmd{Text: string(v), Seq: int(unmarshalSeq(k))}
+	if data := mb.Get(k); data != nil {
+		json.Unmarshal(data, &cmd.Meta)
+	}
+	return cmd
+}
+
 func marshalSeq(seq uint64) []byte {
 	b := make([]byte, 8)
 	binary.BigEndian.PutUint64(b, seq)
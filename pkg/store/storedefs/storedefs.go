@@ -22,6 +22,9 @@ type Store interface {
 	CmdsWithSeq(from, upto int) ([]Cmd, error)
 	NextCmd(from int, prefix string) (Cmd, error)
 	PrevCmd(upto int, prefix string) (Cmd, error)
+	AddCmds(cmds []Cmd) error
+	SetCmdMeta(seq int, meta CmdMeta) error
+	SearchCmds(q CmdQuery) ([]Cmd, error)
 
 	AddDir(dir string, incFactor float64) error
 	DelDir(dir string) error
@@ -38,4 +41,49 @@ type Dir struct {
 type Cmd struct {
 	Text string
 	Seq  int
+	Meta CmdMeta
 }
+
+// CmdMeta contains information about how a command in the command history was
+// run. The zero value means that no such information was recorded, which is
+// the case for commands added with AddCmd but never run.
+type CmdMeta struct {
+	// Time the command started, in seconds since the Unix epoch.
+	Start float64
+	// Duration of the command, in seconds.
+	Duration float64
+	// Working directory when the command started.
+	Dir string
+	// "ok" if the command finished without an exception. Otherwise, the type
+	// of the exception's reason (like "fail" or "external-cmd/exited"), or
+	// "unknown" if the reason has no type.
+	Status string
+	// ID of the interactive session that ran the command.
+	Session string
+}
+
+// CmdQuery specifies a search in the command history.
+type CmdQuery struct {
+	// The pattern to search for, interpreted according to Mode.
+	Pattern string
+	// How to interpret Pattern; one of the Search* constants. Defaults to
+	// SearchSubstr if empty.
+	Mode string
+	// Maximum number of results; 0 or negative means no limit.
+	Limit int
+	// Whether to search from the newest command to the oldest, instead of
+	// the other way around.
+	NewestFirst bool
+}
+
+// Possible values of CmdQuery.Mode.
+const (
+	// Match commands that contain the pattern as a substring.
+	SearchSubstr = "substr"
+	// Match commands that contain a match of the pattern as a regular
+	// expression, using the syntax of the regexp package.
+	SearchRegexp = "regexp"
+	// Match commands using the pattern as a filter in the listing modes of
+	// the line editor. See the src.elv.sh/pkg/filter package.
+	SearchFilter = "filter"
+)
@@ -1,6 +1,7 @@
 package storetest
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 
@@ -30,6 +31,31 @@ var (
 	}
 )
 
+var cmdSearches = []struct {
+	query    storedefs.CmdQuery
+	wantCmds []storedefs.Cmd
+	wantErr  error
+}{
+	{storedefs.CmdQuery{Pattern: "bar"},
+		[]storedefs.Cmd{{Text: "put bar", Seq: 2}, {Text: "echo bar", Seq: 4}}, nil},
+	{storedefs.CmdQuery{Pattern: "bar", NewestFirst: true},
+		[]storedefs.Cmd{{Text: "echo bar", Seq: 4}, {Text: "put bar", Seq: 2}}, nil},
+	{storedefs.CmdQuery{Pattern: "put", Limit: 1},
+		[]storedefs.Cmd{{Text: "put bar", Seq: 2}}, nil},
+	{storedefs.CmdQuery{Pattern: "put", Limit: 1, NewestFirst: true},
+		[]storedefs.Cmd{{Text: "put lorem", Seq: 3}}, nil},
+	{storedefs.CmdQuery{Pattern: "^e.*o$", Mode: storedefs.SearchRegexp},
+		[]storedefs.Cmd{{Text: "echo foo", Seq: 1}}, nil},
+	{storedefs.CmdQuery{Pattern: "PUT 'bar'", Mode: storedefs.SearchFilter},
+		nil, nil},
+	{storedefs.CmdQuery{Pattern: "put 'r'", Mode: storedefs.SearchFilter},
+		[]storedefs.Cmd{{Text: "put bar", Seq: 2}, {Text: "put lorem", Seq: 3}}, nil},
+	{storedefs.CmdQuery{Pattern: "x", Mode: "bad"},
+		nil, errors.New(`unknown search mode "bad"`)},
+	{storedefs.CmdQuery{Pattern: "(", Mode: storedefs.SearchRegexp},
+		nil, errors.New("error parsing regexp: missing closing ): `(`")},
+}
+
 // TestCmd tests the command history functionality of a Store.
 func TestCmd(t *testing.T, store storedefs.Store) {
 	startSeq, err := store.NextCmdSeq()
@@ -96,6 +122,52 @@ func TestCmd(t *testing.T, store storedefs.Store) {
 		}
 	}
 
+	// SearchCmds
+	for _, tt := range cmdSearches {
+		cmds, err := store.SearchCmds(tt.query)
+		if !equalCmds(cmds, tt.wantCmds) || !matchErr(err, tt.wantErr) {
+			t.Errorf("store.SearchCmds(%+v) => (%v, %v), want (%v, %v)",
+				tt.query, cmds, err, tt.wantCmds, tt.wantErr)
+		}
+	}
+
+	// SetCmdMeta
+	meta := storedefs.CmdMeta{
+		Start: 1700000000.5, Duration: 1.25, Dir: "/tmp",
+		Status: "external-cmd/exited", Session: "session"}
+	if err := store.SetCmdMeta(2, meta); err != nil {
+		t.Errorf("store.SetCmdMeta(2, %v) => %v, want nil", meta, err)
+	}
+	wantCmd := storedefs.Cmd{Text: cmds[1], Seq: 2, Meta: meta}
+	if cmds, err := store.CmdsWithSeq(2, 3); !equalCmds(cmds, []storedefs.Cmd{wantCmd}) || err != nil {
+		t.Errorf("store.CmdsWithSeq(2, 3) => (%v, %v), want (%v, nil)",
+			cmds, err, []storedefs.Cmd{wantCmd})
+	}
+	if cmd, err := store.NextCmd(2, ""); cmd != wantCmd || err != nil {
+		t.Errorf("store.NextCmd(2, \"\") => (%v, %v), want (%v, nil)",
+			cmd, err, wantCmd)
+	}
+	if cmd, err := store.PrevCmd(3, ""); cmd != wantCmd || err != nil {
+		t.Errorf("store.PrevCmd(3, \"\") => (%v, %v), want (%v, nil)",
+			cmd, err, wantCmd)
+	}
+	if err := store.SetCmdMeta(100, meta); !matchErr(err, storedefs.ErrNoMatchingCmd) {
+		t.Errorf("store.SetCmdMeta(100, %v) => %v, want %v",
+			meta, err, storedefs.ErrNoMatchingCmd)
+	}
+
+	// AddCmds
+	added := []storedefs.Cmd{{Text: "echo added"}, {Text: "put added", Meta: meta}}
+	if err := store.AddCmds(added); err != nil {
+		t.Errorf("store.AddCmds(%v) => %v, want nil", added, err)
+	}
+	wantAdded := []storedefs.Cmd{
+		{Text: "echo added", Seq: 5}, {Text: "put added", Seq: 6, Meta: meta}}
+	if cmds, err := store.CmdsWithSeq(5, 7); !equalCmds(cmds, wantAdded) || err != nil {
+		t.Errorf("store.CmdsWithSeq(5, 7) => (%v, %v), want (%v, nil)",
+			cmds, err, wantAdded)
+	}
+
 	// DelCmd
 	if err := store.DelCmd(1); err != nil {
 		t.Error("Failed to remove cmd")
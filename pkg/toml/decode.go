@@ -0,0 +1,684 @@
+// Package toml implements a decoder and an encoder for TOML documents, as
+// specified in https://toml.io/en/v1.0.0.
+//
+// Documents are decoded into the following Go types:
+//
+//   - Tables are decoded into map[string]any.
+//   - Arrays are decoded into []any.
+//   - Strings are decoded into string.
+//   - Integers are decoded into int64.
+//   - Floats are decoded into float64.
+//   - Booleans are decoded into bool.
+//   - Dates and times are decoded into strings, keeping their TOML syntax.
+//
+// The encoder accepts the same types, and int.
+package toml
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Error is returned when a document can't be decoded.
+type Error struct {
+	Line    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// A table during decoding. The values are *table, *tableArray, or values of
+// any of the types documented in the package doc.
+type table struct {
+	m map[string]any
+	// Defined with a [header].
+	header bool
+	// Defined by a dotted key.
+	dotted bool
+	// Defined as an inline table; can't be extended.
+	inline bool
+}
+
+// An array of tables defined by [[header]]s.
+type tableArray struct{ tables []*table }
+
+func newTable() *table { return &table{m: make(map[string]any)} }
+
+// Decode decodes a TOML document.
+func Decode(src string) (m map[string]any, err error) {
+	p := &parser{src: src}
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(*Error); ok {
+				err = e
+				return
+			}
+			panic(r)
+		}
+	}()
+	root := newTable()
+	p.document(root)
+	return toGo(root).(map[string]any), nil
+}
+
+func toGo(v any) any {
+	switch v := v.(type) {
+	case *table:
+		m := make(map[string]any, len(v.m))
+		for k, elem := range v.m {
+			m[k] = toGo(elem)
+		}
+		return m
+	case *tableArray:
+		a := make([]any, len(v.tables))
+		for i, t := range v.tables {
+			a[i] = toGo(t)
+		}
+		return a
+	case []any:
+		a := make([]any, len(v))
+		for i, elem := range v {
+			a[i] = toGo(elem)
+		}
+		return a
+	default:
+		return v
+	}
+}
+
+type parser struct {
+	src string
+	pos int
+}
+
+func (p *parser) fail(format string, args ...any) {
+	line := strings.Count(p.src[:p.pos], "\n") + 1
+	panic(&Error{line, fmt.Sprintf(format, args...)})
+}
+
+func (p *parser) eof() bool { return p.pos >= len(p.src) }
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *parser) hasPrefix(s string) bool { return strings.HasPrefix(p.src[p.pos:], s) }
+
+func (p *parser) expect(b byte) {
+	if p.peek() != b {
+		p.fail("expected %q, found %s", b, p.found())
+	}
+	p.pos++
+}
+
+// Describes the character at the current position for error messages.
+func (p *parser) found() string {
+	switch {
+	case p.eof():
+		return "end of input"
+	case p.peek() == '\n' || p.hasPrefix("\r\n"):
+		return "newline"
+	default:
+		r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+		return strconv.QuoteRune(r)
+	}
+}
+
+func (p *parser) skipSpaces() {
+	for p.peek() == ' ' || p.peek() == '\t' {
+		p.pos++
+	}
+}
+
+func (p *parser) skipComment() {
+	if p.peek() != '#' {
+		return
+	}
+	for !p.eof() && p.peek() != '\n' && !p.hasPrefix("\r\n") {
+		c := p.peek()
+		if c < 0x20 && c != '\t' || c == 0x7f {
+			p.fail("control character %q in comment", c)
+		}
+		p.pos++
+	}
+}
+
+// Consumes a newline, returning whether there is one.
+func (p *parser) newline() bool {
+	if p.peek() == '\n' {
+		p.pos++
+		return true
+	} else if p.hasPrefix("\r\n") {
+		p.pos += 2
+		return true
+	}
+	return false
+}
+
+// Skips whitespace, comments and newlines.
+func (p *parser) skipBlank() {
+	for {
+		p.skipSpaces()
+		p.skipComment()
+		if !p.newline() {
+			return
+		}
+	}
+}
+
+// Consumes the rest of a line after an expression.
+func (p *parser) endOfLine() {
+	p.skipSpaces()
+	p.skipComment()
+	if !p.eof() && !p.newline() {
+		p.fail("expected newline, found %s", p.found())
+	}
+}
+
+func (p *parser) document(root *table) {
+	current := root
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return
+		}
+		if p.hasPrefix("[[") {
+			p.pos += 2
+			keys := p.key()
+			p.expect(']')
+			p.expect(']')
+			current = p.arrayTableHeader(root, keys)
+		} else if p.peek() == '[' {
+			p.pos++
+			keys := p.key()
+			p.expect(']')
+			current = p.tableHeader(root, keys)
+		} else {
+			p.keyValue(current)
+		}
+		p.endOfLine()
+	}
+}
+
+// Returns the table for a key that is part of a header, creating an implicit
+// table if it doesn't exist.
+func (p *parser) descend(t *table, key string) *table {
+	switch v := t.m[key].(type) {
+	case nil:
+		sub := newTable()
+		t.m[key] = sub
+		return sub
+	case *table:
+		if v.inline {
+			p.fail("cannot extend inline table %s", quoteKey(key))
+		}
+		return v
+	case *tableArray:
+		return v.tables[len(v.tables)-1]
+	default:
+		p.fail("key %s is already defined as a value", quoteKey(key))
+		return nil
+	}
+}
+
+func (p *parser) tableHeader(root *table, keys []string) *table {
+	t := root
+	for _, key := range keys[:len(keys)-1] {
+		t = p.descend(t, key)
+	}
+	last := keys[len(keys)-1]
+	switch v := t.m[last].(type) {
+	case nil:
+		sub := newTable()
+		sub.header = true
+		t.m[last] = sub
+		return sub
+	case *table:
+		if v.header || v.dotted || v.inline {
+			p.fail("table %s is defined more than once", joinKeys(keys))
+		}
+		v.header = true
+		return v
+	default:
+		p.fail("table %s is defined more than once", joinKeys(keys))
+		return nil
+	}
+}
+
+func (p *parser) arrayTableHeader(root *table, keys []string) *table {
+	t := root
+	for _, key := range keys[:len(keys)-1] {
+		t = p.descend(t, key)
+	}
+	last := keys[len(keys)-1]
+	sub := newTable()
+	sub.header = true
+	switch v := t.m[last].(type) {
+	case nil:
+		t.m[last] = &tableArray{[]*table{sub}}
+	case *tableArray:
+		v.tables = append(v.tables, sub)
+	default:
+		p.fail("key %s is already defined as a %s", joinKeys(keys), kind(v))
+	}
+	return sub
+}
+
+func kind(v any) string {
+	switch v.(type) {
+	case *table:
+		return "table"
+	case []any:
+		return "static array"
+	default:
+		return "value"
+	}
+}
+
+func (p *parser) keyValue(t *table) {
+	keys := p.key()
+	p.skipSpaces()
+	p.expect('=')
+	p.skipSpaces()
+	for _, key := range keys[:len(keys)-1] {
+		switch v := t.m[key].(type) {
+		case nil:
+			sub := newTable()
+			sub.dotted = true
+			t.m[key] = sub
+			t = sub
+		case *table:
+			if !v.dotted {
+				p.fail("cannot extend table %s with dotted keys", quoteKey(key))
+			}
+			t = v
+		default:
+			p.fail("key %s is already defined as a %s", quoteKey(key), kind(v))
+		}
+	}
+	last := keys[len(keys)-1]
+	if _, exists := t.m[last]; exists {
+		p.fail("key %s is defined more than once", joinKeys(keys))
+	}
+	t.m[last] = p.value()
+}
+
+// Parses a possibly dotted key.
+func (p *parser) key() []string {
+	var keys []string
+	for {
+		p.skipSpaces()
+		keys = append(keys, p.simpleKey())
+		p.skipSpaces()
+		if p.peek() != '.' {
+			return keys
+		}
+		p.pos++
+	}
+}
+
+func (p *parser) simpleKey() string {
+	switch p.peek() {
+	case '"':
+		if p.hasPrefix(`"""`) {
+			p.fail("multi-line strings cannot be used as keys")
+		}
+		return p.basicString()
+	case '\'':
+		if p.hasPrefix("'''") {
+			p.fail("multi-line strings cannot be used as keys")
+		}
+		return p.literalString()
+	}
+	start := p.pos
+	for !p.eof() && isBareKeyChar(p.peek()) {
+		p.pos++
+	}
+	if p.pos == start {
+		p.fail("expected key, found %s", p.found())
+	}
+	return p.src[start:p.pos]
+}
+
+func isBareKeyChar(b byte) bool {
+	return 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9' ||
+		b == '_' || b == '-'
+}
+
+func (p *parser) value() any {
+	switch {
+	case p.hasPrefix(`"""`):
+		return p.multiLineBasicString()
+	case p.peek() == '"':
+		return p.basicString()
+	case p.hasPrefix("'''"):
+		return p.multiLineLiteralString()
+	case p.peek() == '\'':
+		return p.literalString()
+	case p.peek() == '[':
+		return p.array()
+	case p.peek() == '{':
+		return p.inlineTable()
+	}
+	return p.atom()
+}
+
+func (p *parser) array() []any {
+	p.expect('[')
+	a := []any{}
+	for {
+		p.skipBlank()
+		if p.peek() == ']' {
+			p.pos++
+			return a
+		}
+		a = append(a, p.value())
+		p.skipBlank()
+		if p.peek() == ',' {
+			p.pos++
+		} else if p.peek() != ']' {
+			p.fail("expected ',' or ']', found %s", p.found())
+		}
+	}
+}
+
+func (p *parser) inlineTable() *table {
+	p.expect('{')
+	t := newTable()
+	p.skipSpaces()
+	if p.peek() == '}' {
+		p.pos++
+		t.inline = true
+		return t
+	}
+	for {
+		p.keyValue(t)
+		p.skipSpaces()
+		if p.peek() == '}' {
+			p.pos++
+			markInline(t)
+			return t
+		}
+		p.expect(',')
+		p.skipSpaces()
+	}
+}
+
+// Marks a table and the tables defined by dotted keys in it as inline.
+func markInline(t *table) {
+	t.inline = true
+	for _, v := range t.m {
+		if sub, ok := v.(*table); ok && sub.dotted {
+			markInline(sub)
+		}
+	}
+}
+
+var (
+	decIntPattern = regexp.MustCompile(`^[+-]?(0|[1-9](_?[0-9])*)$`)
+	hexIntPattern = regexp.MustCompile(`^0x[0-9a-fA-F](_?[0-9a-fA-F])*$`)
+	octIntPattern = regexp.MustCompile(`^0o[0-7](_?[0-7])*$`)
+	binIntPattern = regexp.MustCompile(`^0b[01](_?[01])*$`)
+	floatPattern  = regexp.MustCompile(
+		`^[+-]?(0|[1-9](_?[0-9])*)(\.[0-9](_?[0-9])*)?([eE][+-]?[0-9](_?[0-9])*)?$`)
+
+	datePattern     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	timePattern     = regexp.MustCompile(`^\d{2}:\d{2}:\d{2}(\.\d+)?$`)
+	dateTimePattern = regexp.MustCompile(
+		`^\d{4}-\d{2}-\d{2}[Tt ]\d{2}:\d{2}:\d{2}(\.\d+)?([Zz]|[+-]\d{2}:\d{2})?$`)
+)
+
+// Parses a boolean, number, date or time.
+func (p *parser) atom() any {
+	start := p.pos
+	for !p.eof() && isAtomChar(p.peek()) {
+		p.pos++
+	}
+	s := p.src[start:p.pos]
+	// A space may separate the date and time of a date-time.
+	if datePattern.MatchString(s) && p.peek() == ' ' && p.pos+3 < len(p.src) &&
+		isDigit(p.src[p.pos+1]) && isDigit(p.src[p.pos+2]) && p.src[p.pos+3] == ':' {
+		p.pos++
+		for !p.eof() && isAtomChar(p.peek()) {
+			p.pos++
+		}
+		s = p.src[start:p.pos]
+	}
+	if s == "" {
+		p.fail("expected value, found %s", p.found())
+	}
+
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "inf", "+inf":
+		return math.Inf(1)
+	case "-inf":
+		return math.Inf(-1)
+	case "nan", "+nan", "-nan":
+		return math.NaN()
+	}
+	switch {
+	case decIntPattern.MatchString(s):
+		return p.parseInt(s, 10)
+	case hexIntPattern.MatchString(s):
+		return p.parseInt(s[2:], 16)
+	case octIntPattern.MatchString(s):
+		return p.parseInt(s[2:], 8)
+	case binIntPattern.MatchString(s):
+		return p.parseInt(s[2:], 2)
+	case floatPattern.MatchString(s):
+		f, err := strconv.ParseFloat(strings.ReplaceAll(s, "_", ""), 64)
+		if err != nil {
+			p.fail("invalid float %s", s)
+		}
+		return f
+	case dateTimePattern.MatchString(s):
+		layout := "2006-01-02T15:04:05.999999999"
+		if strings.ContainsAny(s[19:], "Zz+-") {
+			layout += "Z07:00"
+		}
+		p.checkTime(strings.ToUpper(s[:10]+"T"+s[11:]), layout)
+		return s
+	case datePattern.MatchString(s):
+		p.checkTime(s, "2006-01-02")
+		return s
+	case timePattern.MatchString(s):
+		p.checkTime(s, "15:04:05.999999999")
+		return s
+	}
+	p.fail("invalid value %s", s)
+	return nil
+}
+
+func (p *parser) checkTime(s, layout string) {
+	if _, err := time.Parse(layout, s); err != nil {
+		p.fail("invalid date or time %s", s)
+	}
+}
+
+func (p *parser) parseInt(s string, base int) int64 {
+	i, err := strconv.ParseInt(strings.ReplaceAll(s, "_", ""), base, 64)
+	if err != nil {
+		p.fail("integer %s is out of range", s)
+	}
+	return i
+}
+
+func isAtomChar(b byte) bool {
+	return isBareKeyChar(b) || b == '+' || b == '.' || b == ':'
+}
+
+func isDigit(b byte) bool { return '0' <= b && b <= '9' }
+
+func (p *parser) basicString() string {
+	p.expect('"')
+	var sb strings.Builder
+	for {
+		switch c := p.peek(); {
+		case p.eof() || c == '\n' || p.hasPrefix("\r\n"):
+			p.fail("unterminated string")
+		case c == '"':
+			p.pos++
+			return sb.String()
+		case c == '\\':
+			p.escape(&sb)
+		default:
+			p.stringChar(&sb)
+		}
+	}
+}
+
+func (p *parser) multiLineBasicString() string {
+	p.pos += 3
+	p.newline()
+	var sb strings.Builder
+	for {
+		switch c := p.peek(); {
+		case p.eof():
+			p.fail("unterminated string")
+		case p.hasPrefix(`"""`):
+			p.pos += 3
+			// Up to two quotes can immediately precede the closing delimiter.
+			for i := 0; i < 2 && p.peek() == '"'; i++ {
+				sb.WriteByte('"')
+				p.pos++
+			}
+			return sb.String()
+		case c == '\\':
+			// A backslash at the end of a line trims the newline and all
+			// whitespace that follows.
+			save := p.pos
+			p.pos++
+			p.skipSpaces()
+			if p.newline() {
+				for {
+					p.skipSpaces()
+					if !p.newline() {
+						break
+					}
+				}
+				continue
+			}
+			p.pos = save
+			p.escape(&sb)
+		case c == '\n' || p.hasPrefix("\r\n"):
+			p.newline()
+			sb.WriteByte('\n')
+		default:
+			p.stringChar(&sb)
+		}
+	}
+}
+
+func (p *parser) literalString() string {
+	p.expect('\'')
+	var sb strings.Builder
+	for {
+		switch c := p.peek(); {
+		case p.eof() || c == '\n' || p.hasPrefix("\r\n"):
+			p.fail("unterminated string")
+		case c == '\'':
+			p.pos++
+			return sb.String()
+		default:
+			p.stringChar(&sb)
+		}
+	}
+}
+
+func (p *parser) multiLineLiteralString() string {
+	p.pos += 3
+	p.newline()
+	var sb strings.Builder
+	for {
+		switch c := p.peek(); {
+		case p.eof():
+			p.fail("unterminated string")
+		case p.hasPrefix("'''"):
+			p.pos += 3
+			for i := 0; i < 2 && p.peek() == '\''; i++ {
+				sb.WriteByte('\'')
+				p.pos++
+			}
+			return sb.String()
+		case c == '\n' || p.hasPrefix("\r\n"):
+			p.newline()
+			sb.WriteByte('\n')
+		default:
+			p.stringChar(&sb)
+		}
+	}
+}
+
+// Consumes a character that is part of a string literal.
+func (p *parser) stringChar(sb *strings.Builder) {
+	r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+	if r == utf8.RuneError && size == 1 {
+		p.fail("invalid UTF-8")
+	}
+	if r < 0x20 && r != '\t' || r == 0x7f {
+		p.fail("control character %q in string", r)
+	}
+	sb.WriteRune(r)
+	p.pos += size
+}
+
+func (p *parser) escape(sb *strings.Builder) {
+	p.pos++
+	c := p.peek()
+	p.pos++
+	switch c {
+	case 'b':
+		sb.WriteByte('\b')
+	case 't':
+		sb.WriteByte('\t')
+	case 'n':
+		sb.WriteByte('\n')
+	case 'f':
+		sb.WriteByte('\f')
+	case 'r':
+		sb.WriteByte('\r')
+	case '"':
+		sb.WriteByte('"')
+	case '\\':
+		sb.WriteByte('\\')
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if p.pos+n > len(p.src) {
+			p.fail("invalid unicode escape")
+		}
+		code, err := strconv.ParseUint(p.src[p.pos:p.pos+n], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			p.fail("invalid unicode escape")
+		}
+		sb.WriteRune(rune(code))
+		p.pos += n
+	default:
+		p.pos--
+		p.fail("invalid escape sequence \\%s", p.found())
+	}
+}
+
+func joinKeys(keys []string) string {
+	quoted := make([]string, len(keys))
+	for i, key := range keys {
+		quoted[i] = quoteKey(key)
+	}
+	return strings.Join(quoted, ".")
+}
@@ -0,0 +1,87 @@
+package toml
+
+import (
+	"math"
+	"testing"
+
+	"src.elv.sh/pkg/tt"
+)
+
+type m = map[string]any
+type a = []any
+
+var Args = tt.Args
+
+func TestDecode(t *testing.T) {
+	tt.Test(t, Decode,
+		// Keys
+		Args("").Rets(m{}, error(nil)),
+		Args("# comment\na = 1 # comment\n").Rets(m{"a": int64(1)}, error(nil)),
+		Args(`bare-key_1 = 1
+"quoted key" = 2
+'literal key' = 3`).Rets(
+			m{"bare-key_1": int64(1), "quoted key": int64(2), "literal key": int64(3)}, error(nil)),
+		Args("a.b . c = 1\na.d = 2").Rets(
+			m{"a": m{"b": m{"c": int64(1)}, "d": int64(2)}}, error(nil)),
+		Args("a = 1\r\nb = 2\r\n").Rets(m{"a": int64(1), "b": int64(2)}, error(nil)),
+
+		// Strings
+		Args(`s = "a\tb\"\\\u00e9\U0001F600"`).Rets(m{"s": "a\tb\"\\é😀"}, error(nil)),
+		Args(`s = 'C:\path'`).Rets(m{"s": `C:\path`}, error(nil)),
+		Args("s = \"\"\"\nline 1\nline 2\"\"\"\"").Rets(m{"s": "line 1\nline 2\""}, error(nil)),
+		Args("s = \"\"\"a \\\n   b\"\"\"").Rets(m{"s": "a b"}, error(nil)),
+		Args("s = '''\nraw\\n\n'''").Rets(m{"s": "raw\\n\n"}, error(nil)),
+
+		// Numbers and booleans
+		Args("a = [+1, -2, 1_000, 0xff, 0o17, 0b101]").Rets(
+			m{"a": a{int64(1), int64(-2), int64(1000), int64(255), int64(15), int64(5)}}, error(nil)),
+		Args("a = [1.5, -0.5e3, 1e2, 3_1.4_1, inf, -inf]").Rets(
+			m{"a": a{1.5, -500.0, 100.0, 31.41, math.Inf(1), math.Inf(-1)}}, error(nil)),
+		Args("a = [true, false]").Rets(m{"a": a{true, false}}, error(nil)),
+
+		// Dates and times
+		Args(`a = [1979-05-27T07:32:00Z, 1979-05-27 07:32:00.999-07:00,
+	1979-05-27T07:32:00, 1979-05-27, 07:32:00]`).Rets(
+			m{"a": a{"1979-05-27T07:32:00Z", "1979-05-27 07:32:00.999-07:00",
+				"1979-05-27T07:32:00", "1979-05-27", "07:32:00"}}, error(nil)),
+
+		// Arrays and inline tables
+		Args("a = [\n  1, # one\n  [2, 'x'],\n]").Rets(
+			m{"a": a{int64(1), a{int64(2), "x"}}}, error(nil)),
+		Args("t = {x = 1, y.z = 2}\ne = {}").Rets(
+			m{"t": m{"x": int64(1), "y": m{"z": int64(2)}}, "e": m{}}, error(nil)),
+
+		// Tables
+		Args(`a = 1
+[t]
+x = 1
+[t.u]
+y = 2
+[v.w]
+[v]
+z = 3`).Rets(
+			m{"a": int64(1), "t": m{"x": int64(1), "u": m{"y": int64(2)}},
+				"v": m{"w": m{}, "z": int64(3)}}, error(nil)),
+		Args(`[[p]]
+name = "a"
+[p.q]
+x = 1
+[[p]]
+name = "b"`).Rets(
+			m{"p": a{m{"name": "a", "q": m{"x": int64(1)}}, m{"name": "b"}}}, error(nil)),
+
+		// Errors
+		Args("a = 1\na = 2").Rets(m(nil), &Error{2, "key a is defined more than once"}),
+		Args("[t]\n[t]").Rets(m(nil), &Error{2, "table t is defined more than once"}),
+		Args("[t]\nx.y = 1\n[t.x]").Rets(m(nil), &Error{3, "table t.x is defined more than once"}),
+		Args("a = {x = 1}\na.y = 2").Rets(m(nil), &Error{2, "cannot extend table a with dotted keys"}),
+		Args("a = [1]\n[[a]]").Rets(m(nil), &Error{2, "key a is already defined as a static array"}),
+		Args("a = 1 b = 2").Rets(m(nil), &Error{1, "expected newline, found 'b'"}),
+		Args(`a = "x`).Rets(m(nil), &Error{1, "unterminated string"}),
+		Args(`a = "\x"`).Rets(m(nil), &Error{1, `invalid escape sequence \'x'`}),
+		Args("a = 1979-13-01").Rets(m(nil), &Error{1, "invalid date or time 1979-13-01"}),
+		Args("a = 0123").Rets(m(nil), &Error{1, "invalid value 0123"}),
+		Args("a = 9223372036854775808").Rets(m(nil), &Error{1, "integer 9223372036854775808 is out of range"}),
+		Args("a =").Rets(m(nil), &Error{1, "expected value, found end of input"}),
+	)
+}
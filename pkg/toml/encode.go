@@ -0,0 +1,217 @@
+package toml
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Encode encodes a table as a TOML document. Keys are sorted, and nested
+// tables are written as [headers] after the other keys of their parent table.
+func Encode(m map[string]any) (string, error) {
+	e := &encoder{}
+	err := e.table(nil, m, false)
+	if err != nil {
+		return "", err
+	}
+	return e.sb.String(), nil
+}
+
+type encoder struct {
+	sb strings.Builder
+}
+
+// Writes the body of a table, followed by its sub-tables and arrays of
+// tables. If header is true, the body is preceded by a header, unless the
+// table only contains sub-tables.
+func (e *encoder) table(path []string, m map[string]any, header bool) error {
+	keys := sortedKeys(m)
+	var plain, tables, arrays []string
+	for _, k := range keys {
+		switch v := m[k].(type) {
+		case map[string]any:
+			tables = append(tables, k)
+		case []any:
+			if isArrayOfTables(v) {
+				arrays = append(arrays, k)
+			} else {
+				plain = append(plain, k)
+			}
+		default:
+			plain = append(plain, k)
+		}
+	}
+
+	if header && (len(plain) > 0 || len(keys) == 0) {
+		e.newSection()
+		e.sb.WriteString("[" + joinKeys(path) + "]\n")
+	}
+	for _, k := range plain {
+		e.sb.WriteString(quoteKey(k) + " = ")
+		if err := e.value(m[k]); err != nil {
+			return err
+		}
+		e.sb.WriteByte('\n')
+	}
+	for _, k := range tables {
+		err := e.table(appendKey(path, k), m[k].(map[string]any), true)
+		if err != nil {
+			return err
+		}
+	}
+	for _, k := range arrays {
+		subPath := appendKey(path, k)
+		for _, elem := range m[k].([]any) {
+			e.newSection()
+			e.sb.WriteString("[[" + joinKeys(subPath) + "]]\n")
+			err := e.table(subPath, elem.(map[string]any), false)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Separates sections with an empty line.
+func (e *encoder) newSection() {
+	if e.sb.Len() > 0 {
+		e.sb.WriteByte('\n')
+	}
+}
+
+func (e *encoder) value(v any) error {
+	switch v := v.(type) {
+	case string:
+		e.sb.WriteString(quoteString(v))
+	case bool:
+		e.sb.WriteString(strconv.FormatBool(v))
+	case int:
+		e.sb.WriteString(strconv.Itoa(v))
+	case int64:
+		e.sb.WriteString(strconv.FormatInt(v, 10))
+	case *big.Int:
+		if !v.IsInt64() {
+			return fmt.Errorf("integer %s is out of range", v)
+		}
+		e.sb.WriteString(v.String())
+	case float64:
+		e.sb.WriteString(formatFloat(v))
+	case []any:
+		e.sb.WriteByte('[')
+		for i, elem := range v {
+			if i > 0 {
+				e.sb.WriteString(", ")
+			}
+			if err := e.value(elem); err != nil {
+				return err
+			}
+		}
+		e.sb.WriteByte(']')
+	case map[string]any:
+		e.sb.WriteByte('{')
+		for i, k := range sortedKeys(v) {
+			if i > 0 {
+				e.sb.WriteString(",")
+			}
+			e.sb.WriteString(" " + quoteKey(k) + " = ")
+			if err := e.value(v[k]); err != nil {
+				return err
+			}
+		}
+		if len(v) > 0 {
+			e.sb.WriteByte(' ')
+		}
+		e.sb.WriteByte('}')
+	case nil:
+		return fmt.Errorf("null values cannot be encoded in TOML")
+	default:
+		return fmt.Errorf("unsupported type %T", v)
+	}
+	return nil
+}
+
+func isArrayOfTables(a []any) bool {
+	if len(a) == 0 {
+		return false
+	}
+	for _, elem := range a {
+		if _, ok := elem.(map[string]any); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func appendKey(path []string, key string) []string {
+	return append(path[:len(path):len(path)], key)
+}
+
+var bareKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func quoteKey(key string) string {
+	if bareKeyPattern.MatchString(key) {
+		return key
+	}
+	return quoteString(key)
+}
+
+func quoteString(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\r':
+			sb.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&sb, `\u%04X`, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
@@ -0,0 +1,57 @@
+package toml
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+
+	"src.elv.sh/pkg/tt"
+)
+
+func TestEncode(t *testing.T) {
+	tt.Test(t, Encode,
+		Args(m{}).Rets("", error(nil)),
+		Args(m{"s": "a\"\n\x01", "i": 1, "j": int64(2), "b": true,
+			"f": []any{1.0, 1.5, 1e100, math.Inf(-1)}}).Rets(
+			`b = true
+f = [1.0, 1.5, 1e+100, -inf]
+i = 1
+j = 2
+s = "a\"\n\u0001"
+`, error(nil)),
+		Args(m{"a b": 1, "t": m{"x": 1, "u": m{"y": m{}}}, "a": []any{m{"x": 1}, 2}}).Rets(
+			`a = [{ x = 1 }, 2]
+"a b" = 1
+
+[t]
+x = 1
+
+[t.u.y]
+`, error(nil)),
+		Args(m{"p": []any{m{"name": "a", "q": m{"x": 1}}, m{"name": "b"}}}).Rets(
+			`[[p]]
+name = "a"
+
+[p.q]
+x = 1
+
+[[p]]
+name = "b"
+`, error(nil)),
+		Args(m{"a": big.NewInt(3)}).Rets("a = 3\n", error(nil)),
+		Args(m{"a": new(big.Int).Lsh(big.NewInt(1), 64)}).Rets(
+			"", errors.New("integer 18446744073709551616 is out of range")),
+		Args(m{"a": nil}).Rets("", errors.New("null values cannot be encoded in TOML")),
+	)
+}
+
+func TestEncode_RoundTrip(t *testing.T) {
+	doc := m{"a": int64(1), "t": m{"s": "x", "u": m{"f": 1.5}},
+		"p": []any{m{"x": int64(1)}, m{"y": []any{"z"}}}}
+	s, err := Encode(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt.Test(t, Decode, Args(s).Rets(doc, error(nil)))
+}
@@ -0,0 +1,1070 @@
+// Package yaml implements a decoder and an encoder for YAML documents.
+//
+// The decoder supports the commonly used subset of YAML 1.2: block and flow
+// collections, plain, quoted and block scalars, comments, anchors, aliases,
+// merge keys, tags of the core schema and streams of multiple documents.
+// Explicit keys ("? key") and keys that are collections are not supported.
+//
+// Documents are decoded into the following Go types:
+//
+//   - Mappings are decoded into map[string]any; keys are always strings.
+//   - Sequences are decoded into []any.
+//   - Scalars are resolved with the core schema, and decoded into nil, bool,
+//     int64 (or *big.Int if it doesn't fit), float64 or string.
+//
+// The encoder accepts the same types, and int.
+package yaml
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Error is returned when a document can't be decoded.
+type Error struct {
+	Line    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// Decode decodes all the documents in a YAML stream.
+func Decode(src string) (docs []any, err error) {
+	src = strings.TrimPrefix(src, "\uFEFF")
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	p := &parser{src: src}
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(*Error); ok {
+				err = e
+				return
+			}
+			panic(r)
+		}
+	}()
+	return p.stream(), nil
+}
+
+type parser struct {
+	src     string
+	pos     int
+	anchors map[string]any
+	// Number of nodes that aliases have expanded to so far.
+	aliasNodes int
+}
+
+// Maximum number of nodes that aliases in a stream can expand to. Aliases
+// share the value of their anchors, but consumers of the decoded documents see
+// them as copies, so a small document with nested aliases can expand to a huge
+// number of nodes (the "billion laughs" attack).
+const maxAliasNodes = 1_000_000
+
+// Contexts of block nodes.
+type context int
+
+const (
+	docContext context = iota
+	mapValueContext
+	seqItemContext
+)
+
+func (p *parser) fail(format string, args ...any) {
+	line := strings.Count(p.src[:p.pos], "\n") + 1
+	panic(&Error{line, fmt.Sprintf(format, args...)})
+}
+
+func (p *parser) eof() bool { return p.pos >= len(p.src) }
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *parser) peekAt(i int) byte {
+	if p.pos+i >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos+i]
+}
+
+func (p *parser) hasPrefix(s string) bool { return strings.HasPrefix(p.src[p.pos:], s) }
+
+func (p *parser) col() int {
+	return p.pos - (strings.LastIndexByte(p.src[:p.pos], '\n') + 1)
+}
+
+func isBlank(b byte) bool { return b == ' ' || b == '\t' }
+
+// Reports whether b ends a token, being a whitespace, a newline or the end of
+// input (represented by 0).
+func isSep(b byte) bool { return b == ' ' || b == '\t' || b == '\n' || b == 0 }
+
+func (p *parser) atEOL() bool { return p.eof() || p.peek() == '\n' }
+
+func (p *parser) skipSpaces() {
+	for isBlank(p.peek()) {
+		p.pos++
+	}
+}
+
+// Skips a comment. A comment must be preceded by whitespace unless it starts
+// a line.
+func (p *parser) skipComment() {
+	if p.peek() == '#' && (p.col() == 0 || isBlank(p.src[p.pos-1])) {
+		for !p.atEOL() {
+			p.pos++
+		}
+	}
+}
+
+// Skips spaces and a comment, and consumes the end of the line, moving to the
+// start of the next line with content.
+func (p *parser) endLine() {
+	p.skipSpaces()
+	p.skipComment()
+	if !p.atEOL() {
+		if p.peek() == ':' {
+			p.fail("mapping values are not allowed here")
+		}
+		p.fail("unexpected %s", p.found())
+	}
+	p.nextContentLine()
+}
+
+// Moves from the end of a line to the first non-blank character of the next
+// line that is not empty or a comment.
+func (p *parser) nextContentLine() {
+	if p.eof() {
+		return
+	}
+	p.pos++
+	p.skipEmptyLines()
+}
+
+// Moves from the start of a line to the first non-blank character of the
+// first line that is not empty or a comment.
+func (p *parser) skipEmptyLines() {
+	for !p.eof() {
+		lineStart := p.pos
+		for p.peek() == ' ' {
+			p.pos++
+		}
+		if p.peek() == '\t' {
+			for isBlank(p.peek()) {
+				p.pos++
+			}
+			if !p.atEOL() && p.peek() != '#' {
+				p.pos = lineStart
+				p.fail("tabs cannot be used for indentation")
+			}
+		}
+		p.skipComment()
+		if !p.atEOL() {
+			return
+		}
+		if p.eof() {
+			return
+		}
+		p.pos++
+	}
+}
+
+func isDocMarker(line string) bool {
+	return (strings.HasPrefix(line, "---") || strings.HasPrefix(line, "...")) &&
+		isSep(byteAt(line, 3))
+}
+
+// Reports whether the current position is at a document marker ("---" or
+// "...") at the start of a line.
+func (p *parser) atDocMarker() bool {
+	return p.col() == 0 && isDocMarker(p.src[p.pos:])
+}
+
+// Reports whether the current position is at the end of the current block
+// structure.
+func (p *parser) atBlockEnd() bool { return p.eof() || p.atDocMarker() }
+
+func (p *parser) atSeqIndicator() bool { return p.peek() == '-' && isSep(p.peekAt(1)) }
+
+// Describes the character at the current position for error messages.
+func (p *parser) found() string {
+	switch {
+	case p.eof():
+		return "end of input"
+	case p.peek() == '\n':
+		return "newline"
+	default:
+		r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+		return strconv.QuoteRune(r)
+	}
+}
+
+func (p *parser) stream() []any {
+	docs := []any{}
+	p.skipEmptyLines()
+	for !p.eof() {
+		for p.col() == 0 && p.peek() == '%' {
+			// Directives are ignored.
+			for !p.atEOL() {
+				p.pos++
+			}
+			p.nextContentLine()
+		}
+		p.anchors = make(map[string]any)
+		if p.atDocMarker() && p.hasPrefix("...") {
+			p.pos += 3
+			p.endLine()
+			continue
+		}
+		var doc any
+		if p.atDocMarker() {
+			p.pos += 3
+			doc = p.blockNode(-1, docContext)
+		} else if p.eof() {
+			break
+		} else {
+			doc = p.content(-1, docContext, true)
+		}
+		docs = append(docs, doc)
+		if p.atDocMarker() && p.hasPrefix("...") {
+			p.pos += 3
+			p.endLine()
+		} else if !p.atBlockEnd() {
+			p.fail("unexpected %s", p.found())
+		}
+	}
+	return docs
+}
+
+// Parses a block node whose content is indented more than parentIndent. It is
+// called after the indicator that introduces the node: the ":" after a key, the
+// "-" of a sequence item, or the "---" of a document.
+func (p *parser) blockNode(parentIndent int, ctx context) any {
+	p.skipSpaces()
+	anchor, tag := p.properties()
+	p.skipComment()
+	if !p.atEOL() {
+		return p.withProperties(anchor, tag, p.content(parentIndent, ctx, ctx != mapValueContext))
+	}
+	p.nextContentLine()
+	var v any
+	if !p.atBlockEnd() {
+		if p.col() > parentIndent {
+			v = p.content(parentIndent, ctx, true)
+		} else if ctx == mapValueContext && p.col() == parentIndent && p.atSeqIndicator() {
+			// A sequence that is the value of a mapping entry may have the same
+			// indentation as the key.
+			v = p.blockSequence(p.col())
+		}
+	}
+	return p.withProperties(anchor, tag, v)
+}
+
+// Parses the content of a node. If allowCollections is false, the content
+// can't be a block collection; this is the case for the value of a mapping
+// entry on the same line as the key.
+func (p *parser) content(parentIndent int, ctx context, allowCollections bool) any {
+	anchor, tag := p.properties()
+	if anchor != "" || tag != "" {
+		p.skipComment()
+		if p.atEOL() {
+			p.nextContentLine()
+			if p.atBlockEnd() || p.col() <= parentIndent {
+				return p.withProperties(anchor, tag, nil)
+			}
+		}
+		return p.withProperties(anchor, tag, p.content(parentIndent, ctx, allowCollections))
+	}
+
+	switch {
+	case p.peek() == '?' && isSep(p.peekAt(1)):
+		p.fail("explicit keys are not supported")
+	case p.peek() == '|' || p.peek() == '>':
+		return p.blockScalar(parentIndent)
+	case allowCollections && p.atSeqIndicator():
+		return p.blockSequence(p.col())
+	case p.isImplicitKey():
+		if !allowCollections {
+			p.fail("mapping values are not allowed here")
+		}
+		return p.blockMapping(p.col())
+	case p.peek() == '[' || p.peek() == '{':
+		v := p.flowNode()
+		p.endLine()
+		return v
+	case p.peek() == '*':
+		v := p.alias()
+		p.endLine()
+		return v
+	case p.peek() == '"':
+		s := p.doubleQuoted()
+		p.endLine()
+		return s
+	case p.peek() == '\'':
+		s := p.singleQuoted()
+		p.endLine()
+		return s
+	}
+	s := p.plainScalar(parentIndent)
+	p.endLine()
+	return resolve(s)
+}
+
+func (p *parser) blockSequence(indent int) []any {
+	items := []any{}
+	for !p.atBlockEnd() && p.col() == indent && p.atSeqIndicator() {
+		p.pos++
+		items = append(items, p.blockNode(indent, seqItemContext))
+	}
+	if !p.atBlockEnd() && p.col() > indent {
+		p.fail("bad indentation of a sequence entry")
+	}
+	return items
+}
+
+func (p *parser) blockMapping(indent int) map[string]any {
+	m := make(map[string]any)
+	var merges []any
+	for !p.atBlockEnd() && p.col() == indent && !p.atSeqIndicator() {
+		keyPos := p.pos
+		key, isMerge := p.implicitKey()
+		value := p.blockNode(indent, mapValueContext)
+		if isMerge {
+			merges = append(merges, value)
+			continue
+		}
+		if _, exists := m[key]; exists {
+			p.pos = keyPos
+			p.fail("duplicate key %s", strconv.Quote(key))
+		}
+		m[key] = value
+	}
+	if !p.atBlockEnd() && p.col() > indent {
+		p.fail("bad indentation of a mapping entry")
+	}
+	for _, merge := range merges {
+		p.merge(m, merge)
+	}
+	return m
+}
+
+// Merges the entries of a mapping or a sequence of mappings that are the value
+// of a merge key ("<<") into m. Existing keys take precedence.
+func (p *parser) merge(m map[string]any, v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, elem := range v {
+			if _, exists := m[k]; !exists {
+				m[k] = elem
+			}
+		}
+	case []any:
+		for _, elem := range v {
+			if _, ok := elem.(map[string]any); !ok {
+				p.fail("merge key must refer to a mapping or a sequence of mappings")
+			}
+			p.merge(m, elem)
+		}
+	default:
+		p.fail("merge key must refer to a mapping or a sequence of mappings")
+	}
+}
+
+// Reports whether the current line starts with an implicit key followed by
+// ":", without consuming anything.
+func (p *parser) isImplicitKey() bool {
+	i := p.pos
+	src := p.src
+	switch {
+	case i >= len(src):
+		return false
+	case src[i] == '"' || src[i] == '\'':
+		quote := src[i]
+		for i++; ; i++ {
+			if i >= len(src) || src[i] == '\n' {
+				return false
+			}
+			if quote == '"' && src[i] == '\\' {
+				i++
+			} else if src[i] == quote {
+				if quote == '\'' && i+1 < len(src) && src[i+1] == '\'' {
+					i++
+					continue
+				}
+				break
+			}
+		}
+		for i++; i < len(src) && isBlank(src[i]); i++ {
+		}
+		return i < len(src) && src[i] == ':' && (i+1 == len(src) || isSep(src[i+1]))
+	case !isPlainStart(src[i], byteAt(src, i+1), false):
+		return false
+	}
+	for ; i < len(src) && src[i] != '\n'; i++ {
+		if src[i] == ':' && (i+1 == len(src) || isSep(src[i+1])) {
+			return true
+		}
+		if src[i] == '#' && isBlank(src[i-1]) {
+			return false
+		}
+	}
+	return false
+}
+
+func byteAt(s string, i int) byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return 0
+}
+
+// Parses an implicit key and the ":" after it. Returns whether the key is the
+// merge key "<<".
+func (p *parser) implicitKey() (string, bool) {
+	if !p.isImplicitKey() {
+		if p.peek() == '?' && isSep(p.peekAt(1)) {
+			p.fail("explicit keys are not supported")
+		}
+		p.fail("could not find expected ':'")
+	}
+	var key string
+	isMerge := false
+	switch p.peek() {
+	case '"':
+		key = p.doubleQuoted()
+	case '\'':
+		key = p.singleQuoted()
+	default:
+		start := p.pos
+		for !(p.peek() == ':' && isSep(p.peekAt(1))) {
+			p.pos++
+		}
+		key = strings.TrimRight(p.src[start:p.pos], " \t")
+		isMerge = key == "<<"
+	}
+	p.skipSpaces()
+	p.pos++ // ':'
+	return key, isMerge
+}
+
+// Parses an anchor and a tag, in any order.
+func (p *parser) properties() (anchor, tag string) {
+	for {
+		switch {
+		case p.peek() == '&' && anchor == "":
+			p.pos++
+			anchor = p.name()
+		case p.peek() == '!' && tag == "":
+			start := p.pos
+			for !isSep(p.peek()) {
+				p.pos++
+			}
+			tag = p.src[start:p.pos]
+		default:
+			return anchor, tag
+		}
+		p.skipSpaces()
+	}
+}
+
+// Parses the name of an anchor or alias.
+func (p *parser) name() string {
+	start := p.pos
+	for !isSep(p.peek()) && !strings.ContainsRune(",[]{}", rune(p.peek())) {
+		p.pos++
+	}
+	if p.pos == start {
+		p.fail("expected anchor name")
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *parser) alias() any {
+	p.pos++
+	name := p.name()
+	v, ok := p.anchors[name]
+	if !ok {
+		p.fail("unknown anchor %s", name)
+	}
+	p.aliasNodes += countNodes(v, maxAliasNodes-p.aliasNodes+1)
+	if p.aliasNodes > maxAliasNodes {
+		p.fail("aliases expand to more than %d nodes", maxAliasNodes)
+	}
+	return v
+}
+
+// Returns the number of nodes in v, counting aliased nodes each time they
+// appear. Counting stops once it exceeds limit, so that the time spent is
+// bounded by limit.
+func countNodes(v any, limit int) int {
+	n := 1
+	switch v := v.(type) {
+	case []any:
+		for _, elem := range v {
+			if n > limit {
+				break
+			}
+			n += countNodes(elem, limit-n)
+		}
+	case map[string]any:
+		for _, elem := range v {
+			if n > limit {
+				break
+			}
+			n += countNodes(elem, limit-n)
+		}
+	}
+	return n
+}
+
+// Applies a tag to a node and records the node under an anchor.
+func (p *parser) withProperties(anchor, tag string, v any) any {
+	if tag != "" {
+		v = p.applyTag(tag, v)
+	}
+	if anchor != "" {
+		p.anchors[anchor] = v
+	}
+	return v
+}
+
+// Applies a tag of the core schema. Other tags are ignored.
+func (p *parser) applyTag(tag string, v any) any {
+	text := ""
+	switch v := v.(type) {
+	case nil:
+	case string:
+		text = v
+	case bool, int64, *big.Int, float64:
+		text = fmt.Sprint(v)
+	default:
+		return v
+	}
+	switch tag {
+	case "!!str", "!":
+		return text
+	case "!!null":
+		return nil
+	case "!!bool":
+		if b, ok := resolve(text).(bool); ok {
+			return b
+		}
+	case "!!int":
+		switch i := resolve(text).(type) {
+		case int64, *big.Int:
+			return i
+		}
+	case "!!float":
+		switch f := resolve(text).(type) {
+		case float64:
+			return f
+		case int64:
+			return float64(f)
+		case *big.Int:
+			f64, _ := new(big.Float).SetInt(f).Float64()
+			return f64
+		}
+	default:
+		return v
+	}
+	p.fail("cannot decode %s as %s", strconv.Quote(text), tag)
+	return nil
+}
+
+// Reports whether a plain scalar can start with c followed by next.
+func isPlainStart(c, next byte, flow bool) bool {
+	switch c {
+	case '-', '?', ':':
+		return !isSep(next) && !(flow && strings.IndexByte(",[]{}", next) >= 0)
+	case ',', '[', ']', '{', '}', '#', '&', '*', '!', '|', '>', '\'', '"', '%', '@', '`',
+		' ', '\t', '\n', 0:
+		return false
+	}
+	return true
+}
+
+// Parses a plain scalar in block context. Continuation lines must be indented
+// more than parentIndent; they are folded into the scalar.
+func (p *parser) plainScalar(parentIndent int) string {
+	if !isPlainStart(p.peek(), p.peekAt(1), false) {
+		p.fail("unexpected %s", p.found())
+	}
+	var sb strings.Builder
+	sb.WriteString(p.plainLine(false))
+	for p.atEOL() && !p.eof() {
+		// Look ahead for a continuation line.
+		save := p.pos
+		emptyLines := 0
+		p.pos++
+		for {
+			for isBlank(p.peek()) {
+				p.pos++
+			}
+			if p.peek() != '\n' {
+				break
+			}
+			emptyLines++
+			p.pos++
+		}
+		if p.eof() || p.col() <= parentIndent || p.atDocMarker() || p.peek() == '#' {
+			p.pos = save
+			break
+		}
+		if emptyLines == 0 {
+			sb.WriteByte(' ')
+		} else {
+			sb.WriteString(strings.Repeat("\n", emptyLines))
+		}
+		sb.WriteString(p.plainLine(false))
+	}
+	return sb.String()
+}
+
+// Parses the part of a plain scalar on the current line, stopping before a
+// ": ", a comment, the end of the line, or flow indicators in flow context.
+// Trailing whitespace is not included.
+func (p *parser) plainLine(flow bool) string {
+	start := p.pos
+	end := p.pos
+	for !p.atEOL() {
+		c := p.peek()
+		if c == ':' && (isSep(p.peekAt(1)) || flow && strings.IndexByte(",[]{}", p.peekAt(1)) >= 0) {
+			break
+		}
+		if c == '#' && p.pos > start && isBlank(p.src[p.pos-1]) {
+			break
+		}
+		if flow && strings.IndexByte(",[]{}", c) >= 0 {
+			break
+		}
+		p.pos++
+		if !isBlank(c) {
+			end = p.pos
+		}
+	}
+	p.pos = end
+	return p.src[start:end]
+}
+
+// Skips whitespace, newlines and comments in flow context.
+func (p *parser) skipFlowSpace() {
+	for {
+		p.skipSpaces()
+		p.skipComment()
+		if p.eof() || p.peek() != '\n' {
+			return
+		}
+		p.pos++
+	}
+}
+
+func (p *parser) flowNode() any {
+	anchor, tag := p.properties()
+	var v any
+	switch p.peek() {
+	case '[':
+		v = p.flowSequence()
+	case '{':
+		v = p.flowMapping()
+	case '*':
+		v = p.alias()
+	case '"':
+		v = p.doubleQuoted()
+	case '\'':
+		v = p.singleQuoted()
+	case ',', ']', '}':
+		v = nil
+	default:
+		if !isPlainStart(p.peek(), p.peekAt(1), true) {
+			p.fail("unexpected %s", p.found())
+		}
+		v = resolve(p.plainLine(true))
+	}
+	return p.withProperties(anchor, tag, v)
+}
+
+func (p *parser) flowSequence() []any {
+	p.pos++
+	items := []any{}
+	for {
+		p.skipFlowSpace()
+		if p.peek() == ']' {
+			p.pos++
+			return items
+		}
+		item := p.flowNode()
+		p.skipFlowSpace()
+		if p.peek() == ':' {
+			// A single-pair mapping.
+			p.pos++
+			p.skipFlowSpace()
+			item = map[string]any{p.flowKey(item): p.flowNode()}
+			p.skipFlowSpace()
+		}
+		items = append(items, item)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			p.fail("expected ',' or ']', found %s", p.found())
+		}
+	}
+}
+
+func (p *parser) flowMapping() map[string]any {
+	p.pos++
+	m := make(map[string]any)
+	for {
+		p.skipFlowSpace()
+		if p.peek() == '}' {
+			p.pos++
+			return m
+		}
+		keyPos := p.pos
+		key := p.flowKey(p.flowNode())
+		p.skipFlowSpace()
+		var value any
+		if p.peek() == ':' {
+			p.pos++
+			p.skipFlowSpace()
+			value = p.flowNode()
+			p.skipFlowSpace()
+		}
+		if _, exists := m[key]; exists {
+			p.pos = keyPos
+			p.fail("duplicate key %s", strconv.Quote(key))
+		}
+		m[key] = value
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+		default:
+			p.fail("expected ',' or '}', found %s", p.found())
+		}
+	}
+}
+
+// Converts a node used as a key in flow context to a string.
+func (p *parser) flowKey(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool, int64, *big.Int, float64:
+		return fmt.Sprint(v)
+	default:
+		p.fail("collections cannot be used as keys")
+		return ""
+	}
+}
+
+func (p *parser) doubleQuoted() string {
+	p.pos++
+	var buf []byte
+	// Number of whitespace characters at the end of buf that come from the
+	// source literally, and are trimmed when a line is folded.
+	trailing := 0
+	for {
+		if p.eof() {
+			p.fail("unterminated string")
+		}
+		c := p.peek()
+		switch c {
+		case '"':
+			p.pos++
+			return string(buf)
+		case '\n':
+			buf = p.fold(buf[:len(buf)-trailing])
+			trailing = 0
+		case '\\':
+			if p.peekAt(1) == '\n' {
+				// An escaped line break is removed along with the leading
+				// whitespace of the next line.
+				p.pos += 2
+				p.skipSpaces()
+				trailing = 0
+				continue
+			}
+			buf = p.escape(buf)
+			trailing = 0
+		default:
+			buf = append(buf, c)
+			p.pos++
+			if isBlank(c) {
+				trailing++
+			} else {
+				trailing = 0
+			}
+		}
+	}
+}
+
+func (p *parser) singleQuoted() string {
+	p.pos++
+	var buf []byte
+	trailing := 0
+	for {
+		if p.eof() {
+			p.fail("unterminated string")
+		}
+		c := p.peek()
+		switch {
+		case c == '\'' && p.peekAt(1) == '\'':
+			buf = append(buf, '\'')
+			p.pos += 2
+			trailing = 0
+		case c == '\'':
+			p.pos++
+			return string(buf)
+		case c == '\n':
+			buf = p.fold(buf[:len(buf)-trailing])
+			trailing = 0
+		default:
+			buf = append(buf, c)
+			p.pos++
+			if isBlank(c) {
+				trailing++
+			} else {
+				trailing = 0
+			}
+		}
+	}
+}
+
+// Folds a line break in a quoted scalar, at the current position. A single
+// line break becomes a space, and each following empty line becomes a
+// newline.
+func (p *parser) fold(buf []byte) []byte {
+	emptyLines := 0
+	for {
+		p.pos++
+		p.skipSpaces()
+		if p.peek() != '\n' {
+			break
+		}
+		emptyLines++
+	}
+	if p.atDocMarker() {
+		p.fail("unterminated string")
+	}
+	if emptyLines == 0 {
+		return append(buf, ' ')
+	}
+	for i := 0; i < emptyLines; i++ {
+		buf = append(buf, '\n')
+	}
+	return buf
+}
+
+var simpleEscapes = map[byte]string{
+	'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n",
+	'v': "\v", 'f': "\f", 'r': "\r", 'e': "\x1b", ' ': " ", '"': "\"",
+	'/': "/", '\\': "\\", 'N': "\u0085", '_': "\u00a0", 'L': "\u2028",
+	'P': "\u2029",
+}
+
+func (p *parser) escape(buf []byte) []byte {
+	c := p.peekAt(1)
+	if s, ok := simpleEscapes[c]; ok {
+		p.pos += 2
+		return append(buf, s...)
+	}
+	n := map[byte]int{'x': 2, 'u': 4, 'U': 8}[c]
+	if n == 0 {
+		p.pos++
+		p.fail("invalid escape sequence \\%s", p.found())
+	}
+	if p.pos+2+n > len(p.src) {
+		p.fail("invalid escape sequence")
+	}
+	code, err := strconv.ParseUint(p.src[p.pos+2:p.pos+2+n], 16, 32)
+	if err != nil || !utf8.ValidRune(rune(code)) {
+		p.fail("invalid escape sequence")
+	}
+	p.pos += 2 + n
+	return utf8.AppendRune(buf, rune(code))
+}
+
+type chomping int
+
+const (
+	clip chomping = iota
+	strip
+	keep
+)
+
+// Parses a literal or folded block scalar.
+func (p *parser) blockScalar(parentIndent int) string {
+	folded := p.peek() == '>'
+	p.pos++
+	chomp, indent := clip, -1
+	for i := 0; i < 2; i++ {
+		switch c := p.peek(); {
+		case c == '-' && chomp == clip:
+			chomp = strip
+		case c == '+' && chomp == clip:
+			chomp = keep
+		case '1' <= c && c <= '9' && indent == -1:
+			indent = max(parentIndent, 0) + int(c-'0')
+		default:
+			continue
+		}
+		p.pos++
+	}
+	p.skipSpaces()
+	p.skipComment()
+	if !p.atEOL() {
+		p.fail("unexpected %s in block scalar header", p.found())
+	}
+
+	// Collect the content lines. The indentation of the content is detected
+	// from the first non-empty line if there is no indentation indicator.
+	var lines []string
+	terminated := false
+	for p.pos+1 < len(p.src) {
+		rest := p.src[p.pos+1:]
+		lineLen := strings.IndexByte(rest, '\n')
+		if lineLen == -1 {
+			lineLen = len(rest)
+		}
+		line := rest[:lineLen]
+		trimmed := strings.TrimLeft(line, " ")
+		spaces := len(line) - len(trimmed)
+		if trimmed != "" {
+			if indent == -1 {
+				if spaces <= parentIndent {
+					break
+				}
+				indent = spaces
+			}
+			if spaces < indent || spaces == 0 && isDocMarker(line) {
+				break
+			}
+		}
+		if indent >= 0 && len(line) >= indent {
+			lines = append(lines, line[indent:])
+		} else {
+			lines = append(lines, "")
+		}
+		p.pos += 1 + lineLen
+		terminated = p.pos < len(p.src)
+	}
+	if !p.eof() {
+		p.pos++
+		p.skipEmptyLines()
+	}
+
+	// Separate trailing empty lines, and count the line breaks after the
+	// content.
+	n := len(lines)
+	for n > 0 && lines[n-1] == "" {
+		n--
+	}
+	breaks := len(lines) - n
+	if terminated {
+		breaks++
+	}
+	lines = lines[:n]
+
+	var text string
+	if folded {
+		text = foldLines(lines)
+	} else {
+		text = strings.Join(lines, "\n")
+	}
+	switch chomp {
+	case clip:
+		if n > 0 && breaks > 0 {
+			text += "\n"
+		}
+	case keep:
+		text += strings.Repeat("\n", breaks)
+	}
+	return text
+}
+
+// Joins the lines of a folded block scalar. Line breaks between lines are
+// folded into spaces, except around more-indented lines and empty lines.
+func foldLines(lines []string) string {
+	var sb strings.Builder
+	emptyLines := 0
+	first, prevMoreIndented := true, false
+	for _, line := range lines {
+		if line == "" {
+			emptyLines++
+			continue
+		}
+		moreIndented := isBlank(line[0])
+		switch {
+		case first:
+			sb.WriteString(strings.Repeat("\n", emptyLines))
+		case emptyLines == 0 && !moreIndented && !prevMoreIndented:
+			sb.WriteByte(' ')
+		case !moreIndented && !prevMoreIndented:
+			sb.WriteString(strings.Repeat("\n", emptyLines))
+		default:
+			sb.WriteString(strings.Repeat("\n", emptyLines+1))
+		}
+		sb.WriteString(line)
+		emptyLines, first, prevMoreIndented = 0, false, moreIndented
+	}
+	return sb.String()
+}
+
+var (
+	decIntPattern = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	octIntPattern = regexp.MustCompile(`^0o[0-7]+$`)
+	hexIntPattern = regexp.MustCompile(`^0x[0-9a-fA-F]+$`)
+	floatPattern  = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+)
+
+// Resolves a plain scalar with the core schema.
+func resolve(s string) any {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return math.Inf(1)
+	case "-.inf", "-.Inf", "-.INF":
+		return math.Inf(-1)
+	case ".nan", ".NaN", ".NAN":
+		return math.NaN()
+	}
+	switch {
+	case decIntPattern.MatchString(s):
+		return parseInt(strings.TrimPrefix(s, "+"), 10)
+	case octIntPattern.MatchString(s):
+		return parseInt(s[2:], 8)
+	case hexIntPattern.MatchString(s):
+		return parseInt(s[2:], 16)
+	case floatPattern.MatchString(s):
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+	return s
+}
+
+func parseInt(s string, base int) any {
+	z, _ := new(big.Int).SetString(s, base)
+	if z.IsInt64() {
+		return z.Int64()
+	}
+	return z
+}
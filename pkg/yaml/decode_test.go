@@ -0,0 +1,162 @@
+package yaml
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"src.elv.sh/pkg/tt"
+)
+
+type m = map[string]any
+type a = []any
+
+var Args = tt.Args
+
+func TestDecode(t *testing.T) {
+	tt.Test(t, Decode,
+		// Streams and documents
+		Args("").Rets(a{}, error(nil)),
+		Args("# comment\n").Rets(a{}, error(nil)),
+		Args("foo").Rets(a{"foo"}, error(nil)),
+		Args("%YAML 1.2\n---\nfoo\n...\n---\nbar\n---\n").Rets(a{"foo", "bar", nil}, error(nil)),
+		Args("--- foo\n--- [1]").Rets(a{"foo", a{int64(1)}}, error(nil)),
+		Args("a: 1\r\nb: 2\r\n").Rets(a{m{"a": int64(1), "b": int64(2)}}, error(nil)),
+
+		// Block collections
+		Args(`
+a: 1 # comment
+b:
+  c: x
+  d:
+  - y
+  - z
+e:
+    - f: 1
+      g: 2
+    - - h
+      - i
+    -
+    - j
+`).Rets(a{m{"a": int64(1), "b": m{"c": "x", "d": a{"y", "z"}},
+			"e": a{m{"f": int64(1), "g": int64(2)}, a{"h", "i"}, nil, "j"}}}, error(nil)),
+		Args("- a\n- b: c\n  d: e\n").Rets(a{a{"a", m{"b": "c", "d": "e"}}}, error(nil)),
+		Args(`"quoted key": 1
+'single': 2
+key with spaces: 3
+"": 4`).Rets(a{m{"quoted key": int64(1), "single": int64(2),
+			"key with spaces": int64(3), "": int64(4)}}, error(nil)),
+		Args("a:\nb: ~").Rets(a{m{"a": nil, "b": nil}}, error(nil)),
+
+		// Scalars
+		Args("[~, null, true, False, 12, -3, +4, 0o17, 0xff, 1.5, -.5, 1e3, .inf, -.Inf, 12345678901234567890]").Rets(
+			a{a{nil, nil, true, false, int64(12), int64(-3), int64(4), int64(15), int64(255),
+				1.5, -0.5, 1000.0, math.Inf(1), math.Inf(-1), bigInt("12345678901234567890")}}, error(nil)),
+		Args("[yes, 1.2.3, 0x, a b, http://x.y/z?a=b, -1-2]").Rets(
+			a{a{"yes", "1.2.3", "0x", "a b", "http://x.y/z?a=b", "-1-2"}}, error(nil)),
+		Args("a: plain\n  multi-line\n\n  scalar\nb: x").Rets(
+			a{m{"a": "plain multi-line\nscalar", "b": "x"}}, error(nil)),
+		Args(`"a\tb\"\\\x41\u00e9\U0001F600\
+  c d
+  
+  e"`).Rets(a{"a\tb\"\\Aé😀c d\ne"}, error(nil)),
+		Args(`'it''s
+  folded'`).Rets(a{"it's folded"}, error(nil)),
+
+		// Block scalars
+		Args("a: |\n  line 1\n    line 2\n\n  line 3\n\nb: 1").Rets(
+			a{m{"a": "line 1\n  line 2\n\nline 3\n", "b": int64(1)}}, error(nil)),
+		Args("a: |-\n  x\n\n").Rets(a{m{"a": "x"}}, error(nil)),
+		Args("a: |+\n  x\n\n").Rets(a{m{"a": "x\n\n"}}, error(nil)),
+		Args("a: |\n  x").Rets(a{m{"a": "x"}}, error(nil)),
+		Args("a: |2\n   x\n  y\n").Rets(a{m{"a": " x\ny\n"}}, error(nil)),
+		Args("- >\n  folded\n  text\n\n  next\n    more\n  last\n- x").Rets(
+			a{a{"folded text\nnext\n  more\nlast\n", "x"}}, error(nil)),
+		Args("--- |\nfoo\n--- >-\nbar\n baz").Rets(a{"foo\n", "bar\n baz"}, error(nil)),
+
+		// Flow collections
+		Args(`{a: 1, "b": [x, 'y', {c: d}], e, f: }`).Rets(
+			a{m{"a": int64(1), "b": a{"x", "y", m{"c": "d"}}, "e": nil, "f": nil}}, error(nil)),
+		Args("a: [1,\n  2, # comment\n  ]\nb: {}\nc: []").Rets(
+			a{m{"a": a{int64(1), int64(2)}, "b": m{}, "c": a{}}}, error(nil)),
+		Args(`{"json":true,"n":[1,2.5]}`).Rets(a{m{"json": true, "n": a{int64(1), 2.5}}}, error(nil)),
+		Args("[a: 1, b]").Rets(a{a{m{"a": int64(1)}, "b"}}, error(nil)),
+
+		// Anchors, aliases, tags and merge keys
+		Args(`
+base: &base
+  x: 1
+  y: 2
+other: &o {z: 3}
+derived:
+  <<: [*base, *o]
+  y: 20
+list: [&v value, *v]
+tagged: [!!str 1, !!float 2, !!int "3", !custom x]
+`).Rets(a{m{
+			"base":    m{"x": int64(1), "y": int64(2)},
+			"other":   m{"z": int64(3)},
+			"derived": m{"x": int64(1), "y": int64(20), "z": int64(3)},
+			"list":    a{"value", "value"},
+			"tagged":  a{"1", 2.0, int64(3), "x"},
+		}}, error(nil)),
+
+		// Errors
+		Args("a: 1\na: 2").Rets(a(nil), &Error{2, `duplicate key "a"`}),
+		Args("a: b: c").Rets(a(nil), &Error{1, "mapping values are not allowed here"}),
+		Args("a:\n  - 1\n - 2").Rets(a(nil), &Error{3, "bad indentation of a mapping entry"}),
+		Args("- a:\n    - 1\n   - 2").Rets(a(nil), &Error{3, "bad indentation of a mapping entry"}),
+		Args("a:\n    - 1\n  - 2").Rets(a(nil), &Error{3, "bad indentation of a mapping entry"}),
+		Args("a: 1\n b: 2").Rets(a(nil), &Error{2, "mapping values are not allowed here"}),
+		Args("a: 1\nfoo").Rets(a(nil), &Error{2, "could not find expected ':'"}),
+		Args("- 1\na: 2").Rets(a(nil), &Error{2, "unexpected 'a'"}),
+		Args("a: *x").Rets(a(nil), &Error{1, "unknown anchor x"}),
+		Args(`"abc`).Rets(a(nil), &Error{1, "unterminated string"}),
+		Args(`"\q"`).Rets(a(nil), &Error{1, `invalid escape sequence \'q'`}),
+		Args("[1, 2").Rets(a(nil), &Error{1, "expected ',' or ']', found end of input"}),
+		Args("a:\n\t- 1").Rets(a(nil), &Error{2, "tabs cannot be used for indentation"}),
+		Args("? a\n: b").Rets(a(nil), &Error{1, "explicit keys are not supported"}),
+		Args("a: !!int x").Rets(a(nil), &Error{1, `cannot decode "x" as !!int`}),
+	)
+}
+
+func bigInt(s string) *big.Int {
+	z, _ := new(big.Int).SetString(s, 10)
+	return z
+}
+
+func TestDecode_NaN(t *testing.T) {
+	docs, err := Decode(".nan")
+	if err != nil || len(docs) != 1 {
+		t.Fatalf("got (%v, %v)", docs, err)
+	}
+	if f, ok := docs[0].(float64); !ok || !math.IsNaN(f) {
+		t.Errorf("got %v, want NaN", docs[0])
+	}
+}
+
+func TestDecode_AliasExpansionLimit(t *testing.T) {
+	// Each level has 10 aliases to the previous one, so level 9 expands to more
+	// than 10^9 nodes.
+	var sb strings.Builder
+	sb.WriteString("l0: &l0 [x]\n")
+	for i := 1; i <= 9; i++ {
+		fmt.Fprintf(&sb, "l%d: &l%d [", i, i)
+		for j := 0; j < 10; j++ {
+			if j > 0 {
+				sb.WriteString(", ")
+			}
+			fmt.Fprintf(&sb, "*l%d", i-1)
+		}
+		sb.WriteString("]\n")
+	}
+
+	_, err := Decode(sb.String())
+	wantErr := &Error{7, "aliases expand to more than 1000000 nodes"}
+	if !reflect.DeepEqual(err, wantErr) {
+		t.Errorf("got error %v, want %v", err, wantErr)
+	}
+}
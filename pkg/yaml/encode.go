@@ -0,0 +1,240 @@
+package yaml
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Encode encodes a value as a YAML document, using block style for non-empty
+// collections. Keys of mappings are sorted.
+func Encode(v any) (string, error) {
+	e := &encoder{}
+	if err := e.node(v, 0, docContext); err != nil {
+		return "", err
+	}
+	return e.sb.String(), nil
+}
+
+type encoder struct {
+	sb strings.Builder
+}
+
+// Writes a node and the newline after it. The caller has written the key and
+// ":" of a mapping entry or the "-" of a sequence item at the given
+// indentation, or nothing for a document.
+func (e *encoder) node(v any, indent int, ctx context) error {
+	switch v := v.(type) {
+	case map[string]any:
+		if len(v) > 0 {
+			switch ctx {
+			case docContext:
+				return e.mapping(v, 0, true)
+			case seqItemContext:
+				e.sb.WriteByte(' ')
+				return e.mapping(v, indent+2, true)
+			default:
+				e.sb.WriteByte('\n')
+				return e.mapping(v, indent+2, false)
+			}
+		}
+	case []any:
+		if len(v) > 0 {
+			switch ctx {
+			case docContext:
+				return e.sequence(v, 0, true)
+			case seqItemContext:
+				e.sb.WriteByte(' ')
+				return e.sequence(v, indent+2, true)
+			default:
+				e.sb.WriteByte('\n')
+				return e.sequence(v, indent+2, false)
+			}
+		}
+	case string:
+		if literal, ok := literalBlock(v, indent+2); ok {
+			if ctx != docContext {
+				e.sb.WriteByte(' ')
+			}
+			e.sb.WriteString(literal)
+			return nil
+		}
+	}
+	s, err := scalar(v)
+	if err != nil {
+		return err
+	}
+	if ctx != docContext {
+		e.sb.WriteByte(' ')
+	}
+	e.sb.WriteString(s + "\n")
+	return nil
+}
+
+func (e *encoder) mapping(m map[string]any, indent int, firstInline bool) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		if i > 0 || !firstInline {
+			e.sb.WriteString(strings.Repeat(" ", indent))
+		}
+		e.sb.WriteString(quote(k) + ":")
+		if err := e.node(m[k], indent, mapValueContext); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *encoder) sequence(items []any, indent int, firstInline bool) error {
+	for i, item := range items {
+		if i > 0 || !firstInline {
+			e.sb.WriteString(strings.Repeat(" ", indent))
+		}
+		e.sb.WriteString("-")
+		if err := e.node(item, indent, seqItemContext); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the representation of a scalar or an empty collection.
+func scalar(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "null", nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case *big.Int:
+		return v.String(), nil
+	case float64:
+		switch {
+		case math.IsInf(v, 1):
+			return ".inf", nil
+		case math.IsInf(v, -1):
+			return "-.inf", nil
+		case math.IsNaN(v):
+			return ".nan", nil
+		}
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return s, nil
+	case string:
+		return quote(v), nil
+	case map[string]any:
+		return "{}", nil
+	case []any:
+		return "[]", nil
+	default:
+		return "", fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+// Returns a string as a plain scalar if possible, or a double-quoted scalar
+// otherwise.
+func quote(s string) string {
+	if canBePlain(s) {
+		return s
+	}
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"':
+			sb.WriteString(`\"`)
+		case r == '\\':
+			sb.WriteString(`\\`)
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\t':
+			sb.WriteString(`\t`)
+		case r == '\r':
+			sb.WriteString(`\r`)
+		case r < 0x100 && !unicode.IsPrint(r):
+			fmt.Fprintf(&sb, `\x%02x`, r)
+		case !unicode.IsPrint(r) && r != ' ':
+			fmt.Fprintf(&sb, `\u%04x`, r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+func canBePlain(s string) bool {
+	if s == "" || s != strings.TrimSpace(s) || !isPlainStart(s[0], byteAt(s, 1), true) ||
+		strings.HasPrefix(s, "...") || strings.HasSuffix(s, ":") ||
+		strings.Contains(s, ": ") || strings.Contains(s, " #") ||
+		strings.ContainsAny(s, ",[]{}") {
+		return false
+	}
+	if _, isString := resolve(s).(string); !isString {
+		return false
+	}
+	if yaml11Bools[s] {
+		// Quote strings that YAML 1.1 decoders treat as booleans.
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+var yaml11Bools = map[string]bool{
+	"y": true, "Y": true, "yes": true, "Yes": true, "YES": true,
+	"n": true, "N": true, "no": true, "No": true, "NO": true,
+	"on": true, "On": true, "ON": true, "off": true, "Off": true, "OFF": true,
+}
+
+// Returns a multi-line string as a literal block scalar whose content is
+// written with the given indentation, if possible.
+func literalBlock(s string, indent int) (string, bool) {
+	if !strings.Contains(s, "\n") || strings.HasPrefix(s, " ") || strings.HasPrefix(s, "\n") {
+		return "", false
+	}
+	for _, r := range s {
+		if r != '\n' && !unicode.IsPrint(r) {
+			return "", false
+		}
+	}
+	header := "|\n"
+	body := strings.TrimSuffix(s, "\n")
+	switch {
+	case !strings.HasSuffix(s, "\n"):
+		header = "|-\n"
+	case strings.HasSuffix(s, "\n\n"):
+		header = "|+\n"
+	}
+	var sb strings.Builder
+	sb.WriteString(header)
+	for _, line := range strings.Split(body, "\n") {
+		if strings.TrimRight(line, " ") != line {
+			// Trailing spaces are kept in literal blocks, but are easily lost
+			// by editors.
+			return "", false
+		}
+		if line != "" {
+			sb.WriteString(strings.Repeat(" ", indent) + line)
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String(), true
+}
@@ -0,0 +1,92 @@
+package yaml
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"src.elv.sh/pkg/tt"
+)
+
+func TestEncode(t *testing.T) {
+	tt.Test(t, Encode,
+		Args(nil).Rets("null\n", error(nil)),
+		Args("foo").Rets("foo\n", error(nil)),
+		Args(m{}).Rets("{}\n", error(nil)),
+		Args(a{}).Rets("[]\n", error(nil)),
+		Args(a{nil, true, 1, int64(2), bigInt("12345678901234567890"),
+			1.0, 1.5, math.Inf(1), math.Inf(-1)}).Rets(`- null
+- true
+- 1
+- 2
+- 12345678901234567890
+- 1.0
+- 1.5
+- .inf
+- -.inf
+`, error(nil)),
+		Args(a{"", "true", "12", "1.5", "yes", " x", "a: b", "a #b", "- x", "[x]",
+			"...", "x:", "tab\there", "quote\"", "é"}).Rets(`- ""
+- "true"
+- "12"
+- "1.5"
+- "yes"
+- " x"
+- "a: b"
+- "a #b"
+- "- x"
+- "[x]"
+- "..."
+- "x:"
+- "tab\there"
+- quote"
+- é
+`, error(nil)),
+		Args(m{"b": m{"c": a{"x", m{"w": 1, "z": a{}}}, "d": m{}}, "a": a{a{1, 2}},
+			"key with: colon": 3}).Rets(`a:
+  - - 1
+    - 2
+b:
+  c:
+    - x
+    - w: 1
+      z: []
+  d: {}
+"key with: colon": 3
+`, error(nil)),
+		Args(m{"s": "line 1\n  line 2\n\nline 3\n", "t": "x\ny", "u": "x\n\n",
+			"v": a{"a\nb"}}).Rets(`s: |
+  line 1
+    line 2
+
+  line 3
+t: |-
+  x
+  y
+u: |+
+  x
+
+v:
+  - |-
+    a
+    b
+`, error(nil)),
+		Args(a{"trailing \nspace"}).Rets(`- "trailing \nspace"
+`, error(nil)),
+		Args(a{struct{}{}}).Rets("", errors.New("unsupported type struct {}")),
+	)
+}
+
+func TestEncode_RoundTrip(t *testing.T) {
+	docs := a{
+		m{"a": a{int64(1), "2", m{"b": "multi\nline\n"}}, "c": m{"d": nil, "e": 1.5}},
+		a{a{}, m{}, "x\n\n", "", "- x", " lead"},
+	}
+	for _, doc := range docs {
+		s, err := Encode(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tt.Test(t, Decode, Args(s).Rets(a{doc}, error(nil)))
+	}
+}